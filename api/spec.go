@@ -0,0 +1,9 @@
+// Package api embeds the OpenAPI specification so it ships inside the binary
+package api
+
+import _ "embed"
+
+// Spec is the contents of openapi.yaml
+//
+//go:embed openapi.yaml
+var Spec []byte
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"techwave/api"
+	"techwave/cache"
+	"techwave/handlers"
+	"techwave/jobs"
+	"techwave/middleware"
+	"techwave/models"
+	"techwave/notify"
+	"techwave/repository"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+)
+
+// Config holds everything needed to build an App.
+// Zero values disable the optional features.
+type Config struct {
+	// Store holds enrollments; nil uses a new in-memory repository
+	Store repository.Store
+	// RedisClient backs the enrollment cache; nil disables caching
+	RedisClient *redis.Client
+	// RedisReplicaClient serves cache reads when set; nil reads from RedisClient
+	RedisReplicaClient *redis.Client
+	// CacheTTL is the enrollment cache TTL; zero uses cache.EnrollmentCacheTTL
+	CacheTTL time.Duration
+	// CacheFallbackSize caps the in-process cache used while Redis is
+	// unreachable; zero disables the fallback
+	CacheFallbackSize int
+	// CacheNamespace prefixes every cache key; empty leaves keys unprefixed
+	CacheNamespace string
+	// CacheCompressThreshold gzips cached values longer than this many bytes;
+	// zero disables compression
+	CacheCompressThreshold int
+	// LogOutput receives one JSON line per request; nil disables request logging
+	LogOutput io.Writer
+	// DebugBodyOutput receives one JSON line per request with its request and
+	// response bodies; nil disables body logging, which is costly
+	DebugBodyOutput io.Writer
+	// DebugBodyBytes caps each logged body; zero uses
+	// middleware.DefaultDebugBodyBytes
+	DebugBodyBytes int
+	// DebugRedactFields lists JSON fields masked in logged bodies; nil uses
+	// middleware.DefaultRedactedFields
+	DebugRedactFields []string
+	// MaxBodyBytes caps request bodies; zero uses middleware.DefaultMaxBodyBytes
+	MaxBodyBytes int64
+	// RequestTimeout bounds each request, answering 503 once it passes; zero
+	// disables the deadline
+	RequestTimeout time.Duration
+	// RateLimitRPS and RateLimitBurst configure per-client rate limiting; zero RPS disables it
+	RateLimitRPS   float64
+	RateLimitBurst int
+	// CORSAllowedOrigins enables CORS for the listed origins ("*" for any)
+	CORSAllowedOrigins []string
+	// CORSAllowCredentials allows cookies and auth headers cross-origin
+	CORSAllowCredentials bool
+	// Notifier receives enrollment lifecycle events; nil disables notifications
+	Notifier notify.Notifier
+	// ValidateRequests checks request bodies against the OpenAPI spec
+	// before they reach a handler
+	ValidateRequests bool
+	// GradeScale derives letter grades from scores; nil uses
+	// models.DefaultGradeScale
+	GradeScale models.GradeScale
+	// PassingScore is the lowest score reported as passed; zero uses
+	// models.DefaultPassingScore
+	PassingScore float64
+	// AdminAPIKey guards the admin endpoints; empty disables them
+	AdminAPIKey string
+	// Env names the deployment environment; "production" blocks storage
+	// resets unless AllowProductionReset is set
+	Env                  string
+	AllowProductionReset bool
+	// NewEnrollmentID generates the IDs of created enrollments; nil uses
+	// random UUIDs
+	NewEnrollmentID func() string
+	// ClampPageLimit serves list requests whose limit exceeds
+	// handlers.MaxPageLimit with the maximum and a Warning header instead of
+	// answering 400
+	ClampPageLimit bool
+	// DefaultEnrollmentStatus is given to created enrollments sent without a
+	// status; empty keeps status required. It must be a valid status.
+	DefaultEnrollmentStatus string
+	// RepanicOnPanic logs a handler panic and then re-raises it instead of
+	// answering 500, so tests see the panic
+	RepanicOnPanic bool
+}
+
+// App is one isolated instance of the API with its own storage and cache
+type App struct {
+	Enrollments repository.Store
+	Grades      *repository.GradeRepository
+	Audit       *repository.AuditRepository
+	Courses     *repository.CourseRepository
+	Cache       *cache.EnrollmentCache
+	// Jobs runs background work such as grade recomputes; stop it with StopJobs
+	Jobs *jobs.Queue
+
+	cfg               Config
+	enrollmentHandler *handlers.EnrollmentHandler
+	handler           http.Handler
+
+	// expiryStop and expiryDone control the pending expiry sweep
+	expiryStop chan struct{}
+	expiryDone chan struct{}
+}
+
+// NewApp creates an App and registers its routes. Grades, the audit trail and
+// course capacities always start empty; enrollments use cfg.Store when set.
+func NewApp(cfg Config) *App {
+	enrollments := cfg.Store
+	if enrollments == nil {
+		enrollments = repository.NewEnrollmentRepository()
+	}
+	a := &App{
+		Enrollments: enrollments,
+		Grades:      repository.NewGradeRepository(enrollments),
+		Audit:       repository.NewAuditRepository(),
+		Courses:     repository.NewCourseRepository(),
+		Jobs:        jobs.NewQueue(jobs.DefaultWorkers),
+		cfg:         cfg,
+	}
+
+	// Initialize cache (nil-safe, graceful degradation)
+	if cfg.RedisClient != nil {
+		a.Cache = cache.NewEnrollmentCacheWithOptions(cfg.RedisClient, cache.Options{
+			TTL:               cfg.CacheTTL,
+			FallbackSize:      cfg.CacheFallbackSize,
+			Namespace:         cfg.CacheNamespace,
+			Replica:           cfg.RedisReplicaClient,
+			CompressThreshold: cfg.CacheCompressThreshold,
+		})
+	}
+
+	a.enrollmentHandler = handlers.NewEnrollmentHandler(a.Enrollments, a.Cache, a.Audit, a.Courses, handlers.EnrollmentHandlerOptions{
+		Notifier:       cfg.Notifier,
+		NewID:          cfg.NewEnrollmentID,
+		ClampPageLimit: cfg.ClampPageLimit,
+		DefaultStatus:  cfg.DefaultEnrollmentStatus,
+	})
+	a.handler = a.buildRoutes()
+	return a
+}
+
+// StopJobs stops the background job queue, cancelling running jobs and
+// waiting for them to return
+func (a *App) StopJobs() {
+	a.Jobs.Close()
+}
+
+// Routes returns the HTTP handler serving every route of the app
+func (a *App) Routes() http.Handler {
+	return a.handler
+}
+
+// WarmCache pre-loads up to limit enrollments into the cache and returns how
+// many were cached. It is a no-op when caching is disabled.
+func (a *App) WarmCache(ctx context.Context, limit int) (int, error) {
+	if a.Cache == nil {
+		return 0, nil
+	}
+
+	enrollments := a.Enrollments.GetAll(ctx)
+	if len(enrollments) > limit {
+		enrollments = enrollments[:limit]
+	}
+
+	return a.Cache.WarmUp(ctx, enrollments)
+}
+
+// buildRoutes wires handlers and middleware onto a new router
+func (a *App) buildRoutes() http.Handler {
+	enrollmentHandler := a.enrollmentHandler
+	gradeScale := a.cfg.GradeScale
+	if gradeScale == nil {
+		gradeScale = models.DefaultGradeScale
+	}
+	passingScore := a.cfg.PassingScore
+	if passingScore == 0 {
+		passingScore = models.DefaultPassingScore
+	}
+	gradeHandler := handlers.NewGradeHandler(a.Enrollments, a.Grades, gradeScale, passingScore)
+	courseHandler := handlers.NewCourseHandler(a.Enrollments, a.Grades, a.Courses)
+	cacheHandler := handlers.NewCacheHandler(a.Cache)
+	healthHandler := handlers.NewHealthHandler(a.Enrollments, a.Cache)
+	adminHandler := handlers.NewAdminHandler(a.Enrollments, a.Grades, a.Cache, gradeScale, a.cfg.AdminAPIKey, a.cfg.Env, a.cfg.AllowProductionReset, a.Jobs)
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(handlers.NotFound)
+	router.MethodNotAllowedHandler = methodNotAllowedHandler(router)
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Tracing)
+	if a.cfg.LogOutput != nil {
+		router.Use(middleware.RequestLogger(a.cfg.LogOutput))
+	}
+	router.Use(middleware.Recover(a.cfg.RepanicOnPanic))
+	if a.cfg.RequestTimeout > 0 {
+		router.Use(middleware.Timeout(a.cfg.RequestTimeout))
+	}
+	// Throttle before any body is buffered or validated, so clients over
+	// their limit cost as little as possible
+	if a.cfg.RateLimitRPS > 0 {
+		router.Use(middleware.RateLimit(a.cfg.RateLimitRPS, a.cfg.RateLimitBurst))
+	}
+
+	maxBodyBytes := a.cfg.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = middleware.DefaultMaxBodyBytes
+	}
+	router.Use(middleware.BodyLimit(maxBodyBytes))
+	if a.cfg.DebugBodyOutput != nil {
+		redact := a.cfg.DebugRedactFields
+		if redact == nil {
+			redact = middleware.DefaultRedactedFields
+		}
+		router.Use(middleware.DebugBodies(a.cfg.DebugBodyOutput, a.cfg.DebugBodyBytes, redact))
+	}
+	router.Use(middleware.RequireJSON)
+
+	if a.cfg.ValidateRequests {
+		validate, err := middleware.RequestValidation(api.Spec)
+		if err != nil {
+			log.Printf("WARNING: Request validation disabled: %v", err)
+		} else {
+			router.Use(validate)
+		}
+	}
+
+	// Root endpoint
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		cacheStatus := "disabled"
+		if a.Cache != nil {
+			cacheStatus = "enabled"
+		}
+		fmt.Fprintf(w, "Grade Management API - Cache: %s", cacheStatus)
+	}).Methods("GET")
+
+	// Health, liveness and readiness probes
+	router.HandleFunc("/health", healthHandler.Health).Methods("GET")
+	router.HandleFunc("/health/live", healthHandler.Live).Methods("GET")
+	router.HandleFunc("/health/ready", healthHandler.Ready).Methods("GET")
+
+	// API routes with /api prefix
+	apiRouter := router.PathPrefix("/api").Subrouter()
+	apiRouter.Use(middleware.CacheStatusMiddleware)
+
+	// Enrollment routes
+	apiRouter.HandleFunc("/enrollments", enrollmentHandler.CreateEnrollment).Methods("POST")
+	apiRouter.HandleFunc("/enrollments", enrollmentHandler.GetAllEnrollments).Methods("GET")
+	apiRouter.HandleFunc("/enrollments", enrollmentHandler.DeleteEnrollments).Methods("DELETE")
+	apiRouter.HandleFunc("/enrollments/bulk", enrollmentHandler.BulkCreateEnrollments).Methods("POST")
+	apiRouter.HandleFunc("/enrollments/bulk-status", enrollmentHandler.BulkUpdateStatus).Methods("POST")
+	apiRouter.HandleFunc("/enrollments/count", enrollmentHandler.CountEnrollments).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/search", enrollmentHandler.SearchEnrollments).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/recent", enrollmentHandler.RecentEnrollments).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/all", adminHandler.ResetStore).Methods("DELETE")
+	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.GetEnrollment).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.HeadEnrollment).Methods("HEAD")
+	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.UpdateEnrollment).Methods("PUT")
+	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.DeleteEnrollment).Methods("DELETE")
+	apiRouter.HandleFunc("/enrollments/{id}/restore", enrollmentHandler.RestoreEnrollment).Methods("POST")
+	apiRouter.HandleFunc("/enrollments/{id}/history", enrollmentHandler.GetEnrollmentHistory).Methods("GET")
+
+	// Grade routes
+	apiRouter.HandleFunc("/enrollments/{id}/grades", gradeHandler.CreateGrade).Methods("POST")
+	apiRouter.HandleFunc("/enrollments/{id}/grades", gradeHandler.GetGrades).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/{id}/grades/summary", gradeHandler.GetGradeSummary).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/{id}/grades/{gradeId}", gradeHandler.GetGrade).Methods("GET")
+	apiRouter.HandleFunc("/enrollments/{id}/grades/{gradeId}", gradeHandler.UpdateGrade).Methods("PUT")
+	apiRouter.HandleFunc("/enrollments/{id}/grades/{gradeId}", gradeHandler.DeleteGrade).Methods("DELETE")
+
+	// Student routes
+	apiRouter.HandleFunc("/students/{studentId}/gpa", gradeHandler.GetStudentGPA).Methods("GET")
+
+	// Course routes
+	apiRouter.HandleFunc("/courses/{courseId}/stats", courseHandler.GetCourseStats).Methods("GET")
+	apiRouter.HandleFunc("/courses/{courseId}/capacity", courseHandler.SetCapacity).Methods("POST")
+
+	// Admin jobs
+	apiRouter.HandleFunc("/admin/recompute-grades", adminHandler.RecomputeGrades).Methods("POST")
+	apiRouter.HandleFunc("/admin/jobs/{id}", adminHandler.GetJob).Methods("GET")
+
+	// Seed data for demos and end-to-end tests (-tags seed builds only)
+	registerSeedRoutes(apiRouter, enrollmentHandler)
+
+	// Cache administration routes
+	apiRouter.HandleFunc("/cache/stats", cacheHandler.GetStats).Methods("GET")
+	apiRouter.HandleFunc("/cache", cacheHandler.ClearCache).Methods("DELETE")
+
+	// OPTIONS on any path reports the methods its routes accept
+	router.Methods("OPTIONS").HandlerFunc(optionsHandler(router))
+
+	// CORS wraps the router so preflight requests never reach route matching
+	if len(a.cfg.CORSAllowedOrigins) == 0 {
+		return router
+	}
+	log.Printf("✓ CORS enabled for origins: %v (credentials: %v)", a.cfg.CORSAllowedOrigins, a.cfg.CORSAllowCredentials)
+	if a.cfg.CORSAllowCredentials {
+		return middleware.CORSWithCredentials(a.cfg.CORSAllowedOrigins)(router)
+	}
+	return middleware.CORS(a.cfg.CORSAllowedOrigins)(router)
+}
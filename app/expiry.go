@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"log"
+	"strings"
+	"techwave/config"
+	"time"
+)
+
+// ExpirePending soft-deletes every pending enrollment created more than
+// maxAge ago, logs what it removed and returns how many expired
+func (a *App) ExpirePending(ctx context.Context, maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	expired, err := a.enrollmentHandler.ExpirePending(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(expired) > 0 {
+		ids := make([]string, len(expired))
+		for i, enrollment := range expired {
+			ids[i] = enrollment.ID
+		}
+		log.Printf("Expired %d pending enrollment(s) created before %s: %s",
+			len(expired), cutoff.Format(time.RFC3339), strings.Join(ids, ", "))
+	}
+	return len(expired), nil
+}
+
+// StartPendingExpiry runs ExpirePending every interval in the background
+// until StopPendingExpiry. A zero or negative interval uses
+// config.DefaultPendingExpiryInterval.
+func (a *App) StartPendingExpiry(interval, maxAge time.Duration) {
+	if interval <= 0 {
+		interval = config.DefaultPendingExpiryInterval
+	}
+	a.expiryStop = make(chan struct{})
+	a.expiryDone = make(chan struct{})
+
+	go func() {
+		defer close(a.expiryDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := a.ExpirePending(context.Background(), maxAge); err != nil {
+					log.Printf("WARNING: Pending expiry sweep failed: %v", err)
+				}
+			case <-a.expiryStop:
+				return
+			}
+		}
+	}()
+}
+
+// StopPendingExpiry stops the background sweep, waiting for one in progress
+// to finish. It is a no-op when the sweep was never started.
+func (a *App) StopPendingExpiry() {
+	if a.expiryStop == nil {
+		return
+	}
+	close(a.expiryStop)
+	<-a.expiryDone
+	a.expiryStop = nil
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"techwave/models"
+	"techwave/repository"
+)
+
+// ImportSeedFile loads the JSON array of enrollments at path into the store
+// and returns how many were imported. It does nothing when the store already
+// holds enrollments, soft-deleted ones included, so restarting never imports
+// twice. Records that are malformed, fail validation or duplicate an existing
+// enrollment are logged and skipped; only an unreadable file or one that is
+// not a JSON array is an error.
+func (a *App) ImportSeedFile(ctx context.Context, path string) (int, error) {
+	if len(a.Enrollments.Find(repository.EnrollmentFilter{IncludeDeleted: true})) > 0 {
+		log.Printf("Store already holds enrollments, skipping seed file %s", path)
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return 0, fmt.Errorf("seed file must be a JSON array of enrollments: %w", err)
+	}
+
+	// Decode records one by one so a malformed record only skips itself
+	enrollments := make([]*models.Enrollment, 0, len(records))
+	indexes := make([]int, 0, len(records))
+	for i, record := range records {
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.DisallowUnknownFields()
+		var enrollment models.Enrollment
+		if err := decoder.Decode(&enrollment); err != nil {
+			log.Printf("WARNING: Skipping seed record %d: %v", i, err)
+			continue
+		}
+		enrollments = append(enrollments, &enrollment)
+		indexes = append(indexes, i)
+	}
+
+	imported := 0
+	for j, err := range a.enrollmentHandler.ImportEnrollments(ctx, enrollments) {
+		if err != nil {
+			log.Printf("WARNING: Skipping seed record %d: %v", indexes[j], err)
+			continue
+		}
+		imported++
+	}
+	return imported, nil
+}
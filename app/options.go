@@ -0,0 +1,87 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"techwave/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// probeMethods are the methods an OPTIONS request checks for, in the order
+// they are listed in the Allow header
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// optionsHandler answers OPTIONS requests for any registered path with 204
+// and an Allow header listing the methods the router would accept there.
+// Paths no route matches get 404. CORS preflights never get this far.
+func optionsHandler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r)
+		if len(allowed) == 0 {
+			handlers.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// methodNotAllowedHandler answers requests whose path has routes but none
+// for the method with a JSON 405 and the same Allow header OPTIONS reports.
+// The OPTIONS route matches every path, so mux treats unknown paths as a
+// method mismatch too; those get 404 here.
+func methodNotAllowedHandler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r)
+		if len(allowed) == 0 {
+			handlers.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+// allowedMethods reports which of probeMethods have a route matching r's
+// path. When several path templates match, as "/enrollments/bulk" and
+// "/enrollments/{id}" both do for "/enrollments/bulk", only the methods of
+// the most literal template (fewest variables) are reported.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	templates := make(map[string]string, len(probeMethods))
+	best := ""
+	for _, method := range probeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if !router.Match(probe, &match) || match.MatchErr != nil {
+			continue
+		}
+		template, err := match.Route.GetPathTemplate()
+		if err != nil {
+			continue
+		}
+		templates[method] = template
+		if best == "" || strings.Count(template, "{") < strings.Count(best, "{") {
+			best = template
+		}
+	}
+
+	var allowed []string
+	for _, method := range probeMethods {
+		if template, ok := templates[method]; ok && template == best {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,18 @@
+//go:build seed
+// +build seed
+
+package app
+
+import (
+	"log"
+	"techwave/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// registerSeedRoutes adds POST /api/_seed. This file is only compiled with
+// -tags seed; production builds get the no-op in seed_disabled.go.
+func registerSeedRoutes(apiRouter *mux.Router, enrollmentHandler *handlers.EnrollmentHandler) {
+	log.Printf("WARNING: Seed endpoint enabled at POST /api/_seed")
+	apiRouter.HandleFunc("/_seed", enrollmentHandler.SeedEnrollments).Methods("POST")
+}
@@ -0,0 +1,13 @@
+//go:build !seed
+// +build !seed
+
+package app
+
+import (
+	"techwave/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// registerSeedRoutes is a no-op unless built with -tags seed
+func registerSeedRoutes(apiRouter *mux.Router, enrollmentHandler *handlers.EnrollmentHandler) {}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrAuthFailed is returned by NewClient when Redis rejects the credentials
+var ErrAuthFailed = errors.New("redis authentication failed")
+
+// clientPingTimeout bounds the startup Ping in NewClient
+const clientPingTimeout = 5 * time.Second
+
+// RedisConfig describes how to connect to Redis
+type RedisConfig struct {
+	// Addr is the host:port of the Redis server
+	Addr string
+	// Username and Password authenticate the connection (Username for Redis 6 ACLs)
+	Username string
+	Password string
+	// DB is the database index selected after connecting
+	DB int
+	// PoolSize is the maximum number of socket connections; zero uses the go-redis default
+	PoolSize int
+	// MinIdleConns keeps this many idle connections open
+	MinIdleConns int
+	// TLS enables TLS, as required by most managed Redis services
+	TLS bool
+}
+
+// NewClient builds a Redis client from cfg and pings it.
+// When the ping fails the client is closed and the error returned; a rejected
+// password or username wraps ErrAuthFailed so callers can fail fast on bad
+// credentials while tolerating an unreachable server.
+func NewClient(cfg RedisConfig) (*redis.Client, error) {
+	options := &redis.Options{
+		Addr:         cfg.Addr,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+	}
+	if cfg.TLS {
+		options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), clientPingTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		if isAuthError(err) {
+			return nil, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// isAuthError reports whether err is Redis rejecting the credentials
+func isAuthError(err error) bool {
+	msg := err.Error()
+	for _, prefix := range []string{"NOAUTH", "WRONGPASS", "NOPERM", "ERR AUTH", "ERR invalid password", "ERR invalid username"} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
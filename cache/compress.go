@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressedMagic prefixes gzip-compressed values. Plain values are JSON and
+// never start with a NUL byte, so the prefix tells the two apart on read.
+var compressedMagic = []byte{0x00, 'g', 'z'}
+
+// encode gzips data behind compressedMagic when threshold is positive and
+// data is longer than threshold; otherwise data is returned unchanged
+func encode(data []byte, threshold int) ([]byte, error) {
+	if threshold <= 0 || len(data) <= threshold {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(compressedMagic)
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode reverses encode. Values without compressedMagic are returned
+// unchanged, so entries written before compression was enabled, or below the
+// threshold, still read correctly.
+func decode(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, compressedMagic) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data[len(compressedMagic):]))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
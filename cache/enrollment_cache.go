@@ -5,83 +5,257 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"techwave/models"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer creates cache spans; it follows the global provider set by tracing.Setup
+var tracer = otel.Tracer("techwave/cache")
+
 const (
-	// EnrollmentCacheTTL is the time-to-live for cached enrollments (5 minutes)
+	// EnrollmentCacheTTL is the default time-to-live for cached enrollments (5 minutes)
 	EnrollmentCacheTTL = 5 * time.Minute
 	// EnrollmentCachePrefix is the prefix for enrollment cache keys
 	EnrollmentCachePrefix = "enrollment:"
+	// EnrollmentListCacheKey is the key holding the cached list of all enrollments
+	EnrollmentListCacheKey = "enrollments:all"
+	// EnrollmentListCacheTTL is the time-to-live for the cached enrollment list (30 seconds)
+	EnrollmentListCacheTTL = 30 * time.Second
+	// IdempotencyKeyPrefix is the prefix for idempotency-key cache entries
+	IdempotencyKeyPrefix = "idempotency:"
+	// IdempotencyKeyTTL is how long an idempotency key is remembered (24 hours)
+	IdempotencyKeyTTL = 24 * time.Hour
+	// clearBatchSize is the SCAN count hint and maximum keys per DEL in Clear,
+	// and the maximum keys per UNLINK in DeleteMany
+	clearBatchSize = 500
+	// DefaultFallbackSize is the default number of enrollments the in-process
+	// fallback tier holds while Redis is unreachable
+	DefaultFallbackSize = 1000
+)
+
+// Tier names the cache layer that served a hit
+type Tier string
+
+const (
+	// TierRedis is a hit served by Redis
+	TierRedis Tier = "redis"
+	// TierMemory is a hit served by the in-process fallback during a Redis outage
+	TierMemory Tier = "memory"
 )
 
-// EnrollmentCache provides Redis caching for enrollment data
+// EnrollmentCache provides Redis caching for enrollment data.
+// Every Redis call runs under the caller's context, so a cancelled request
+// or an expired deadline abandons it. With a fallback configured, single
+// enrollments that cannot be written to or read from Redis are kept in a
+// bounded in-process LRU instead, so an outage does not send every read to
+// the repository.
 type EnrollmentCache struct {
-	client *redis.Client
-	ctx    context.Context
+	client    *redis.Client
+	reader    *redis.Client
+	ttl       time.Duration
+	compress  int
+	namespace string
+	fallback  *memoryCache
+	hits      atomic.Int64
+	misses    atomic.Int64
+}
+
+// Options configures an EnrollmentCache. Zero values use the defaults.
+type Options struct {
+	// TTL applies to cached enrollments; zero uses EnrollmentCacheTTL
+	TTL time.Duration
+	// FallbackSize caps the in-process tier used while Redis is failing.
+	// Its entries expire after the same TTL. Zero disables the fallback.
+	FallbackSize int
+	// Namespace prefixes every key as "<namespace>:" so several environments
+	// can share one Redis without colliding; empty leaves keys unprefixed
+	Namespace string
+	// Replica serves enrollment and list reads while writes, deletes,
+	// idempotency keys and locks stay on the primary client. Replication is
+	// asynchronous, so a read may miss or return an entry the primary has
+	// just replaced or removed until the replica catches up. Nil reads from
+	// the primary.
+	Replica *redis.Client
+	// CompressThreshold gzips enrollments and lists whose JSON is longer
+	// than this many bytes before they are stored. Compressed values carry a
+	// marker, so reads detect them whatever the setting. Zero disables it.
+	CompressThreshold int
 }
 
-// NewEnrollmentCache creates a new enrollment cache instance
+// NewEnrollmentCache creates a new enrollment cache instance with the default TTL
 func NewEnrollmentCache(client *redis.Client) *EnrollmentCache {
-	return &EnrollmentCache{
-		client: client,
-		ctx:    context.Background(),
+	return NewEnrollmentCacheWithTTL(client, EnrollmentCacheTTL)
+}
+
+// NewEnrollmentCacheWithTTL creates a new enrollment cache instance with a custom TTL
+func NewEnrollmentCacheWithTTL(client *redis.Client, ttl time.Duration) *EnrollmentCache {
+	return NewEnrollmentCacheWithOptions(client, Options{TTL: ttl})
+}
+
+// NewEnrollmentCacheWithOptions creates a new enrollment cache instance
+// configured by opts
+func NewEnrollmentCacheWithOptions(client *redis.Client, opts Options) *EnrollmentCache {
+	c := &EnrollmentCache{
+		client:   client,
+		reader:   opts.Replica,
+		ttl:      opts.TTL,
+		compress: opts.CompressThreshold,
+	}
+	if c.reader == nil {
+		c.reader = client
 	}
+	if c.ttl <= 0 {
+		c.ttl = EnrollmentCacheTTL
+	}
+	if opts.Namespace != "" {
+		c.namespace = opts.Namespace + ":"
+	}
+	if opts.FallbackSize > 0 {
+		c.fallback = newMemoryCache(opts.FallbackSize)
+	}
+	return c
+}
+
+// HasFallback reports whether the in-process fallback tier is enabled
+func (c *EnrollmentCache) HasFallback() bool {
+	return c.fallback != nil
+}
+
+// TTL returns the time-to-live applied to cached enrollments
+func (c *EnrollmentCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get retrieves an enrollment from cache, recording a child span of any
+// trace carried by ctx. Returns nil, nil on a cache miss.
+func (c *EnrollmentCache) Get(ctx context.Context, id string) (*models.Enrollment, error) {
+	enrollment, _, err := c.Lookup(ctx, id)
+	return enrollment, err
 }
 
-// Get retrieves an enrollment from cache
-func (c *EnrollmentCache) Get(id string) (*models.Enrollment, error) {
+// Lookup is like Get but also reports which tier served a hit. When
+// Redis fails and the fallback holds the enrollment it is returned from
+// TierMemory with a nil error; otherwise the Redis error is returned.
+func (c *EnrollmentCache) Lookup(ctx context.Context, id string) (*models.Enrollment, Tier, error) {
+	ctx, span := tracer.Start(ctx, "cache.Get", trace.WithAttributes(attribute.String("enrollment.id", id)))
+	defer span.End()
+
 	key := c.buildKey(id)
 	
-	data, err := c.client.Get(c.ctx, key).Bytes()
+	data, err := c.read(ctx, key)
 	if err == redis.Nil {
 		// Cache miss
-		return nil, nil
+		c.misses.Add(1)
+		span.SetAttributes(attribute.String("cache.status", "MISS"))
+		return nil, "", nil
 	}
 	if err != nil {
-		// Redis error - log but don't fail
 		log.Printf("Redis Get error for key %s: %v", key, err)
-		return nil, err
+		if c.fallback != nil {
+			if enrollment := c.fallback.get(id, time.Now()); enrollment != nil {
+				c.hits.Add(1)
+				span.SetAttributes(attribute.String("cache.status", "HIT"), attribute.String("cache.tier", string(TierMemory)))
+				log.Printf("Fallback cache HIT for enrollment ID: %s", id)
+				return enrollment, TierMemory, nil
+			}
+		}
+
+		// Redis error - log but don't fail; the caller falls back to the repository
+		c.misses.Add(1)
+		span.SetAttributes(attribute.String("cache.status", "MISS"))
+		span.SetStatus(codes.Error, err.Error())
+		return nil, "", err
 	}
 
 	var enrollment models.Enrollment
-	if err := json.Unmarshal(data, &enrollment); err != nil {
+	if err := unmarshal(data, &enrollment); err != nil {
+		c.misses.Add(1)
+		span.SetAttributes(attribute.String("cache.status", "MISS"))
+		span.SetStatus(codes.Error, err.Error())
 		log.Printf("Failed to unmarshal cached enrollment: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 
+	c.hits.Add(1)
+	span.SetAttributes(attribute.String("cache.status", "HIT"), attribute.String("cache.tier", string(TierRedis)))
 	log.Printf("Cache HIT for enrollment ID: %s", id)
-	return &enrollment, nil
+	return &enrollment, TierRedis, nil
 }
 
-// Set stores an enrollment in cache with TTL
-func (c *EnrollmentCache) Set(enrollment *models.Enrollment) error {
+// Set stores an enrollment in cache with TTL, recording a child span of any
+// trace carried by ctx
+func (c *EnrollmentCache) Set(ctx context.Context, enrollment *models.Enrollment) error {
+	ctx, span := tracer.Start(ctx, "cache.Set", trace.WithAttributes(attribute.String("enrollment.id", enrollment.ID)))
+	defer span.End()
+
 	key := c.buildKey(enrollment.ID)
 	
-	data, err := json.Marshal(enrollment)
+	data, err := c.marshal(enrollment)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		log.Printf("Failed to marshal enrollment for caching: %v", err)
 		return err
 	}
 
-	err = c.client.Set(c.ctx, key, data, EnrollmentCacheTTL).Err()
+	err = c.client.Set(ctx, key, data, c.ttl).Err()
 	if err != nil {
 		log.Printf("Redis Set error for key %s: %v", key, err)
+		if c.fallback != nil {
+			c.fallback.set(enrollment, c.ttl, time.Now())
+			log.Printf("Cached enrollment ID %s in fallback tier (TTL: %v)", enrollment.ID, c.ttl)
+			return nil
+		}
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	log.Printf("Cached enrollment ID: %s (TTL: %v)", enrollment.ID, EnrollmentCacheTTL)
+	log.Printf("Cached enrollment ID: %s (TTL: %v)", enrollment.ID, c.ttl)
 	return nil
 }
 
-// Delete removes an enrollment from cache (for invalidation)
-func (c *EnrollmentCache) Delete(id string) error {
+// WarmUp pre-loads enrollments into cache using a single pipeline round trip.
+// Returns the number of keys written.
+func (c *EnrollmentCache) WarmUp(ctx context.Context, enrollments []*models.Enrollment) (int, error) {
+	if len(enrollments) == 0 {
+		return 0, nil
+	}
+
+	pipe := c.client.Pipeline()
+	queued := 0
+	for _, enrollment := range enrollments {
+		data, err := c.marshal(enrollment)
+		if err != nil {
+			log.Printf("Failed to marshal enrollment %s for warm-up: %v", enrollment.ID, err)
+			continue
+		}
+		pipe.Set(ctx, c.buildKey(enrollment.ID), data, c.ttl)
+		queued++
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Redis pipeline error during cache warm-up: %v", err)
+		return 0, err
+	}
+
+	return queued, nil
+}
+
+// Delete removes an enrollment from cache (for invalidation).
+// The fallback entry is always removed, even when Redis is unreachable.
+func (c *EnrollmentCache) Delete(ctx context.Context, id string) error {
+	if c.fallback != nil {
+		c.fallback.delete(id)
+	}
 	key := c.buildKey(id)
 	
-	err := c.client.Del(c.ctx, key).Err()
+	err := c.client.Del(ctx, key).Err()
 	if err != nil {
 		log.Printf("Redis Delete error for key %s: %v", key, err)
 		return err
@@ -91,25 +265,241 @@ func (c *EnrollmentCache) Delete(id string) error {
 	return nil
 }
 
-// buildKey constructs the Redis key for an enrollment
-func (c *EnrollmentCache) buildKey(id string) string {
-	return fmt.Sprintf("%s%s", EnrollmentCachePrefix, id)
+// DeleteMany removes several enrollments from cache in a single round trip,
+// pipelining one UNLINK per clearBatchSize keys so Redis frees the values in
+// the background. Like Delete, the fallback entries are always removed.
+func (c *EnrollmentCache) DeleteMany(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		if c.fallback != nil {
+			c.fallback.delete(id)
+		}
+		keys[i] = c.buildKey(id)
+	}
+
+	pipe := c.client.Pipeline()
+	for start := 0; start < len(keys); start += clearBatchSize {
+		end := min(start+clearBatchSize, len(keys))
+		pipe.Unlink(ctx, keys[start:end]...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Redis pipeline error invalidating %d enrollment(s): %v", len(ids), err)
+		return err
+	}
+
+	log.Printf("Cache invalidated for %d enrollment(s)", len(ids))
+	return nil
 }
 
-// Ping checks if Redis connection is healthy
-func (c *EnrollmentCache) Ping() error {
-	return c.client.Ping(c.ctx).Err()
+// GetList retrieves the cached list of all live enrollments.
+// Returns nil, nil on a cache miss.
+func (c *EnrollmentCache) GetList(ctx context.Context) ([]*models.Enrollment, error) {
+	data, err := c.read(ctx, c.listKey())
+	if err == redis.Nil {
+		// Cache miss
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Redis Get error for key %s: %v", c.listKey(), err)
+		return nil, err
+	}
+
+	var enrollments []*models.Enrollment
+	if err := unmarshal(data, &enrollments); err != nil {
+		log.Printf("Failed to unmarshal cached enrollment list: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Cache HIT for enrollment list (%d items)", len(enrollments))
+	return enrollments, nil
 }
 
-// GetStats returns basic cache statistics
-func (c *EnrollmentCache) GetStats() (map[string]interface{}, error) {
-	info, err := c.client.Info(c.ctx, "stats").Result()
+// SetList stores the list of all live enrollments.
+// The list is invalidated on every mutation, but because any write anywhere
+// makes it stale it uses a much shorter TTL than single enrollments. This
+// bounds the staleness window if an invalidation is ever missed, at the cost
+// of more frequent rebuilds under steady read traffic.
+func (c *EnrollmentCache) SetList(ctx context.Context, enrollments []*models.Enrollment) error {
+	data, err := c.marshal(enrollments)
+	if err != nil {
+		log.Printf("Failed to marshal enrollment list for caching: %v", err)
+		return err
+	}
+
+	err = c.client.Set(ctx, c.listKey(), data, EnrollmentListCacheTTL).Err()
+	if err != nil {
+		log.Printf("Redis Set error for key %s: %v", c.listKey(), err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteList removes the cached enrollment list (for invalidation)
+func (c *EnrollmentCache) DeleteList(ctx context.Context) error {
+	err := c.client.Del(ctx, c.listKey()).Err()
+	if err != nil {
+		log.Printf("Redis Delete error for key %s: %v", c.listKey(), err)
+		return err
+	}
+
+	return nil
+}
+
+// Clear removes every enrollment key and the cached enrollment list.
+// Keys are found with SCAN rather than FLUSHDB so unrelated keys in the same
+// database, including other namespaces' enrollment keys, are never touched.
+// The full scan completes before any deletes are issued, so removing keys
+// cannot shift the cursor and skip entries; deletes are then sent in
+// batches. Returns the number of keys removed. The fallback tier is emptied
+// first, even when Redis is unreachable.
+func (c *EnrollmentCache) Clear(ctx context.Context) (int64, error) {
+	if c.fallback != nil {
+		c.fallback.clear()
+	}
+
+	keys := []string{c.listKey()}
+	var cursor uint64
+
+	for {
+		batch, next, err := c.client.Scan(ctx, cursor, c.buildKey("*"), clearBatchSize).Result()
+		if err != nil {
+			log.Printf("Redis SCAN error while clearing cache: %v", err)
+			return 0, err
+		}
+		keys = append(keys, batch...)
+
+		// A zero cursor means the iteration is complete
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	var removed int64
+	for start := 0; start < len(keys); start += clearBatchSize {
+		end := start + clearBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		deleted, err := c.client.Del(ctx, keys[start:end]...).Result()
+		if err != nil {
+			log.Printf("Redis Delete error while clearing cache: %v", err)
+			return removed, err
+		}
+		removed += deleted
+	}
+
+	log.Printf("Cache cleared: %d key(s) removed", removed)
+	return removed, nil
+}
+
+// GetIdempotent returns the enrollment ID recorded for an idempotency key.
+// Returns an empty string if the key has not been seen or its create is
+// still running.
+func (c *EnrollmentCache) GetIdempotent(ctx context.Context, key string) (string, error) {
+	id, err := c.client.Get(ctx, c.namespace+IdempotencyKeyPrefix+key).Result()
+	if err == redis.Nil || (err == nil && isPendingIdempotent(id)) {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Redis Get error for idempotency key %s: %v", key, err)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// SetIdempotent records the enrollment ID created for an idempotency key
+func (c *EnrollmentCache) SetIdempotent(ctx context.Context, key, id string) error {
+	err := c.client.Set(ctx, c.namespace+IdempotencyKeyPrefix+key, id, IdempotencyKeyTTL).Err()
+	if err != nil {
+		log.Printf("Redis Set error for idempotency key %s: %v", key, err)
+		return err
+	}
+
+	return nil
+}
+
+// read fetches key from the replica when one is configured. If the replica
+// fails the read is retried on the primary, so a replica outage costs a round
+// trip rather than a miss; redis.Nil is returned as-is.
+func (c *EnrollmentCache) read(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.reader.Get(ctx, key).Bytes()
+	if err != nil && err != redis.Nil && c.reader != c.client {
+		log.Printf("WARNING: Redis replica Get error for key %s, reading from primary: %v", key, err)
+		data, err = c.client.Get(ctx, key).Bytes()
+	}
+	return data, err
+}
+
+// marshal encodes v as JSON, compressed when it exceeds the threshold
+func (c *EnrollmentCache) marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
-	
-	return map[string]interface{}{
-		"info": info,
-		"connected": c.client.Ping(c.ctx).Err() == nil,
-	}, nil
+	return encode(data, c.compress)
+}
+
+// unmarshal decodes a value written by marshal into v
+func unmarshal(data []byte, v interface{}) error {
+	data, err := decode(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// buildKey constructs the Redis key for an enrollment, within the namespace
+func (c *EnrollmentCache) buildKey(id string) string {
+	return fmt.Sprintf("%s%s%s", c.namespace, EnrollmentCachePrefix, id)
+}
+
+// listKey is the Redis key of the cached enrollment list, within the namespace
+func (c *EnrollmentCache) listKey() string {
+	return c.namespace + EnrollmentListCacheKey
+}
+
+// Ping checks if Redis connection is healthy
+func (c *EnrollmentCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// GetStats returns basic cache statistics.
+// hit_count and miss_count cover single-enrollment lookups made by this
+// instance, with fallback hits counted as hits; fallback_entries is present
+// when the fallback tier is enabled.
+// The raw Redis INFO output is omitted when the server does not expose it.
+func (c *EnrollmentCache) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+	hitRatio := 0.0
+	if total := hits + misses; total > 0 {
+		hitRatio = float64(hits) / float64(total)
+	}
+
+	stats := map[string]interface{}{
+		"connected":  c.client.Ping(ctx).Err() == nil,
+		"hit_count":  hits,
+		"miss_count": misses,
+		"hit_ratio":  hitRatio,
+	}
+
+	if c.fallback != nil {
+		stats["fallback_entries"] = c.fallback.len()
+	}
+
+	if info, err := c.client.Info(ctx, "stats").Result(); err == nil {
+		stats["info"] = info
+	} else {
+		log.Printf("Redis INFO unavailable: %v", err)
+	}
+
+	return stats, nil
 }
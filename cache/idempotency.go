@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyReservationTTL bounds how long a create holds an idempotency
+	// key before its outcome is recorded, so a crashed instance cannot block
+	// the key for the full IdempotencyKeyTTL
+	IdempotencyReservationTTL = time.Minute
+	// idempotencyPendingPrefix marks a reserved key whose create is still
+	// running; enrollment IDs are UUIDs and never start with it
+	idempotencyPendingPrefix = "pending:"
+)
+
+// ErrIdempotencyInFlight is returned by ReserveIdempotent while another
+// request holds the key
+var ErrIdempotencyInFlight = errors.New("idempotency key in use")
+
+// Reservation is a claimed idempotency key; finish it with Complete once the
+// enrollment exists, or Release when the create failed
+type Reservation struct {
+	client *redis.Client
+	key    string
+	token  string
+}
+
+// ReserveIdempotent claims an idempotency key with SET NX before a create
+// runs, so concurrent retries cannot both create. It returns a Reservation
+// when the key was free, the enrollment ID when a create with the key has
+// already finished, or ErrIdempotencyInFlight while another request holds
+// it. Any other error is from Redis.
+func (c *EnrollmentCache) ReserveIdempotent(ctx context.Context, key string) (*Reservation, string, error) {
+	reservation := &Reservation{
+		client: c.client,
+		key:    c.namespace + IdempotencyKeyPrefix + key,
+		token:  idempotencyPendingPrefix + uuid.NewString(),
+	}
+
+	claimed, err := c.client.SetNX(ctx, reservation.key, reservation.token, IdempotencyReservationTTL).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	if claimed {
+		return reservation, "", nil
+	}
+
+	id, err := c.client.Get(ctx, reservation.key).Result()
+	// A key gone between the two calls was a reservation that was released
+	// or expired; the holder may still be running, so treat it as in use
+	if err == redis.Nil || (err == nil && isPendingIdempotent(id)) {
+		return nil, "", ErrIdempotencyInFlight
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, id, nil
+}
+
+// Complete records id as the enrollment created under the key for
+// IdempotencyKeyTTL
+func (r *Reservation) Complete(ctx context.Context, id string) error {
+	return r.client.Set(ctx, r.key, id, IdempotencyKeyTTL).Err()
+}
+
+// Release frees the key for a later retry. It only deletes the key while it
+// still holds this reservation.
+func (r *Reservation) Release(ctx context.Context) error {
+	return unlockScript.Run(ctx, r.client, []string{r.key}, r.token).Err()
+}
+
+// isPendingIdempotent reports whether value is a reservation rather than an
+// enrollment ID
+func isPendingIdempotent(value string) bool {
+	return strings.HasPrefix(value, idempotencyPendingPrefix)
+}
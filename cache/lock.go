@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// LockKeyPrefix is the prefix for distributed lock keys
+	LockKeyPrefix = "lock:"
+	// lockRetryInterval is how often Lock retries a key another holder has
+	lockRetryInterval = 20 * time.Millisecond
+)
+
+var (
+	// ErrLockNotAcquired is returned by Lock when ctx ends while another
+	// holder still has the key
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	// ErrLockNotHeld is returned by Unlock when the lock expired, and possibly
+	// passed to another holder, before it was released
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// unlockScript deletes a lock key only while it still holds the caller's
+// token, so a holder whose lock expired never releases someone else's
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lock is a held distributed lock; release it with Unlock
+type Lock struct {
+	client *redis.Client
+	key    string
+	token  string
+}
+
+// Lock acquires a lock on key shared by every instance using the same Redis
+// and namespace, waiting until it is free or ctx ends. The key is set with
+// SET NX PX and a random token, so the lock disappears after ttl if its
+// holder dies. Returns ErrLockNotAcquired when ctx ends first, or the Redis
+// error if Redis cannot be reached.
+//
+// This is a single-instance lock, not Redlock: a Redis failover can lose it,
+// and a holder paused for longer than ttl loses it without noticing until
+// Unlock. Callers must keep the guarded work well inside ttl.
+func (c *EnrollmentCache) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	lock := &Lock{
+		client: c.client,
+		key:    c.namespace + LockKeyPrefix + key,
+		token:  uuid.NewString(),
+	}
+
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := c.client.SetNX(ctx, lock.key, lock.token, ttl).Result()
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		if acquired {
+			return lock, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLockNotAcquired
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock if it is still held. Returns ErrLockNotHeld when
+// it had already expired.
+func (l *Lock) Unlock(ctx context.Context) error {
+	released, err := unlockScript.Run(ctx, l.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
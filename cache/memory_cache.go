@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"techwave/models"
+	"time"
+)
+
+// memoryCache is a size-bounded, least-recently-used in-process store of
+// enrollments with per-entry expiry. EnrollmentCache uses it as a fallback
+// tier while Redis is unreachable.
+type memoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+// memoryEntry is one cached enrollment and when it expires
+type memoryEntry struct {
+	id         string
+	enrollment models.Enrollment
+	expiresAt  time.Time
+}
+
+// newMemoryCache creates a memory cache holding at most capacity enrollments
+func newMemoryCache(capacity int) *memoryCache {
+	return &memoryCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns a copy of the enrollment cached under id, or nil when it is
+// missing or expired
+func (m *memoryCache) get(id string, now time.Time) *models.Enrollment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[id]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !now.Before(entry.expiresAt) {
+		m.removeElement(element)
+		return nil
+	}
+
+	m.order.MoveToFront(element)
+	enrollment := entry.enrollment
+	return &enrollment
+}
+
+// set stores a copy of enrollment until now+ttl, evicting the least recently
+// used entry when the cache is full
+func (m *memoryCache) set(enrollment *models.Enrollment, ttl time.Duration, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[enrollment.ID]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.enrollment = *enrollment
+		entry.expiresAt = now.Add(ttl)
+		m.order.MoveToFront(element)
+		return
+	}
+
+	m.entries[enrollment.ID] = m.order.PushFront(&memoryEntry{
+		id:         enrollment.ID,
+		enrollment: *enrollment,
+		expiresAt:  now.Add(ttl),
+	})
+	if m.order.Len() > m.capacity {
+		m.removeElement(m.order.Back())
+	}
+}
+
+// delete removes the enrollment cached under id, if any
+func (m *memoryCache) delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[id]; ok {
+		m.removeElement(element)
+	}
+}
+
+// clear removes every entry and returns how many there were
+func (m *memoryCache) clear() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := m.order.Len()
+	m.order.Init()
+	m.entries = make(map[string]*list.Element)
+	return removed
+}
+
+// len returns the number of entries, including any that have expired but
+// not yet been evicted
+func (m *memoryCache) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+// removeElement drops an entry; the caller must hold mu
+func (m *memoryCache) removeElement(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).id)
+}
@@ -0,0 +1,210 @@
+// Package client calls the enrollment API from other Go services.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"techwave/models"
+	"time"
+)
+
+// Client calls the enrollment endpoints of one API server. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a client for the server at baseURL, e.g.
+// "http://localhost:8080". A nil httpClient uses http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// ListOptions filters, sorts and paginates List. Zero values are not sent,
+// so the server defaults apply.
+type ListOptions struct {
+	StudentID      string
+	CourseID       string
+	Status         string
+	From           time.Time
+	To             time.Time
+	IncludeDeleted bool
+	// Sort is enrollment_date, created_at, updated_at or status; Order is
+	// asc or desc
+	Sort   string
+	Order  string
+	Limit  int
+	Offset int
+}
+
+// Page is one page of List results
+type Page struct {
+	Data   []models.Enrollment `json:"data"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// enrollmentRequest carries only the fields clients may set. ID is filled in
+// by Create alone, since an update takes its id from the path.
+type enrollmentRequest struct {
+	ID             string     `json:"id,omitempty"`
+	StudentID      string     `json:"student_id"`
+	CourseID       string     `json:"course_id"`
+	Status         string     `json:"status"`
+	EnrollmentDate *time.Time `json:"enrollment_date,omitempty"`
+	Version        int        `json:"version,omitempty"`
+	CourseName     string     `json:"course_name,omitempty"`
+}
+
+// newEnrollmentRequest copies the writable fields of an enrollment
+func newEnrollmentRequest(enrollment models.Enrollment) enrollmentRequest {
+	req := enrollmentRequest{
+		StudentID:  enrollment.StudentID,
+		CourseID:   enrollment.CourseID,
+		Status:     enrollment.Status,
+		Version:    enrollment.Version,
+		CourseName: enrollment.CourseName,
+	}
+	if !enrollment.EnrollmentDate.IsZero() {
+		req.EnrollmentDate = &enrollment.EnrollmentDate
+	}
+	return req
+}
+
+// Create handles POST /api/enrollments. Only ID, StudentID, CourseID,
+// Status, EnrollmentDate and CourseName are sent; an empty ID lets the server
+// generate one and a zero EnrollmentDate lets it use the current time.
+func (c *Client) Create(ctx context.Context, enrollment models.Enrollment) (*models.Enrollment, error) {
+	enrollment.Version = 0
+	req := newEnrollmentRequest(enrollment)
+	req.ID = enrollment.ID
+
+	var created models.Enrollment
+	if err := c.do(ctx, http.MethodPost, "/api/enrollments", req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Get handles GET /api/enrollments/{id}
+func (c *Client) Get(ctx context.Context, id string) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	if err := c.do(ctx, http.MethodGet, "/api/enrollments/"+url.PathEscape(id), nil, &enrollment); err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// List handles GET /api/enrollments
+func (c *Client) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	path := "/api/enrollments"
+	if query := opts.query().Encode(); query != "" {
+		path += "?" + query
+	}
+
+	var page Page
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Update handles PUT /api/enrollments/{id}. enrollment.Version must be the
+// version the change is based on; a stale version returns ErrConflict.
+func (c *Client) Update(ctx context.Context, id string, enrollment models.Enrollment) (*models.Enrollment, error) {
+	var updated models.Enrollment
+	if err := c.do(ctx, http.MethodPut, "/api/enrollments/"+url.PathEscape(id), newEnrollmentRequest(enrollment), &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete handles DELETE /api/enrollments/{id}, soft-deleting the enrollment
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/enrollments/"+url.PathEscape(id), nil, nil)
+}
+
+// query encodes the options that differ from their zero values
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	set("student_id", o.StudentID)
+	set("course_id", o.CourseID)
+	set("status", o.Status)
+	set("sort", o.Sort)
+	set("order", o.Order)
+	if !o.From.IsZero() {
+		query.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		query.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.IncludeDeleted {
+		query.Set("include_deleted", "true")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return query
+}
+
+// do sends a request with an optional JSON body and decodes a successful
+// response into out when it is not nil. Error responses become *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
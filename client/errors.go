@@ -0,0 +1,91 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"techwave/models"
+	"time"
+)
+
+// Sentinel errors matched with errors.Is against the *Error a request returns
+var (
+	// ErrBadRequest means the request was rejected as invalid (400)
+	ErrBadRequest = errors.New("bad request")
+	// ErrNotFound means the enrollment does not exist or is deleted (404)
+	ErrNotFound = errors.New("enrollment not found")
+	// ErrConflict covers stale versions, duplicate enrollments, disallowed
+	// status transitions and full courses (409)
+	ErrConflict = errors.New("conflict")
+	// ErrRateLimited means the client exceeded the server's rate limit (429)
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// maxErrorBodyBytes caps how much of an error response is read
+const maxErrorBodyBytes = 64 << 10
+
+// Error is a non-2xx response from the API
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Message is the "error" field of the response body, or the status text
+	// when the body is not an error response
+	Message string
+	// Code is the machine-readable "code" field of the response body, empty
+	// when the body is not an error response
+	Code models.ErrorCode
+	// Errors lists field-level validation failures, if any
+	Errors models.ValidationErrors
+	// RequestID correlates the failure with the server's logs
+	RequestID string
+	// RetryAfter is how long the server asked the client to wait before
+	// retrying, from the Retry-After header of 429 and 503 responses; zero
+	// when the header is absent
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return "techwave API: " + e.Message
+}
+
+// Unwrap maps the status code onto a sentinel error so callers can use
+// errors.Is(err, client.ErrNotFound)
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// newError reads an error response into an *Error
+func newError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var body models.ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodyBytes)).Decode(&body); err == nil && body.Error != "" {
+		apiErr.Message = body.Error
+		apiErr.Code = body.Code
+		apiErr.Errors = body.Errors
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+	}
+	return apiErr
+}
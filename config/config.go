@@ -0,0 +1,404 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"techwave/cache"
+	"techwave/middleware"
+	"techwave/models"
+	"techwave/repository"
+	"time"
+)
+
+// Storage backends accepted by STORAGE_BACKEND
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+	StorageSQLite = "sqlite"
+)
+
+// DefaultPendingExpiryInterval is how often pending enrollments are checked
+// for expiry when PENDING_EXPIRY_INTERVAL is unset
+const DefaultPendingExpiryInterval = 5 * time.Minute
+
+// Config holds the server settings read from the environment
+type Config struct {
+	// Port is the TCP port the HTTP server listens on (PORT)
+	Port int
+
+	// Redis configures the cache connection (REDIS_ADDR, REDIS_USERNAME,
+	// REDIS_PASSWORD, REDIS_DB, REDIS_POOL_SIZE, REDIS_MIN_IDLE_CONNS, REDIS_TLS)
+	Redis cache.RedisConfig
+	// RedisReplicaAddr is the host:port of a read replica that serves cache
+	// reads with the same credentials, DB and TLS setting; empty reads from
+	// the primary (REDIS_REPLICA_ADDR)
+	RedisReplicaAddr string
+	// CacheTTL is the enrollment cache TTL (CACHE_TTL)
+	CacheTTL time.Duration
+	// CacheWarmLimit caps enrollments pre-loaded on startup; 0 disables (CACHE_WARM_LIMIT)
+	CacheWarmLimit int
+	// CacheFallbackSize caps the in-process cache used during Redis outages;
+	// 0 disables (CACHE_FALLBACK_SIZE)
+	CacheFallbackSize int
+	// CacheNamespace prefixes cache keys so environments can share a Redis
+	// instance; empty leaves keys unprefixed (CACHE_NAMESPACE)
+	CacheNamespace string
+	// CacheCompressThreshold gzips cached values whose JSON is longer than
+	// this many bytes; 0 disables compression (CACHE_COMPRESS_THRESHOLD)
+	CacheCompressThreshold int
+
+	// StorageBackend is memory, file or sqlite (STORAGE_BACKEND)
+	StorageBackend string
+	// DataFile and SnapshotInterval configure the file backend (DATA_FILE, SNAPSHOT_INTERVAL)
+	DataFile         string
+	SnapshotInterval time.Duration
+	// SQLitePath is the database file for the sqlite backend (SQLITE_PATH)
+	SQLitePath string
+	// PendingExpiry is how long an enrollment may stay pending before it is
+	// soft-deleted; unset disables expiry (PENDING_EXPIRY)
+	PendingExpiry time.Duration
+	// PendingExpiryInterval is how often expired pending enrollments are
+	// looked for (PENDING_EXPIRY_INTERVAL)
+	PendingExpiryInterval time.Duration
+	// SeedFile is a JSON array of enrollments imported at startup when the
+	// store is empty; empty disables the import (SEED_FILE)
+	SeedFile string
+
+	// MaxBodyBytes caps request bodies (MAX_BODY_BYTES)
+	MaxBodyBytes int64
+	// RequestTimeout bounds each request before it is answered with 503;
+	// zero disables the deadline (REQUEST_TIMEOUT)
+	RequestTimeout time.Duration
+	// RateLimitRPS and RateLimitBurst configure rate limiting; 0 RPS disables it
+	// (RATE_LIMIT_RPS, RATE_LIMIT_BURST)
+	RateLimitRPS   float64
+	RateLimitBurst int
+	// CORSAllowedOrigins and CORSAllowCredentials configure CORS
+	// (CORS_ALLOWED_ORIGINS, CORS_ALLOW_CREDENTIALS)
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+
+	// DebugBodies logs every request and response body; it is expensive, so
+	// meant only for diagnosing integration issues (DEBUG_BODIES)
+	DebugBodies bool
+	// DebugBodyBytes caps each logged body (DEBUG_BODIES_MAX_BYTES)
+	DebugBodyBytes int
+	// DebugRedactFields lists JSON fields masked in logged bodies
+	// (DEBUG_BODIES_REDACT)
+	DebugRedactFields []string
+
+	// ValidateRequests checks request bodies against the OpenAPI spec; turn
+	// it off to save the work on hot paths (VALIDATE_REQUESTS)
+	ValidateRequests bool
+	// ClampPageLimit lowers a list limit above the maximum page size to the
+	// maximum instead of rejecting it with 400 (CLAMP_PAGE_LIMIT)
+	ClampPageLimit bool
+	// DefaultEnrollmentStatus is given to created enrollments sent without a
+	// status; empty keeps status required (DEFAULT_ENROLLMENT_STATUS)
+	DefaultEnrollmentStatus string
+
+	// GradeScale maps scores to letter grades, e.g. "A:90,B:80,C:70,D:60,F:0"
+	// (GRADE_SCALE)
+	GradeScale models.GradeScale
+	// PassingScore is the lowest score reported as passed (PASSING_SCORE)
+	PassingScore float64
+
+	// WebhookURL receives enrollment lifecycle events; empty disables them (WEBHOOK_URL)
+	WebhookURL string
+	// WebhookSecret signs webhook payloads with HMAC-SHA256 (WEBHOOK_SECRET)
+	WebhookSecret string
+
+	// Env names the deployment environment, e.g. development or production (ENV)
+	Env string
+	// AdminAPIKey guards the admin endpoints; empty disables them (ADMIN_API_KEY)
+	AdminAPIKey string
+	// AllowProductionReset permits DELETE /api/enrollments/all when Env is
+	// production (ALLOW_PRODUCTION_RESET)
+	AllowProductionReset bool
+
+	// ShutdownTimeout bounds the drain of in-flight requests (SHUTDOWN_TIMEOUT)
+	ShutdownTimeout time.Duration
+}
+
+// Load reads the configuration from environment variables, applying defaults
+// for unset values. It returns a descriptive error naming the variable when a
+// value is set but malformed or out of range.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Port: 8080,
+		Redis: cache.RedisConfig{
+			Addr:         "localhost:6379",
+			Username:     os.Getenv("REDIS_USERNAME"),
+			Password:     os.Getenv("REDIS_PASSWORD"),
+			PoolSize:     10,
+			MinIdleConns: 5,
+		},
+		CacheTTL:              cache.EnrollmentCacheTTL,
+		CacheWarmLimit:        1000,
+		CacheFallbackSize:     cache.DefaultFallbackSize,
+		StorageBackend:        StorageMemory,
+		DataFile:              "enrollments.json",
+		SnapshotInterval:      repository.DefaultSnapshotInterval,
+		SQLitePath:            "techwave.db",
+		PendingExpiryInterval: DefaultPendingExpiryInterval,
+		SeedFile:              os.Getenv("SEED_FILE"),
+		MaxBodyBytes:          middleware.DefaultMaxBodyBytes,
+		RequestTimeout:        middleware.DefaultRequestTimeout,
+		DebugBodyBytes:        middleware.DefaultDebugBodyBytes,
+		DebugRedactFields:     middleware.DefaultRedactedFields,
+		WebhookSecret:         os.Getenv("WEBHOOK_SECRET"),
+		Env:                   os.Getenv("ENV"),
+		AdminAPIKey:           os.Getenv("ADMIN_API_KEY"),
+		ValidateRequests:      true,
+		GradeScale:            models.DefaultGradeScale,
+		PassingScore:          models.DefaultPassingScore,
+		ShutdownTimeout:       15 * time.Second,
+	}
+
+	if raw := os.Getenv("PORT"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > 65535 {
+			return nil, fmt.Errorf("invalid PORT %q: must be a number between 1 and 65535", raw)
+		}
+		cfg.Port = value
+	}
+
+	if raw := os.Getenv("REDIS_ADDR"); raw != "" {
+		cfg.Redis.Addr = raw
+	}
+	cfg.RedisReplicaAddr = os.Getenv("REDIS_REPLICA_ADDR")
+
+	var err error
+	if cfg.Redis.DB, err = nonNegativeInt("REDIS_DB", cfg.Redis.DB); err != nil {
+		return nil, err
+	}
+	if cfg.Redis.PoolSize, err = nonNegativeInt("REDIS_POOL_SIZE", cfg.Redis.PoolSize); err != nil {
+		return nil, err
+	}
+	if cfg.Redis.MinIdleConns, err = nonNegativeInt("REDIS_MIN_IDLE_CONNS", cfg.Redis.MinIdleConns); err != nil {
+		return nil, err
+	}
+	if cfg.Redis.TLS, err = boolEnv("REDIS_TLS", cfg.Redis.TLS); err != nil {
+		return nil, err
+	}
+	if cfg.CacheTTL, err = positiveDuration("CACHE_TTL", cfg.CacheTTL); err != nil {
+		return nil, err
+	}
+	if cfg.CacheWarmLimit, err = nonNegativeInt("CACHE_WARM_LIMIT", cfg.CacheWarmLimit); err != nil {
+		return nil, err
+	}
+	if cfg.CacheFallbackSize, err = nonNegativeInt("CACHE_FALLBACK_SIZE", cfg.CacheFallbackSize); err != nil {
+		return nil, err
+	}
+	if cfg.CacheCompressThreshold, err = nonNegativeInt("CACHE_COMPRESS_THRESHOLD", cfg.CacheCompressThreshold); err != nil {
+		return nil, err
+	}
+	if raw := os.Getenv("CACHE_NAMESPACE"); raw != "" {
+		// The namespace becomes part of a SCAN pattern, so glob characters are not allowed
+		if strings.IndexFunc(raw, invalidNamespaceRune) >= 0 {
+			return nil, fmt.Errorf("invalid CACHE_NAMESPACE %q: use only letters, digits, '-', '_' and '.'", raw)
+		}
+		cfg.CacheNamespace = raw
+	}
+
+	if raw := os.Getenv("STORAGE_BACKEND"); raw != "" {
+		switch raw {
+		case StorageMemory, StorageFile, StorageSQLite:
+			cfg.StorageBackend = raw
+		default:
+			return nil, fmt.Errorf("invalid STORAGE_BACKEND %q: must be one of memory, file, sqlite", raw)
+		}
+	}
+	if raw := os.Getenv("DATA_FILE"); raw != "" {
+		cfg.DataFile = raw
+	}
+	if cfg.SnapshotInterval, err = positiveDuration("SNAPSHOT_INTERVAL", cfg.SnapshotInterval); err != nil {
+		return nil, err
+	}
+	if raw := os.Getenv("SQLITE_PATH"); raw != "" {
+		cfg.SQLitePath = raw
+	}
+	if cfg.PendingExpiry, err = positiveDuration("PENDING_EXPIRY", cfg.PendingExpiry); err != nil {
+		return nil, err
+	}
+	if cfg.PendingExpiryInterval, err = positiveDuration("PENDING_EXPIRY_INTERVAL", cfg.PendingExpiryInterval); err != nil {
+		return nil, err
+	}
+
+	if raw := os.Getenv("MAX_BODY_BYTES"); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid MAX_BODY_BYTES %q: must be a positive number of bytes", raw)
+		}
+		cfg.MaxBodyBytes = value
+	}
+
+	if cfg.RequestTimeout, err = nonNegativeDuration("REQUEST_TIMEOUT", cfg.RequestTimeout); err != nil {
+		return nil, err
+	}
+
+	if raw := os.Getenv("RATE_LIMIT_RPS"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_RPS %q: must be a non-negative number", raw)
+		}
+		cfg.RateLimitRPS = value
+	}
+	if cfg.RateLimitRPS > 0 {
+		// Default burst allows one second's worth of requests at once
+		cfg.RateLimitBurst = int(math.Ceil(cfg.RateLimitRPS))
+		if raw := os.Getenv("RATE_LIMIT_BURST"); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value <= 0 {
+				return nil, fmt.Errorf("invalid RATE_LIMIT_BURST %q: must be a positive integer", raw)
+			}
+			cfg.RateLimitBurst = value
+		}
+	}
+
+	if raw := os.Getenv("CORS_ALLOWED_ORIGINS"); raw != "" {
+		cfg.CORSAllowedOrigins = strings.Split(raw, ",")
+	}
+	if cfg.CORSAllowCredentials, err = boolEnv("CORS_ALLOW_CREDENTIALS", cfg.CORSAllowCredentials); err != nil {
+		return nil, err
+	}
+	if cfg.DebugBodies, err = boolEnv("DEBUG_BODIES", cfg.DebugBodies); err != nil {
+		return nil, err
+	}
+	if raw := os.Getenv("DEBUG_BODIES_MAX_BYTES"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid DEBUG_BODIES_MAX_BYTES %q: must be a positive number of bytes", raw)
+		}
+		cfg.DebugBodyBytes = value
+	}
+	if raw := os.Getenv("DEBUG_BODIES_REDACT"); raw != "" {
+		cfg.DebugRedactFields = strings.Split(raw, ",")
+	}
+	if cfg.ValidateRequests, err = boolEnv("VALIDATE_REQUESTS", cfg.ValidateRequests); err != nil {
+		return nil, err
+	}
+	if cfg.ClampPageLimit, err = boolEnv("CLAMP_PAGE_LIMIT", cfg.ClampPageLimit); err != nil {
+		return nil, err
+	}
+
+	if raw := os.Getenv("DEFAULT_ENROLLMENT_STATUS"); raw != "" {
+		status := models.NormalizeStatus(raw)
+		if !models.ValidStatuses[status] {
+			return nil, fmt.Errorf("invalid DEFAULT_ENROLLMENT_STATUS %q: must be one of pending, active, completed, waitlisted", raw)
+		}
+		cfg.DefaultEnrollmentStatus = status
+	}
+
+	if raw := os.Getenv("GRADE_SCALE"); raw != "" {
+		scale, err := models.ParseGradeScale(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GRADE_SCALE %q: %v", raw, err)
+		}
+		cfg.GradeScale = scale
+	}
+	if raw := os.Getenv("PASSING_SCORE"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value > 100 {
+			return nil, fmt.Errorf("invalid PASSING_SCORE %q: must be a number above 0 and at most 100", raw)
+		}
+		cfg.PassingScore = value
+	}
+
+	if raw := os.Getenv("WEBHOOK_URL"); raw != "" {
+		parsed, err := url.Parse(raw)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid WEBHOOK_URL %q: must be an absolute http or https URL", raw)
+		}
+		cfg.WebhookURL = raw
+	}
+
+	if cfg.AllowProductionReset, err = boolEnv("ALLOW_PRODUCTION_RESET", cfg.AllowProductionReset); err != nil {
+		return nil, err
+	}
+
+	if cfg.ShutdownTimeout, err = positiveDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Addr returns the listen address for the configured port
+func (c *Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// positiveDuration parses the named variable as a Go duration, returning def
+// when it is unset
+func positiveDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration such as 30s or 5m", name, raw)
+	}
+	return value, nil
+}
+
+// nonNegativeDuration parses the named variable as a Go duration >= 0,
+// returning def when it is unset
+func nonNegativeDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative duration such as 30s, or 0", name, raw)
+	}
+	return value, nil
+}
+
+// nonNegativeInt parses the named variable as an integer >= 0, returning def
+// when it is unset
+func nonNegativeInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, raw)
+	}
+	return value, nil
+}
+
+// boolEnv parses the named variable as a boolean, returning def when it is unset
+func boolEnv(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", name, raw)
+	}
+	return value, nil
+}
+
+// invalidNamespaceRune reports whether r may not appear in CACHE_NAMESPACE
+func invalidNamespaceRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case r == '-', r == '_', r == '.':
+		return false
+	}
+	return true
+}
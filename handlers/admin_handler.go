@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"techwave/cache"
+	"techwave/jobs"
+	"techwave/middleware"
+	"techwave/models"
+	"techwave/repository"
+)
+
+// ProductionEnv is the ENV value that blocks destructive admin endpoints
+// unless they are explicitly allowed
+const ProductionEnv = "production"
+
+// AdminHandler handles administration requests such as storage resets and
+// grade recomputes. Every request must carry the configured admin key in the
+// X-API-Key header.
+type AdminHandler struct {
+	enrollments     repository.Store
+	grades          *repository.GradeRepository
+	cache           *cache.EnrollmentCache
+	scale           models.GradeScale
+	apiKey          string
+	env             string
+	allowProduction bool
+	jobs            *jobs.Queue
+}
+
+// NewAdminHandler creates a new admin handler. An empty apiKey disables the
+// admin endpoints; in the production env a reset also needs allowProduction.
+// Background work such as grade recomputes runs on queue.
+func NewAdminHandler(enrollments repository.Store, grades *repository.GradeRepository, cache *cache.EnrollmentCache, scale models.GradeScale, apiKey, env string, allowProduction bool, queue *jobs.Queue) *AdminHandler {
+	return &AdminHandler{
+		enrollments:     enrollments,
+		grades:          grades,
+		cache:           cache,
+		scale:           scale,
+		apiKey:          apiKey,
+		env:             env,
+		allowProduction: allowProduction,
+		jobs:            queue,
+	}
+}
+
+// resetResult reports what DELETE /api/enrollments/all removed
+type resetResult struct {
+	Deleted       int   `json:"deleted"`
+	GradesDeleted int   `json:"grades_deleted"`
+	CacheFlushed  int64 `json:"cache_keys_flushed"`
+}
+
+// ResetStore handles DELETE /api/enrollments/all
+// Permanently removes every enrollment and grade and flushes the cache
+// namespace. The audit trail is append-only and is kept. In the production
+// env the reset is refused unless allowProduction is set.
+func (h *AdminHandler) ResetStore(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	if h.env == ProductionEnv && !h.allowProduction {
+		respondWithError(w, r, http.StatusForbidden, models.CodeResetForbidden, "Resetting storage is not allowed in production")
+		return
+	}
+
+	deleted, err := h.enrollments.DeleteAll()
+	if err != nil {
+		log.Printf("Failed to reset enrollment storage: %v", err)
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to reset enrollments")
+		return
+	}
+	result := resetResult{
+		Deleted:       deleted,
+		GradesDeleted: h.grades.DeleteAll(),
+	}
+
+	if h.cache != nil {
+		// Storage is already empty, so flush even if the client has gone
+		flushed, err := h.cache.Clear(context.WithoutCancel(r.Context()))
+		if err != nil {
+			log.Printf("WARNING: Failed to flush cache after reset: %v", err)
+		}
+		result.CacheFlushed = flushed
+	}
+
+	log.Printf("Enrollment storage reset: %d enrollments, %d grades, %d cache keys removed",
+		result.Deleted, result.GradesDeleted, result.CacheFlushed)
+	respond(w, r, http.StatusOK, result)
+}
+
+// authorize writes an error response and returns false unless the admin
+// endpoints are enabled and the request carries the key
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.apiKey == "" {
+		respondWithError(w, r, http.StatusForbidden, models.CodeAdminDisabled, "Admin endpoints are disabled")
+		return false
+	}
+
+	key := r.Header.Get(middleware.APIKeyHeader)
+	if subtle.ConstantTimeCompare([]byte(key), []byte(h.apiKey)) != 1 {
+		respondWithError(w, r, http.StatusUnauthorized, models.CodeUnauthorized, "A valid X-API-Key header is required")
+		return false
+	}
+	return true
+}
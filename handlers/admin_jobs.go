@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"techwave/jobs"
+	"techwave/middleware"
+	"techwave/models"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// RecomputeBatchSize is how many grades a recompute job relabels per
+	// hold of the grade lock
+	RecomputeBatchSize = 100
+
+	// JobTypeRecomputeGrades re-derives every letter grade from the scale
+	JobTypeRecomputeGrades = "recompute-grades"
+)
+
+// RecomputeGrades handles POST /api/admin/recompute-grades
+// Queues a background job that re-derives every stored letter grade from the
+// current grade scale, including letters that were sent explicitly, and
+// answers 202 with the job. The grades are relabeled in batches of
+// RecomputeBatchSize so grade requests are only held up for one batch at a
+// time. GPAs are always derived from scores on read, so they need no
+// recompute.
+func (h *AdminHandler) RecomputeGrades(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	status, err := h.jobs.Enqueue(jobs.Job{
+		Type: JobTypeRecomputeGrades,
+		Run:  h.recomputeGrades,
+	})
+	if err != nil {
+		respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeJobQueueUnavailable, middleware.RetryAfterSeconds(middleware.DefaultRetryAfter), err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/admin/jobs/"+status.ID)
+	respond(w, r, http.StatusAccepted, status)
+}
+
+// recomputeGrades relabels every grade batch by batch, reporting progress
+// after each batch and stopping early once ctx is cancelled
+func (h *AdminHandler) recomputeGrades(ctx context.Context, progress *jobs.Progress) error {
+	ids := h.grades.IDs()
+	updated := 0
+	for start := 0; start < len(ids); start += RecomputeBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := ids[start:min(start+RecomputeBatchSize, len(ids))]
+		changed := h.grades.Relabel(batch, h.scale.Letter)
+		progress.Add(len(batch), changed)
+		updated += changed
+	}
+
+	log.Printf("Grade recompute finished: %d grades checked, %d relabeled", len(ids), updated)
+	return nil
+}
+
+// GetJob handles GET /api/admin/jobs/{id}
+func (h *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	status, exists := h.jobs.Status(mux.Vars(r)["id"])
+	if !exists {
+		respondWithError(w, r, http.StatusNotFound, models.CodeJobNotFound, "Job not found")
+		return
+	}
+
+	respond(w, r, http.StatusOK, status)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"techwave/cache"
+	"techwave/middleware"
+	"techwave/models"
+)
+
+// CacheHandler handles HTTP requests for cache administration
+type CacheHandler struct {
+	cache *cache.EnrollmentCache
+}
+
+// NewCacheHandler creates a new cache handler
+func NewCacheHandler(cache *cache.EnrollmentCache) *CacheHandler {
+	return &CacheHandler{
+		cache: cache,
+	}
+}
+
+// GetStats handles GET /api/cache/stats
+func (h *CacheHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		respondWithError(w, r, http.StatusServiceUnavailable, models.CodeCacheUnavailable, "Cache is disabled")
+		return
+	}
+
+	stats, err := h.cache.GetStats(r.Context())
+	if err != nil {
+		log.Printf("Failed to read cache stats: %v", err)
+		respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeCacheUnavailable, middleware.RetryAfterSeconds(middleware.DefaultRetryAfter), "Failed to retrieve cache stats")
+		return
+	}
+
+	respond(w, r, http.StatusOK, stats)
+}
+
+// ClearCache handles DELETE /api/cache
+func (h *CacheHandler) ClearCache(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		respondWithError(w, r, http.StatusServiceUnavailable, models.CodeCacheUnavailable, "Cache is disabled")
+		return
+	}
+
+	removed, err := h.cache.Clear(r.Context())
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to clear cache")
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]int64{"removed": removed})
+}
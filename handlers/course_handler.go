@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"techwave/models"
+	"techwave/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// courseStats is the response body for GET /api/courses/{courseId}/stats
+type courseStats struct {
+	CourseID string         `json:"course_id"`
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+	// GradeCount and AverageScore cover every grade recorded in the course;
+	// AverageScore is omitted until the first grade
+	GradeCount   int      `json:"grade_count"`
+	AverageScore *float64 `json:"average_score,omitempty"`
+}
+
+// capacityRequest is the body of POST /api/courses/{courseId}/capacity
+type capacityRequest struct {
+	Capacity *int `json:"capacity"`
+}
+
+// courseCapacity is the response body for POST /api/courses/{courseId}/capacity
+type courseCapacity struct {
+	CourseID  string `json:"course_id"`
+	Capacity  int    `json:"capacity"`
+	Active    int    `json:"active"`
+	Available int    `json:"available"`
+}
+
+// CourseHandler handles HTTP requests about a course as a whole
+type CourseHandler struct {
+	enrollments repository.Store
+	grades      *repository.GradeRepository
+	courses     *repository.CourseRepository
+}
+
+// NewCourseHandler creates a new course handler
+func NewCourseHandler(enrollments repository.Store, grades *repository.GradeRepository, courses *repository.CourseRepository) *CourseHandler {
+	return &CourseHandler{
+		enrollments: enrollments,
+		grades:      grades,
+		courses:     courses,
+	}
+}
+
+// SetCapacity handles POST /api/courses/{courseId}/capacity
+// The capacity caps the course's active enrollments. A course may be given a
+// capacity before it has any enrollments; lowering it below the current
+// number of active enrollments moves no one.
+func (h *CourseHandler) SetCapacity(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+
+	var req capacityRequest
+	if err := decodeStrict(r, &req); err != nil {
+		respondWithDecodeError(w, r, err)
+		return
+	}
+	if req.Capacity == nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, "capacity is required")
+		return
+	}
+	if *req.Capacity < 1 {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, "capacity must be a positive integer")
+		return
+	}
+
+	var active int
+	h.courses.WithSeats(func() error {
+		h.courses.SetCapacity(courseID, *req.Capacity)
+		active = h.enrollments.CountByStatus(repository.EnrollmentFilter{CourseID: courseID, Status: "active"})["active"]
+		return nil
+	})
+
+	respond(w, r, http.StatusOK, courseCapacity{
+		CourseID:  courseID,
+		Capacity:  *req.Capacity,
+		Active:    active,
+		Available: max(*req.Capacity-active, 0),
+	})
+}
+
+// GetCourseStats handles GET /api/courses/{courseId}/stats
+// Soft-deleted enrollments and their grades are left out.
+func (h *CourseHandler) GetCourseStats(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+
+	results, err := h.grades.GetGradesByCourse(courseID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeCourseNotFound, "Course has no enrollments")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve course")
+		return
+	}
+
+	stats := courseStats{
+		CourseID: courseID,
+		Total:    len(results),
+		ByStatus: make(map[string]int, len(models.ValidStatuses)),
+	}
+	for status := range models.ValidStatuses {
+		stats.ByStatus[status] = 0
+	}
+
+	var sum float64
+	for _, result := range results {
+		stats.ByStatus[result.Enrollment.Status]++
+		for _, grade := range result.Grades {
+			sum += grade.Score
+			stats.GradeCount++
+		}
+	}
+	if stats.GradeCount > 0 {
+		average := math.Round(sum/float64(stats.GradeCount)*100) / 100
+		stats.AverageScore = &average
+	}
+
+	respond(w, r, http.StatusOK, stats)
+}
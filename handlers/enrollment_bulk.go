@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"techwave/middleware"
+	"techwave/models"
+	"techwave/notify"
+	"techwave/repository"
+)
+
+// bulkCreateResult reports the outcome of one item in a bulk create request
+type bulkCreateResult struct {
+	Index  int                     `json:"index"`
+	ID     string                  `json:"id,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+	Errors models.ValidationErrors `json:"errors,omitempty"`
+}
+
+// BulkCreateEnrollments handles POST /api/enrollments/bulk
+// Each item is validated and inserted independently; failures are reported
+// per item without rolling back the items that succeeded. Items are seated in
+// order, so once a course fills up its later pending or active items are
+// waitlisted.
+func (h *EnrollmentHandler) BulkCreateEnrollments(w http.ResponseWriter, r *http.Request) {
+	var requests []models.Enrollment
+	if err := decodeJSON(r, &requests); err != nil {
+		respondWithDecodeError(w, r, err)
+		return
+	}
+
+	if len(requests) == 0 {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, "At least one enrollment is required")
+		return
+	}
+
+	results := make([]bulkCreateResult, len(requests))
+	batch := make([]*models.Enrollment, 0, len(requests))
+	batchIndexes := make([]int, 0, len(requests))
+
+	for i := range requests {
+		results[i].Index = i
+		enrollment := &requests[i]
+
+		h.applyDefaultStatus(enrollment)
+		if err := enrollment.Validate(); err != nil {
+			results[i].Error = err.Error()
+			errors.As(err, &results[i].Errors)
+			continue
+		}
+
+		h.prepareNewEnrollment(enrollment)
+		batch = append(batch, enrollment)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	courseIDs := make([]string, len(batch))
+	for j, enrollment := range batch {
+		courseIDs[j] = enrollment.CourseID
+	}
+
+	var errs []error
+	err := h.withSeats(r.Context(), courseIDs, func() error {
+		claimed := make(map[string]int)
+		for _, enrollment := range batch {
+			h.admit(enrollment, claimed)
+		}
+		errs = h.repo.CreateBatch(batch)
+		return nil
+	})
+	if err != nil {
+		respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeSeatsBusy, middleware.RetryAfterSeconds(SeatLockWait), err.Error())
+		return
+	}
+
+	created := 0
+	for j, err := range errs {
+		i := batchIndexes[j]
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].ID = batch[j].ID
+		h.recordAudit(batch[j].ID, models.AuditActionCreate, "", batch[j].Status)
+		h.notify(notify.EventEnrollmentCreated, batch[j])
+		created++
+	}
+
+	// The cached list no longer includes every enrollment
+	if created > 0 {
+		h.invalidateList(r.Context())
+	}
+
+	status := http.StatusCreated
+	if created < len(requests) {
+		status = http.StatusMultiStatus
+	}
+
+	respond(w, r, status, results)
+}
+
+// bulkStatusRequest is the body of POST /api/enrollments/bulk-status
+type bulkStatusRequest struct {
+	CourseID   string `json:"course_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+}
+
+// bulkStatusResult reports how many enrollments a bulk status update changed
+type bulkStatusResult struct {
+	Updated int `json:"updated"`
+}
+
+// BulkUpdateStatus handles POST /api/enrollments/bulk-status
+// Moves every live enrollment in a course from one status to another in a
+// single repository operation, e.g. active to completed at term end. Moving
+// enrollments to active needs a free seat for each of them; otherwise
+// nothing changes and 409 is returned.
+func (h *EnrollmentHandler) BulkUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	var req bulkStatusRequest
+	if err := decodeStrict(r, &req); err != nil {
+		respondWithDecodeError(w, r, err)
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, err.Error())
+		return
+	}
+
+	var updated []*models.Enrollment
+	err := h.withSeats(r.Context(), []string{req.CourseID}, func() error {
+		if err := h.checkSeats(req); err != nil {
+			return err
+		}
+		var err error
+		updated, err = h.repo.UpdateStatusForCourse(req.CourseID, req.FromStatus, req.ToStatus)
+		return err
+	})
+	if err != nil {
+		if err == repository.ErrCourseFull {
+			respondWithError(w, r, http.StatusConflict, models.CodeCourseFull, err.Error())
+			return
+		}
+		if err == errSeatsBusy {
+			respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeSeatsBusy, middleware.RetryAfterSeconds(SeatLockWait), err.Error())
+			return
+		}
+		var transitionErr *models.TransitionError
+		if errors.As(err, &transitionErr) {
+			respondWithError(w, r, http.StatusConflict, models.CodeInvalidTransition, transitionErr.Error())
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to update enrollments")
+		return
+	}
+
+	h.invalidateMany(r.Context(), updated)
+	for _, enrollment := range updated {
+		h.recordAudit(enrollment.ID, models.AuditActionUpdate, req.FromStatus, req.ToStatus)
+		h.notify(notify.EventEnrollmentUpdated, enrollment)
+	}
+
+	respond(w, r, http.StatusOK, bulkStatusResult{Updated: len(updated)})
+}
+
+// bulkDeleteResult reports how many enrollments a bulk delete removed
+type bulkDeleteResult struct {
+	Deleted int `json:"deleted"`
+}
+
+// DeleteEnrollments handles DELETE /api/enrollments
+// Soft-deletes every live enrollment matching the student_id, course_id,
+// status, from and to query parameters in a single repository operation. At
+// least one of them is required so a bare request cannot delete everything.
+func (h *EnrollmentHandler) DeleteEnrollments(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
+	if filter.IsEmpty() {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "at least one of student_id, course_id, status, from or to is required")
+		return
+	}
+
+	deleted, err := h.repo.DeleteWhere(filter)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to delete enrollments")
+		return
+	}
+
+	h.afterDeleteMany(r.Context(), deleted, models.AuditActionDelete)
+
+	respond(w, r, http.StatusOK, bulkDeleteResult{Deleted: len(deleted)})
+}
+
+// afterDeleteMany evicts enrollments removed by Store.DeleteWhere from the
+// cache, records action in each audit trail and announces the deletions
+func (h *EnrollmentHandler) afterDeleteMany(ctx context.Context, deleted []*models.Enrollment, action string) {
+	h.invalidateMany(ctx, deleted)
+	for _, enrollment := range deleted {
+		h.recordAudit(enrollment.ID, action, enrollment.Status, "")
+		h.notify(notify.EventEnrollmentDeleted, enrollment)
+	}
+}
+
+// checkSeats returns repository.ErrCourseFull when the course lacks a seat
+// for every enrollment req would make active
+func (h *EnrollmentHandler) checkSeats(req bulkStatusRequest) error {
+	if req.ToStatus != "active" || req.FromStatus == "active" || !models.CanTransition(req.FromStatus, req.ToStatus) {
+		return nil
+	}
+
+	free, limited := h.freeSeats(req.CourseID)
+	if !limited {
+		return nil
+	}
+	moving := h.repo.CountByStatus(repository.EnrollmentFilter{CourseID: req.CourseID, Status: req.FromStatus})[req.FromStatus]
+	if moving > free {
+		return repository.ErrCourseFull
+	}
+	return nil
+}
+
+// validate normalizes both statuses and checks that the course and both
+// statuses are present and valid
+func (req *bulkStatusRequest) validate() error {
+	req.FromStatus = models.NormalizeStatus(req.FromStatus)
+	req.ToStatus = models.NormalizeStatus(req.ToStatus)
+	if req.CourseID == "" {
+		return errors.New("course_id is required")
+	}
+	if req.FromStatus == "" {
+		return errors.New("from_status is required")
+	}
+	if req.ToStatus == "" {
+		return errors.New("to_status is required")
+	}
+	if !models.ValidStatuses[req.FromStatus] {
+		return errors.New("from_status must be one of: pending, active, completed, waitlisted")
+	}
+	if !models.ValidStatuses[req.ToStatus] {
+		return errors.New("to_status must be one of: pending, active, completed, waitlisted")
+	}
+	return nil
+}
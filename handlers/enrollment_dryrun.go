@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"techwave/models"
+	"techwave/repository"
+	"time"
+)
+
+// parseDryRun reads the dry_run query parameter. A dry run validates a write
+// and returns the enrollment it would store without changing anything.
+func parseDryRun(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("dry_run")
+	if raw == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be true or false")
+	}
+	return dryRun, nil
+}
+
+// previewCreate makes the checks Store.Create would and fills in the fields
+// it would set, without storing the enrollment
+func (h *EnrollmentHandler) previewCreate(enrollment *models.Enrollment) error {
+	if taken := h.repo.Find(repository.EnrollmentFilter{ID: enrollment.ID, IncludeDeleted: true}); len(taken) > 0 {
+		return repository.ErrIDTaken
+	}
+	if err := h.checkDuplicate("", enrollment); err != nil {
+		return err
+	}
+
+	enrollment.Version = 1
+	enrollment.TrackCompletion(nil, time.Now())
+	return nil
+}
+
+// previewUpdate makes the checks Store.Update would make when replacing
+// existing with enrollment and fills in the fields it would set, without
+// storing the change
+func (h *EnrollmentHandler) previewUpdate(existing, enrollment *models.Enrollment) error {
+	if enrollment.Version != existing.Version {
+		return repository.ErrVersionConflict
+	}
+	if !models.CanTransition(existing.Status, enrollment.Status) {
+		return &models.TransitionError{From: existing.Status, To: enrollment.Status}
+	}
+	if err := h.checkDuplicate(existing.ID, enrollment); err != nil {
+		return err
+	}
+
+	if enrollment.CreatedAt.IsZero() {
+		enrollment.CreatedAt = existing.CreatedAt
+	}
+	if enrollment.EnrollmentDate.IsZero() {
+		enrollment.EnrollmentDate = existing.EnrollmentDate
+	}
+	enrollment.Version = existing.Version + 1
+	enrollment.TrackCompletion(existing, time.Now())
+	return nil
+}
+
+// checkDuplicate returns repository.ErrAlreadyExists when another live
+// enrollment than id holds the student's place in the course
+func (h *EnrollmentHandler) checkDuplicate(id string, enrollment *models.Enrollment) error {
+	matches := h.repo.Find(repository.EnrollmentFilter{
+		StudentID: enrollment.StudentID,
+		CourseID:  enrollment.CourseID,
+	})
+	for _, match := range matches {
+		if match.ID != id {
+			return repository.ErrAlreadyExists
+		}
+	}
+	return nil
+}
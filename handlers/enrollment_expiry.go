@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+	"techwave/models"
+	"techwave/repository"
+	"time"
+)
+
+// ExpirePending soft-deletes every pending enrollment created before cutoff
+// and returns them. Like a DELETE, each one is evicted from the cache and
+// announced as deleted, and its audit trail records an expire action; it can
+// still be restored.
+func (h *EnrollmentHandler) ExpirePending(ctx context.Context, cutoff time.Time) ([]*models.Enrollment, error) {
+	expired, err := h.repo.DeleteWhere(repository.EnrollmentFilter{
+		Status:        "pending",
+		CreatedBefore: cutoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.afterDeleteMany(ctx, expired, models.AuditActionExpire)
+	return expired, nil
+}
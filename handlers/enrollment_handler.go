@@ -1,133 +1,549 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"techwave/cache"
+	"techwave/middleware"
 	"techwave/models"
+	"techwave/notify"
 	"techwave/repository"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"golang.org/x/sync/singleflight"
 )
 
+const (
+	// DefaultPageLimit is the page size used when no limit is supplied
+	DefaultPageLimit = 50
+	// MaxPageLimit is the largest page size a client may request
+	MaxPageLimit = 500
+	// MaxJSONIndent is the most spaces a client may ask JSON to be indented by
+	MaxJSONIndent = 8
+	// DefaultRecentLimit is how many recent enrollments are returned when no
+	// limit is supplied
+	DefaultRecentLimit = 10
+	// MaxRecentLimit caps the limit of GET /api/enrollments/recent
+	MaxRecentLimit = 100
+	// MaxIDAttempts is how many generated IDs CreateEnrollment tries before
+	// giving up on a run of ID collisions
+	MaxIDAttempts = 3
+)
+
+// errInvalidID is returned for an enrollment id that is not a UUID
+var errInvalidID = errors.New("invalid enrollment id format")
+
+// enrollmentPage is the paginated response body for GET /api/enrollments
+type enrollmentPage struct {
+	Data   []*models.Enrollment `json:"data"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
 // EnrollmentHandler handles HTTP requests for enrollments
 type EnrollmentHandler struct {
-	repo  *repository.EnrollmentRepository
+	repo  repository.Store
 	cache *cache.EnrollmentCache
+	audit *repository.AuditRepository
+	// courses holds seat capacities checked whenever an enrollment would
+	// become active
+	courses *repository.CourseRepository
+	// notifier publishes lifecycle events; nil disables notifications
+	notifier notify.Notifier
+	// loads coalesces concurrent repository loads of the same enrollment id
+	loads singleflight.Group
+	// newID generates the IDs of created enrollments
+	newID func() string
+	// clampPageLimit lowers a list limit above MaxPageLimit to the maximum
+	// instead of rejecting it
+	clampPageLimit bool
+	// defaultStatus is given to created enrollments that omit a status;
+	// empty keeps status required
+	defaultStatus string
 }
 
-// NewEnrollmentHandler creates a new enrollment handler
-func NewEnrollmentHandler(repo *repository.EnrollmentRepository, cache *cache.EnrollmentCache) *EnrollmentHandler {
+// EnrollmentHandlerOptions configures an EnrollmentHandler. Zero values use
+// the defaults.
+type EnrollmentHandlerOptions struct {
+	// Notifier publishes lifecycle events; nil disables notifications
+	Notifier notify.Notifier
+	// NewID generates the IDs of created enrollments; nil uses random UUIDs
+	NewID func() string
+	// ClampPageLimit lowers a list limit above MaxPageLimit to it rather
+	// than answering 400
+	ClampPageLimit bool
+	// DefaultStatus is applied to created enrollments sent without a status
+	// instead of rejecting them; it must be a valid status. Empty keeps
+	// status required.
+	DefaultStatus string
+}
+
+// NewEnrollmentHandler creates a new enrollment handler configured by opts
+func NewEnrollmentHandler(repo repository.Store, cache *cache.EnrollmentCache, audit *repository.AuditRepository, courses *repository.CourseRepository, opts EnrollmentHandlerOptions) *EnrollmentHandler {
+	newID := opts.NewID
+	if newID == nil {
+		newID = uuid.NewString
+	}
 	return &EnrollmentHandler{
-		repo:  repo,
-		cache: cache,
+		repo:           repo,
+		cache:          cache,
+		audit:          audit,
+		courses:        courses,
+		notifier:       opts.Notifier,
+		newID:          newID,
+		clampPageLimit: opts.ClampPageLimit,
+		defaultStatus:  models.NormalizeStatus(opts.DefaultStatus),
 	}
 }
 
 // CreateEnrollment handles POST /api/enrollments
+// When an Idempotency-Key header is sent and the key was already used within
+// the last 24 hours, the originally created enrollment is returned instead of
+// creating a new one. The key is reserved in Redis before the create runs, so
+// a concurrent request with the same key gets 409 rather than creating a
+// second enrollment. Idempotency requires the cache to be enabled.
+// A pending or active enrollment in a course with no free seat is stored as
+// waitlisted.
+// With ?dry_run=true the enrollment is validated and returned with 200 as it
+// would be stored, generated ID included, but nothing is written: no
+// repository or cache change, audit entry, notification or idempotency key.
+// The body may carry its own UUID as id, which is kept and answered with 409
+// when already taken. Otherwise an ID is generated; if it is already taken a
+// new one is generated, up to MaxIDAttempts times, before answering 500.
 func (h *EnrollmentHandler) CreateEnrollment(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseDryRun(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if dryRun {
+		idempotencyKey = ""
+	}
+	// The key is reserved before anything is created and released again
+	// unless the create succeeds, so concurrent retries cannot both create
+	var reservation *cache.Reservation
+	staleKey := false
+	if idempotencyKey != "" && h.cache != nil {
+		var id string
+		reservation, id, err = h.cache.ReserveIdempotent(r.Context(), idempotencyKey)
+		switch {
+		case err == cache.ErrIdempotencyInFlight:
+			respondWithError(w, r, http.StatusConflict, models.CodeIdempotencyInFlight, "A request with this Idempotency-Key is still in progress")
+			return
+		case err != nil:
+			log.Printf("WARNING: Idempotency key unavailable, creating without it: %v", err)
+		case id != "":
+			if existing, err := h.repo.GetByID(r.Context(), id); err == nil {
+				w.Header().Set("Idempotent-Replayed", "true")
+				respond(w, r, http.StatusCreated, existing)
+				return
+			}
+			// The original enrollment is gone, so the key is repointed below
+			staleKey = true
+		}
+		defer func() {
+			if reservation != nil {
+				if err := reservation.Release(context.WithoutCancel(r.Context())); err != nil {
+					log.Printf("Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+	}
+
 	var enrollment models.Enrollment
 
-	if err := json.NewDecoder(r.Body).Decode(&enrollment); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := decodeStrict(r, &enrollment); err != nil {
+		respondWithDecodeError(w, r, err)
 		return
 	}
 
 	// Validate the enrollment
+	h.applyDefaultStatus(&enrollment)
 	if err := enrollment.Validate(); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithValidationError(w, r, err)
 		return
 	}
 
-	// Set timestamps and generate ID
-	enrollment.ID = uuid.New().String()
-	enrollment.CreatedAt = time.Now()
-	enrollment.UpdatedAt = time.Now()
+	// A client-supplied ID is kept as long as it is a UUID
+	suppliedID := enrollment.ID
+	if suppliedID != "" {
+		if suppliedID, err = normalizeID(suppliedID); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+			return
+		}
+	}
 
-	// Set enrollment date if not provided
-	if enrollment.EnrollmentDate.IsZero() {
-		enrollment.EnrollmentDate = time.Now()
+	// Set timestamps and generate ID
+	h.prepareNewEnrollment(&enrollment)
+	if suppliedID != "" {
+		enrollment.ID = suppliedID
 	}
 
-	// Create the enrollment
-	if err := h.repo.Create(&enrollment); err != nil {
+	// Create the enrollment, waitlisting it if the course is full
+	err = h.withSeats(r.Context(), []string{enrollment.CourseID}, func() error {
+		h.admit(&enrollment, nil)
+		if dryRun {
+			return h.previewCreate(&enrollment)
+		}
+		if suppliedID != "" {
+			return h.repo.Create(r.Context(), &enrollment)
+		}
+		return h.createWithFreshID(r.Context(), &enrollment)
+	})
+	if err != nil {
 		if err == repository.ErrAlreadyExists {
-			respondWithError(w, http.StatusConflict, "Enrollment already exists")
+			respondWithError(w, r, http.StatusConflict, models.CodeEnrollmentExists, "Enrollment already exists")
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create enrollment")
+		if err == repository.ErrIDTaken && suppliedID != "" {
+			respondWithError(w, r, http.StatusConflict, models.CodeEnrollmentIDTaken, "Enrollment ID already exists")
+			return
+		}
+		if err == errSeatsBusy {
+			respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeSeatsBusy, middleware.RetryAfterSeconds(SeatLockWait), err.Error())
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to create enrollment")
+		return
+	}
+	if dryRun {
+		respond(w, r, http.StatusOK, enrollment)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, enrollment)
+	// The cached list no longer includes every enrollment
+	h.invalidateList(r.Context())
+
+	// The enrollment exists now, so record the key even if the client has gone
+	if reservation != nil {
+		if err := reservation.Complete(context.WithoutCancel(r.Context()), enrollment.ID); err != nil {
+			log.Printf("Failed to record idempotency key: %v", err)
+		} else {
+			reservation = nil
+		}
+	} else if staleKey {
+		if err := h.cache.SetIdempotent(context.WithoutCancel(r.Context()), idempotencyKey, enrollment.ID); err != nil {
+			log.Printf("Failed to record idempotency key: %v", err)
+		}
+	}
+
+	h.recordAudit(enrollment.ID, models.AuditActionCreate, "", enrollment.Status)
+	h.notify(notify.EventEnrollmentCreated, &enrollment)
+
+	respond(w, r, http.StatusCreated, enrollment)
 }
 
 // GetEnrollment handles GET /api/enrollments/{id}
-// Implements cache-aside pattern with Redis caching
+// Implements cache-aside pattern with Redis caching. Cache hits report the
+// tier that served them in X-Cache-Tier. If Redis is unreachable the response
+// carries X-Cache-Degraded: true; with the in-process fallback enabled the
+// enrollment is cached there, otherwise it is served from the repository with
+// X-Cache-Status: SKIP.
+// Responses carry an ETag and a Last-Modified time; a matching If-None-Match,
+// or an If-Modified-Since no older than the enrollment, returns 304.
 func (h *EnrollmentHandler) GetEnrollment(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	enrollment, err := h.lookupEnrollment(w, r, id)
+	if err == repository.ErrNotFound {
+		respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve enrollment")
+		return
+	}
+
+	respondWithEnrollment(w, r, enrollment)
+}
+
+// HeadEnrollment handles HEAD /api/enrollments/{id}
+// An existence check: it answers 200 when the enrollment exists and 404 when
+// it does not, with no body either way. The enrollment is found exactly as
+// GetEnrollment finds it, cache first, so the response carries the same
+// cache, ETag and Last-Modified headers and honours the same conditional
+// headers with 304.
+func (h *EnrollmentHandler) HeadEnrollment(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
+	enrollment, err := h.lookupEnrollment(w, r, id)
+	if err == repository.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if setEnrollmentHeaders(w, r, enrollment) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// lookupEnrollment finds an enrollment for GetEnrollment and HeadEnrollment,
+// trying the cache first and setting the cache status, tier and degraded
+// headers. Returns repository.ErrNotFound when there is no such enrollment.
+func (h *EnrollmentHandler) lookupEnrollment(w http.ResponseWriter, r *http.Request, id string) (*models.Enrollment, error) {
 	// Try to get from cache first
-	if h.cache != nil {
-		cachedEnrollment, err := h.cache.Get(id)
+	useCache := h.cache != nil
+	if useCache {
+		cachedEnrollment, tier, err := h.cache.Lookup(r.Context(), id)
 		if err == nil && cachedEnrollment != nil {
 			// Cache HIT
-			w.Header().Set("X-Cache-Status", "HIT")
-			respondWithJSON(w, http.StatusOK, cachedEnrollment)
-			return
+			middleware.SetCacheStatus(r, middleware.CacheHit)
+			w.Header().Set("X-Cache-Tier", string(tier))
+			if tier == cache.TierMemory {
+				w.Header().Set("X-Cache-Degraded", "true")
+			}
+			return cachedEnrollment, nil
+		}
+		if err != nil && h.cache.Ping(r.Context()) != nil {
+			// Redis is down - use the fallback tier if there is one, else
+			// bypass the cache for this request
+			log.Printf("Cache degraded, serving enrollment ID %s from repository: %v", id, err)
+			w.Header().Set("X-Cache-Degraded", "true")
+			useCache = h.cache.HasFallback()
+		} else {
+			// Cache MISS - continue to database
+			log.Printf("Cache MISS for enrollment ID: %s", id)
 		}
-		// Cache MISS - continue to database
-		log.Printf("Cache MISS for enrollment ID: %s", id)
 	}
 
-	// Get from database
-	enrollment, err := h.repo.GetByID(id)
+	// Get from database; concurrent misses for the same id share one load
+	enrollment, err := h.loadEnrollment(r.Context(), id, useCache)
 	if err != nil {
-		if err == repository.ErrNotFound {
-			respondWithError(w, http.StatusNotFound, "Enrollment not found")
-			return
+		return nil, err
+	}
+	if useCache {
+		middleware.SetCacheStatus(r, middleware.CacheMiss)
+	}
+	return enrollment, nil
+}
+
+// loadEnrollment reads an enrollment from the repository and, when useCache
+// is set, stores it in the cache (cache-aside pattern). Concurrent calls for
+// the same id wait for a single in-flight load instead of each hitting the
+// repository. The load is detached from the first caller's cancellation so
+// one client disconnecting does not fail the others waiting on it, but it
+// keeps that caller's deadline so a stuck repository cannot hold it forever.
+func (h *EnrollmentHandler) loadEnrollment(ctx context.Context, id string, useCache bool) (*models.Enrollment, error) {
+	result, err, _ := h.loads.Do(id, func() (interface{}, error) {
+		deadline, hasDeadline := ctx.Deadline()
+		ctx := context.WithoutCancel(ctx)
+		if hasDeadline {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve enrollment")
+
+		enrollment, err := h.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Store in cache for next time
+		if useCache {
+			if err := h.cache.Set(ctx, enrollment); err != nil {
+				log.Printf("Failed to cache enrollment: %v", err)
+				// Don't fail the request if caching fails
+			}
+		}
+		return enrollment, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.Enrollment), nil
+}
+
+// GetAllEnrollments handles GET /api/enrollments
+// Supports limit and offset query parameters for pagination,
+// student_id, course_id and status query parameters for filtering, and
+// sort and order query parameters for ordering. A limit above MaxPageLimit
+// is answered with 400, or when clamping is enabled served as MaxPageLimit
+// with a Warning header.
+func (h *EnrollmentHandler) GetAllEnrollments(w http.ResponseWriter, r *http.Request) {
+	limit, offset, clamped, err := parsePagination(r, h.clampPageLimit)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
+	if clamped {
+		w.Header().Set("Warning", fmt.Sprintf(`299 - "limit clamped to %d"`, MaxPageLimit))
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	sortField, sortOrder, err := parseSort(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
 		return
 	}
 
-	// Store in cache for next time (cache-aside pattern)
-	if h.cache != nil {
-		if err := h.cache.Set(enrollment); err != nil {
-			log.Printf("Failed to cache enrollment: %v", err)
-			// Don't fail the request if caching fails
+	var matches []*models.Enrollment
+	if h.cache != nil && !filter.IncludeDeleted {
+		// Serve from the cached list of live enrollments
+		matches = make([]*models.Enrollment, 0)
+		for _, enrollment := range h.listEnrollments(r) {
+			if filter.Matches(enrollment) {
+				matches = append(matches, enrollment)
+			}
 		}
+	} else {
+		matches = h.repo.Find(filter)
 	}
 
-	// Set cache status to MISS
-	w.Header().Set("X-Cache-Status", "MISS")
-	respondWithJSON(w, http.StatusOK, enrollment)
+	if sortField != "" {
+		// Already validated by parseSort
+		repository.SortEnrollments(matches, sortField, sortOrder)
+	}
+	enrollments, total := repository.Paginate(matches, limit, offset)
+
+	respond(w, r, http.StatusOK, enrollmentPage{
+		Data:   enrollments,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
 }
 
-// GetAllEnrollments handles GET /api/enrollments
-func (h *EnrollmentHandler) GetAllEnrollments(w http.ResponseWriter, r *http.Request) {
-	enrollments := h.repo.GetAll()
-	respondWithJSON(w, http.StatusOK, enrollments)
+// enrollmentCount is the response body for GET /api/enrollments/count
+type enrollmentCount struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// CountEnrollments handles GET /api/enrollments/count
+// Accepts the same filter query parameters as GetAllEnrollments
+func (h *EnrollmentHandler) CountEnrollments(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	byStatus := h.repo.CountByStatus(filter)
+	total := 0
+	for _, count := range byStatus {
+		total += count
+	}
+
+	respond(w, r, http.StatusOK, enrollmentCount{
+		Total:    total,
+		ByStatus: byStatus,
+	})
+}
+
+// SearchEnrollments handles GET /api/enrollments/search?q=
+// Returns live enrollments whose student or course ID contains q (ignoring
+// case), prefix matches first. Search always reads from the repository.
+func (h *EnrollmentHandler) SearchEnrollments(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "q is required")
+		return
+	}
+
+	respond(w, r, http.StatusOK, h.repo.Search(q))
+}
+
+// RecentEnrollments handles GET /api/enrollments/recent?limit=
+// Returns the most recently created live enrollments, newest first. Larger
+// limits are capped at MaxRecentLimit. Like search, it always reads from the
+// repository.
+func (h *EnrollmentHandler) RecentEnrollments(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultRecentLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "limit must be a positive integer")
+			return
+		}
+		limit = min(value, MaxRecentLimit)
+	}
+
+	respond(w, r, http.StatusOK, h.repo.Recent(limit))
 }
 
 // UpdateEnrollment handles PUT /api/enrollments/{id}
+// With ?dry_run=true the update is checked as usual and the enrollment is
+// returned as it would be stored, but nothing is written.
 func (h *EnrollmentHandler) UpdateEnrollment(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	dryRun, err := parseDryRun(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, err.Error())
+		return
+	}
 
 	var enrollment models.Enrollment
-	if err := json.NewDecoder(r.Body).Decode(&enrollment); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if err := decodeStrict(r, &enrollment); err != nil {
+		respondWithDecodeError(w, r, err)
 		return
 	}
 
 	// Validate the enrollment
 	if err := enrollment.Validate(); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithValidationError(w, r, err)
+		return
+	}
+
+	existing, err := h.repo.GetByID(r.Context(), id)
+	if err == repository.ErrNotFound {
+		respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to update enrollment")
+		return
+	}
+
+	// The expected version comes from If-Match (an ETag from a previous
+	// response) or from the version field in the body
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		etag, err := enrollmentETag(existing)
+		if err != nil || !etagMatches(ifMatch, etag) {
+			respondWithError(w, r, http.StatusConflict, models.CodeVersionConflict, "version conflict")
+			return
+		}
+		if enrollment.Version == 0 {
+			enrollment.Version = existing.Version
+		}
+	}
+	if enrollment.Version == 0 {
+		respondWithError(w, r, http.StatusPreconditionRequired, models.CodeVersionRequired, "version or If-Match header is required")
 		return
 	}
 
@@ -135,65 +551,521 @@ func (h *EnrollmentHandler) UpdateEnrollment(w http.ResponseWriter, r *http.Requ
 	enrollment.ID = id
 	enrollment.UpdatedAt = time.Now()
 
-	// Update the enrollment
-	if err := h.repo.Update(id, &enrollment); err != nil {
+	// Update the enrollment; the repository re-checks the version atomically
+	err = h.withSeats(r.Context(), []string{enrollment.CourseID}, func() error {
+		if err := h.checkSeat(existing, &enrollment); err != nil {
+			return err
+		}
+		if dryRun {
+			return h.previewUpdate(existing, &enrollment)
+		}
+		return h.repo.Update(r.Context(), id, &enrollment)
+	})
+	if err != nil {
 		if err == repository.ErrNotFound {
-			respondWithError(w, http.StatusNotFound, "Enrollment not found")
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		if err == repository.ErrCourseFull {
+			respondWithError(w, r, http.StatusConflict, models.CodeCourseFull, err.Error())
+			return
+		}
+		if err == repository.ErrVersionConflict {
+			respondWithError(w, r, http.StatusConflict, models.CodeVersionConflict, "version conflict")
+			return
+		}
+		var transitionErr *models.TransitionError
+		if errors.As(err, &transitionErr) {
+			respondWithError(w, r, http.StatusConflict, models.CodeInvalidTransition, transitionErr.Error())
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to update enrollment")
+		if err == repository.ErrAlreadyExists {
+			respondWithError(w, r, http.StatusConflict, models.CodeEnrollmentExists, "Enrollment already exists")
+			return
+		}
+		if err == errSeatsBusy {
+			respondWithRetry(w, r, http.StatusServiceUnavailable, models.CodeSeatsBusy, middleware.RetryAfterSeconds(SeatLockWait), err.Error())
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to update enrollment")
+		return
+	}
+	if dryRun {
+		respond(w, r, http.StatusOK, enrollment)
 		return
 	}
 
 	// Invalidate cache after update
-	if h.cache != nil {
-		if err := h.cache.Delete(id); err != nil {
-			log.Printf("Failed to invalidate cache for enrollment %s: %v", id, err)
-		}
-	}
+	h.invalidateCache(r.Context(), id)
 
-	respondWithJSON(w, http.StatusOK, enrollment)
+	h.recordAudit(id, models.AuditActionUpdate, existing.Status, enrollment.Status)
+	h.notify(notify.EventEnrollmentUpdated, &enrollment)
+
+	// Return the new ETag so the client can chain further conditional updates
+	if etag, err := enrollmentETag(&enrollment); err == nil {
+		w.Header().Set("ETag", etag)
+	}
+	respond(w, r, http.StatusOK, enrollment)
 }
 
 // DeleteEnrollment handles DELETE /api/enrollments/{id}
+// Enrollments are soft-deleted and can be brought back via the restore endpoint
 func (h *EnrollmentHandler) DeleteEnrollment(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	existing, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to delete enrollment")
+		return
+	}
 
-	if err := h.repo.Delete(id); err != nil {
+	if err := h.repo.Delete(r.Context(), id); err != nil {
 		if err == repository.ErrNotFound {
-			respondWithError(w, http.StatusNotFound, "Enrollment not found")
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete enrollment")
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to delete enrollment")
 		return
 	}
 
 	// Invalidate cache after delete
-	if h.cache != nil {
-		if err := h.cache.Delete(id); err != nil {
-			log.Printf("Failed to invalidate cache for enrollment %s: %v", id, err)
+	h.invalidateCache(r.Context(), id)
+
+	h.recordAudit(id, models.AuditActionDelete, existing.Status, "")
+	h.notify(notify.EventEnrollmentDeleted, existing)
+
+	respond(w, r, http.StatusOK, map[string]string{"message": "Enrollment deleted successfully"})
+}
+
+// RestoreEnrollment handles POST /api/enrollments/{id}/restore
+func (h *EnrollmentHandler) RestoreEnrollment(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	enrollment, err := h.repo.Restore(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		if err == repository.ErrNotDeleted {
+			respondWithError(w, r, http.StatusConflict, models.CodeEnrollmentNotDeleted, "Enrollment is not deleted")
+			return
+		}
+		if err == repository.ErrAlreadyExists {
+			respondWithError(w, r, http.StatusConflict, models.CodeEnrollmentExists, "Enrollment already exists")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to restore enrollment")
+		return
+	}
+
+	// Invalidate cache after restore
+	h.invalidateCache(r.Context(), id)
+
+	h.recordAudit(id, models.AuditActionRestore, "", enrollment.Status)
+
+	respond(w, r, http.StatusOK, enrollment)
+}
+
+// GetEnrollmentHistory handles GET /api/enrollments/{id}/history
+// History remains available for soft-deleted enrollments
+func (h *EnrollmentHandler) GetEnrollmentHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	history := h.audit.GetByEnrollment(id)
+	if len(history) == 0 {
+		respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+		return
+	}
+
+	respond(w, r, http.StatusOK, history)
+}
+
+// listEnrollments returns all live enrollments, consulting the cached list
+// first and repopulating it on a miss
+func (h *EnrollmentHandler) listEnrollments(r *http.Request) []*models.Enrollment {
+	cached, err := h.cache.GetList(r.Context())
+	if err == nil && cached != nil {
+		middleware.SetCacheStatus(r, middleware.CacheHit)
+		return cached
+	}
+
+	enrollments := h.repo.Find(repository.EnrollmentFilter{})
+	if err := h.cache.SetList(r.Context(), enrollments); err != nil {
+		log.Printf("Failed to cache enrollment list: %v", err)
+	}
+
+	middleware.SetCacheStatus(r, middleware.CacheMiss)
+	return enrollments
+}
+
+// invalidateCache removes an enrollment and the enrollment list from cache.
+// It runs after a write has succeeded, so it is detached from ctx's
+// cancellation: a client disconnecting must not leave a stale entry behind.
+func (h *EnrollmentHandler) invalidateCache(ctx context.Context, id string) {
+	if h.cache == nil {
+		return
+	}
+
+	if err := h.cache.Delete(context.WithoutCancel(ctx), id); err != nil {
+		log.Printf("Failed to invalidate cache for enrollment %s: %v", id, err)
+	}
+	h.invalidateList(ctx)
+}
+
+// invalidateMany removes several enrollments and the enrollment list from
+// cache in two round trips however many enrollments there are, detached from
+// ctx's cancellation like invalidateCache
+func (h *EnrollmentHandler) invalidateMany(ctx context.Context, enrollments []*models.Enrollment) {
+	if h.cache == nil || len(enrollments) == 0 {
+		return
+	}
+
+	ids := make([]string, len(enrollments))
+	for i, enrollment := range enrollments {
+		ids[i] = enrollment.ID
+	}
+	if err := h.cache.DeleteMany(context.WithoutCancel(ctx), ids); err != nil {
+		log.Printf("Failed to invalidate cache for %d enrollment(s): %v", len(ids), err)
+	}
+	h.invalidateList(ctx)
+}
+
+// invalidateList removes the cached enrollment list, detached from ctx's
+// cancellation like invalidateCache
+func (h *EnrollmentHandler) invalidateList(ctx context.Context) {
+	if h.cache == nil {
+		return
+	}
+
+	if err := h.cache.DeleteList(context.WithoutCancel(ctx)); err != nil {
+		log.Printf("Failed to invalidate cached enrollment list: %v", err)
+	}
+}
+
+// notify publishes a lifecycle event when a notifier is configured
+func (h *EnrollmentHandler) notify(eventType string, enrollment *models.Enrollment) {
+	if h.notifier != nil {
+		h.notifier.Notify(eventType, enrollment)
+	}
+}
+
+// recordAudit appends a change to the enrollment's audit trail
+func (h *EnrollmentHandler) recordAudit(id, action, oldStatus, newStatus string) {
+	h.audit.Record(models.AuditEntry{
+		EnrollmentID: id,
+		Action:       action,
+		OldStatus:    oldStatus,
+		NewStatus:    newStatus,
+		At:           time.Now(),
+	})
+}
+
+// createWithFreshID stores enrollment, replacing its generated ID and trying
+// again while the ID turns out to be taken. It returns
+// repository.ErrIDTaken once MaxIDAttempts IDs have all collided.
+func (h *EnrollmentHandler) createWithFreshID(ctx context.Context, enrollment *models.Enrollment) error {
+	for attempt := 1; ; attempt++ {
+		err := h.repo.Create(ctx, enrollment)
+		if err != repository.ErrIDTaken || attempt == MaxIDAttempts {
+			return err
+		}
+		log.Printf("WARNING: Generated enrollment ID %s is taken, retrying", enrollment.ID)
+		enrollment.ID = h.newID()
+	}
+}
+
+// prepareNewEnrollment assigns a fresh ID and creation timestamps,
+// defaulting the enrollment date to now when not provided
+func (h *EnrollmentHandler) prepareNewEnrollment(enrollment *models.Enrollment) {
+	now := time.Now()
+	enrollment.ID = h.newID()
+	enrollment.CreatedAt = now
+	enrollment.UpdatedAt = now
+
+	if enrollment.EnrollmentDate.IsZero() {
+		enrollment.EnrollmentDate = now
+	}
+}
+
+// decodeJSON decodes the JSON request body into v.
+// The returned error message is safe to show to clients.
+func decodeJSON(r *http.Request, v interface{}) error {
+	return decodeBody(r, v, false)
+}
+
+// decodeStrict is like decodeJSON but rejects fields that v does not declare
+func decodeStrict(r *http.Request, v interface{}) error {
+	return decodeBody(r, v, true)
+}
+
+// decodeBody implements decodeJSON and decodeStrict
+func decodeBody(r *http.Request, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(r.Body)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		// encoding/json reports these as: json: unknown field "name"
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("unknown field: %s", strings.Trim(field, `"`))
+		}
+		return errors.New("Invalid request payload")
+	}
+
+	return nil
+}
+
+// applyDefaultStatus gives a new enrollment sent without a status the
+// configured default status, if any
+func (h *EnrollmentHandler) applyDefaultStatus(enrollment *models.Enrollment) {
+	if h.defaultStatus != "" && models.NormalizeStatus(enrollment.Status) == "" {
+		enrollment.Status = h.defaultStatus
+	}
+}
+
+// respondWithDecodeError reports a request body decoding error: 413 when the
+// body exceeded the middleware.BodyLimit cap, 400 otherwise
+func respondWithDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, r, http.StatusRequestEntityTooLarge, models.CodeBodyTooLarge, "Request body too large")
+		return
+	}
+	respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidBody, err.Error())
+}
+
+// parseID reads the {id} path parameter and checks that it is a well-formed
+// UUID, returning it in canonical lowercase form
+func parseID(r *http.Request) (string, error) {
+	return normalizeID(mux.Vars(r)["id"])
+}
+
+// normalizeID checks that id is a UUID and returns it in canonical lowercase
+// form
+func normalizeID(id string) (string, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", errInvalidID
+	}
+
+	return parsed.String(), nil
+}
+
+// parsePagination reads the limit and offset query parameters, applying
+// defaults. A limit above MaxPageLimit is an error unless clamp is set, in
+// which case it is lowered to MaxPageLimit and clamped is true.
+func parsePagination(r *http.Request, clamp bool) (limit, offset int, clamped bool, err error) {
+	limit = DefaultPageLimit
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return 0, 0, false, fmt.Errorf("limit must be a positive integer")
+		}
+		if value > MaxPageLimit {
+			if !clamp {
+				return 0, 0, false, fmt.Errorf("limit must not exceed %d", MaxPageLimit)
+			}
+			value, clamped = MaxPageLimit, true
+		}
+		limit = value
+	}
+
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return 0, 0, false, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = value
+	}
+
+	return limit, offset, clamped, nil
+}
+
+// parseFilter reads the student_id, course_id, status, from, to and
+// include_deleted query parameters
+func parseFilter(r *http.Request) (repository.EnrollmentFilter, error) {
+	query := r.URL.Query()
+	filter := repository.EnrollmentFilter{
+		StudentID: query.Get("student_id"),
+		CourseID:  query.Get("course_id"),
+		Status:    models.NormalizeStatus(query.Get("status")),
+	}
+
+	if filter.Status != "" && !models.ValidStatuses[filter.Status] {
+		return filter, fmt.Errorf("status must be one of: pending, active, completed, waitlisted")
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("from must be an RFC3339 timestamp")
+		}
+		filter.FromDate = from
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("to must be an RFC3339 timestamp")
+		}
+		filter.ToDate = to
+	}
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.FromDate.After(filter.ToDate) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+
+	if raw := query.Get("include_deleted"); raw != "" {
+		includeDeleted, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("include_deleted must be true or false")
 		}
+		filter.IncludeDeleted = includeDeleted
+	}
+
+	return filter, nil
+}
+
+// parseSort reads the sort and order query parameters.
+// An empty field keeps the default creation-time ordering.
+func parseSort(r *http.Request) (string, string, error) {
+	query := r.URL.Query()
+	field := query.Get("sort")
+	order := query.Get("order")
+
+	if field != "" && !repository.ValidSortFields[field] {
+		return "", "", repository.ErrInvalidSortField
+	}
+	if order != "" && order != repository.SortAsc && order != repository.SortDesc {
+		return "", "", repository.ErrInvalidSortOrder
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Enrollment deleted successfully"})
+	return field, order, nil
 }
 
-// respondWithError sends an error response
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+// respondWithError sends an error response with status, the machine-readable
+// errorCode and a human-readable message, including the request ID set by
+// middleware.RequestID when present. The message is translated when the
+// Accept-Language header prefers a language in messageCatalog.
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, errorCode models.ErrorCode, message string) {
+	respond(w, r, status, models.ErrorResponse{
+		Error:     localizeError(w, r, errorCode, message),
+		Code:      errorCode,
+		RequestID: w.Header().Get(middleware.RequestIDHeader),
+	})
 }
 
-// respondWithJSON sends a JSON response
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, err := json.Marshal(payload)
+// respondWithRetry sends an error response as respondWithError does, with a
+// Retry-After header asking the client to wait seconds before trying again.
+// It is for 429 and 503 responses to conditions that are expected to clear.
+func respondWithRetry(w http.ResponseWriter, r *http.Request, status int, errorCode models.ErrorCode, seconds int, message string) {
+	middleware.SetRetryAfter(w, seconds)
+	respondWithError(w, r, status, errorCode, message)
+}
+
+// respondWithValidationError sends 400 with the summary under "error" and,
+// for ValidationErrors, every failing field under "errors". Only the summary
+// is translated; field messages stay in English.
+func respondWithValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusBadRequest, models.ErrorResponse{
+		Error:     localizeError(w, r, models.CodeValidationFailed, validationErrs.Error()),
+		Code:      models.CodeValidationFailed,
+		Errors:    validationErrs,
+		RequestID: w.Header().Get(middleware.RequestIDHeader),
+	})
+}
+
+// respond sends payload as JSON, or as YAML or a JSON:API document when the
+// request's Accept header prefers one of them; see responseType. JSON is
+// compact unless the request asks for indentation; see jsonIndent.
+func respond(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
+	var response []byte
+	var err error
+	contentType := responseType(r)
+	if contentType == JSONAPIContentType {
+		payload = toJSONAPI(code, payload)
+	}
+	if contentType == YAMLContentType {
+		response, err = marshalYAML(payload)
+	} else if indent := jsonIndent(r); indent != "" {
+		response, err = json.MarshalIndent(payload, "", indent)
+	} else {
+		response, err = json.Marshal(payload)
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": "Internal server error"}`))
+		w.Write([]byte(`{"error": "Internal server error", "code": "INTERNAL_ERROR"}`))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+// responseType returns the media type to respond with: the first of
+// application/json, a YAML type or JSONAPIContentType that the Accept header
+// names. Anything else, including no Accept header, gets application/json.
+func responseType(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(accept)
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" || mediaType == JSONAPIContentType {
+			return mediaType
+		}
+		if yamlMediaTypes[mediaType] {
+			return YAMLContentType
+		}
+	}
+	return "application/json"
+}
+
+// jsonIndent returns the indentation the request asks for, or "" for compact
+// output. ?pretty=true indents by two spaces; an Accept header such as
+// "application/json; indent=4" indents by that many, up to MaxJSONIndent.
+func jsonIndent(r *http.Request) string {
+	if pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty")); err == nil && pretty {
+		return "  "
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(accept)
+		if err != nil || mediaType != "application/json" {
+			continue
+		}
+		if n, err := strconv.Atoi(params["indent"]); err == nil && n > 0 {
+			return strings.Repeat(" ", min(n, MaxJSONIndent))
+		}
+	}
+	return ""
+}
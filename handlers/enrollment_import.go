@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"techwave/models"
+	"time"
+)
+
+// ImportEnrollments stores enrollments read from a seed file and returns one
+// error per input; nil means it was inserted. Each one is validated like a
+// create, but the id and timestamps it carries are kept: a missing id gets a
+// new UUID and missing timestamps default to now. Imported enrollments are
+// always live, and each gets a create audit entry.
+func (h *EnrollmentHandler) ImportEnrollments(ctx context.Context, enrollments []*models.Enrollment) []error {
+	errs := make([]error, len(enrollments))
+	batch := make([]*models.Enrollment, 0, len(enrollments))
+	batchIndexes := make([]int, 0, len(enrollments))
+
+	now := time.Now()
+	for i, enrollment := range enrollments {
+		h.applyDefaultStatus(enrollment)
+		if err := enrollment.Validate(); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if enrollment.ID == "" {
+			enrollment.ID = h.newID()
+		} else if id, err := normalizeID(enrollment.ID); err != nil {
+			errs[i] = err
+			continue
+		} else {
+			enrollment.ID = id
+		}
+		if enrollment.CreatedAt.IsZero() {
+			enrollment.CreatedAt = now
+		}
+		if enrollment.UpdatedAt.IsZero() {
+			enrollment.UpdatedAt = enrollment.CreatedAt
+		}
+		if enrollment.EnrollmentDate.IsZero() {
+			enrollment.EnrollmentDate = enrollment.CreatedAt
+		}
+		enrollment.DeletedAt = nil
+		enrollment.CompletedAt = nil
+
+		batch = append(batch, enrollment)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	created := 0
+	for j, err := range h.repo.CreateBatch(batch) {
+		errs[batchIndexes[j]] = err
+		if err == nil {
+			h.recordAudit(batch[j].ID, models.AuditActionCreate, "", batch[j].Status)
+			created++
+		}
+	}
+	if created > 0 {
+		h.invalidateList(ctx)
+	}
+
+	return errs
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"techwave/cache"
+	"techwave/models"
+	"techwave/repository"
+	"time"
+)
+
+const (
+	// SeatLockTTL is how long a course's distributed seat lock lives, which
+	// bounds how long a crashed instance can block the course
+	SeatLockTTL = 5 * time.Second
+	// SeatLockWait is how long a request waits for another instance to
+	// release a course's seat lock before giving up with 503
+	SeatLockWait = time.Second
+)
+
+// errSeatsBusy is returned by withSeats when another instance held a
+// course's seat lock for longer than SeatLockWait
+var errSeatsBusy = errors.New("course seats are busy, try again")
+
+// withSeats runs fn under the course repository's seat lock. When Redis is
+// configured it first takes a distributed lock on each listed course, so
+// instances sharing a store cannot both give away the last seat. Every course
+// is locked, capped here or not, since capacities live in each process's
+// CourseRepository and another instance may have one this instance lacks; an
+// instance that was never given a capacity still does not enforce it. The
+// lock is a single-Redis lock, not a full Redlock. If Redis cannot be
+// reached, fn runs under the local lock only.
+func (h *EnrollmentHandler) withSeats(ctx context.Context, courseIDs []string, fn func() error) error {
+	if h.cache != nil {
+		locks, err := h.lockSeats(ctx, courseIDs)
+		if err == cache.ErrLockNotAcquired {
+			return errSeatsBusy
+		}
+		if err != nil {
+			log.Printf("WARNING: Seat lock unavailable, relying on the local lock: %v", err)
+		}
+		defer unlockSeats(ctx, locks)
+	}
+
+	return h.courses.WithSeats(fn)
+}
+
+// lockSeats takes the distributed seat lock of every listed course, in
+// sorted order so overlapping requests cannot deadlock. On error the locks
+// already taken are released.
+func (h *EnrollmentHandler) lockSeats(ctx context.Context, courseIDs []string) ([]*cache.Lock, error) {
+	courseIDs = slices.Clone(courseIDs)
+	slices.Sort(courseIDs)
+	courseIDs = slices.Compact(courseIDs)
+
+	ctx, cancel := context.WithTimeout(ctx, SeatLockWait)
+	defer cancel()
+
+	locks := make([]*cache.Lock, 0, len(courseIDs))
+	for _, courseID := range courseIDs {
+		lock, err := h.cache.Lock(ctx, "seats:"+courseID, SeatLockTTL)
+		if err != nil {
+			unlockSeats(ctx, locks)
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// unlockSeats releases seat locks, detached from ctx's cancellation so a
+// dropped client never leaves a course locked until the TTL
+func unlockSeats(ctx context.Context, locks []*cache.Lock) {
+	for _, lock := range locks {
+		if err := lock.Unlock(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("WARNING: Failed to release seat lock: %v", err)
+		}
+	}
+}
+
+// freeSeats returns how many more enrollments courseID can make active.
+// limited is false when the course has no capacity, i.e. unlimited seats.
+// Callers must hold the course repository's seat lock.
+func (h *EnrollmentHandler) freeSeats(courseID string) (free int, limited bool) {
+	capacity, limited := h.courses.Capacity(courseID)
+	if !limited {
+		return 0, false
+	}
+
+	active := h.repo.CountByStatus(repository.EnrollmentFilter{CourseID: courseID, Status: "active"})["active"]
+	return capacity - active, true
+}
+
+// admit waitlists a new pending or active enrollment whose course has no free
+// seat. claimed counts the seats taken by earlier enrollments of the same
+// batch that are not stored yet; it is nil for single creates.
+func (h *EnrollmentHandler) admit(enrollment *models.Enrollment, claimed map[string]int) {
+	if enrollment.Status != "pending" && enrollment.Status != "active" {
+		return
+	}
+
+	free, limited := h.freeSeats(enrollment.CourseID)
+	if !limited {
+		return
+	}
+	if free-claimed[enrollment.CourseID] <= 0 {
+		enrollment.Status = "waitlisted"
+		return
+	}
+	if enrollment.Status == "active" && claimed != nil {
+		claimed[enrollment.CourseID]++
+	}
+}
+
+// checkSeat returns repository.ErrCourseFull when updating existing to
+// updated would take a seat the course does not have. Changes the
+// transition rules forbid are left for the repository to reject.
+func (h *EnrollmentHandler) checkSeat(existing, updated *models.Enrollment) error {
+	if updated.Status != "active" || !models.CanTransition(existing.Status, updated.Status) {
+		return nil
+	}
+	if existing.Status == "active" && existing.CourseID == updated.CourseID {
+		return nil
+	}
+
+	if free, limited := h.freeSeats(updated.CourseID); limited && free <= 0 {
+		return repository.ErrCourseFull
+	}
+	return nil
+}
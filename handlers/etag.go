@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"techwave/models"
+	"time"
+)
+
+// enrollmentETag computes a strong ETag from the enrollment's JSON encoding.
+// The encoding includes UpdatedAt, so any change produces a new tag, and it
+// is identical whether the enrollment came from the cache or the repository.
+func enrollmentETag(enrollment *models.Enrollment) (string, error) {
+	body, err := json.Marshal(enrollment)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match or If-Match header value
+// matches the ETag. The header may be "*" or a comma-separated list of
+// (possibly weak) tags.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified evaluates a conditional GET against the enrollment's ETag and
+// modification time. If-None-Match takes precedence, so If-Modified-Since is
+// only consulted when the request has no If-None-Match (RFC 9110 §13.2.2).
+func notModified(r *http.Request, etag string, updatedAt time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagMatches(ifNoneMatch, etag)
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || updatedAt.IsZero() {
+		return false
+	}
+	// HTTP dates have one-second resolution
+	return !updatedAt.Truncate(time.Second).After(since)
+}
+
+// respondWithEnrollment sends an enrollment with its ETag and Last-Modified
+// headers, or 304 Not Modified when the request's If-None-Match or
+// If-Modified-Since shows the client already has it
+func respondWithEnrollment(w http.ResponseWriter, r *http.Request, enrollment *models.Enrollment) {
+	if setEnrollmentHeaders(w, r, enrollment) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respond(w, r, http.StatusOK, enrollment)
+}
+
+// setEnrollmentHeaders sets the enrollment's ETag and Last-Modified headers
+// and reports whether the request's conditional headers show the client
+// already has it
+func setEnrollmentHeaders(w http.ResponseWriter, r *http.Request, enrollment *models.Enrollment) bool {
+	if !enrollment.UpdatedAt.IsZero() {
+		w.Header().Set("Last-Modified", enrollment.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	etag, err := enrollmentETag(enrollment)
+	if err == nil {
+		w.Header().Set("ETag", etag)
+	}
+	return notModified(r, etag, enrollment.UpdatedAt)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+	"techwave/models"
+)
+
+// NotFound handles requests whose path matches no route
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, http.StatusNotFound, models.CodeNotFound, "not found")
+}
+
+// MethodNotAllowed handles requests whose path matches a route that does not
+// accept the method. The caller is responsible for setting the Allow header.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, http.StatusMethodNotAllowed, models.CodeMethodNotAllowed, "method not allowed")
+}
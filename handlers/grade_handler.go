@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"techwave/models"
+	"techwave/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// errInvalidGradeID is returned for a grade id that is not a UUID
+var errInvalidGradeID = errors.New("invalid grade id format")
+
+// studentGPA is the response body for GET /api/students/{studentId}/gpa
+type studentGPA struct {
+	StudentID     string  `json:"student_id"`
+	GPA           float64 `json:"gpa"`
+	GradedCourses int     `json:"graded_courses"`
+}
+
+// GradeHandler handles HTTP requests for enrollment grades
+type GradeHandler struct {
+	enrollments repository.Store
+	grades      *repository.GradeRepository
+	scale       models.GradeScale
+	// passingScore is the lowest score reported as passed
+	passingScore float64
+}
+
+// NewGradeHandler creates a new grade handler that derives letter grades on
+// the given scale and reports scores of at least passingScore as passed
+func NewGradeHandler(enrollments repository.Store, grades *repository.GradeRepository, scale models.GradeScale, passingScore float64) *GradeHandler {
+	return &GradeHandler{
+		enrollments:  enrollments,
+		grades:       grades,
+		scale:        scale,
+		passingScore: passingScore,
+	}
+}
+
+// present returns a copy of grade with its read-time fields derived, leaving
+// the stored grade untouched
+func (h *GradeHandler) present(grade *models.Grade) models.Grade {
+	presented := *grade
+	presented.Passed = grade.Score >= h.passingScore
+	return presented
+}
+
+// CreateGrade handles POST /api/enrollments/{id}/grades
+func (h *GradeHandler) CreateGrade(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	if _, err := h.enrollments.GetByID(r.Context(), enrollmentID); err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve enrollment")
+		return
+	}
+
+	var grade models.Grade
+	if err := decodeJSON(r, &grade); err != nil {
+		respondWithDecodeError(w, r, err)
+		return
+	}
+
+	// The enrollment is always taken from the path
+	grade.EnrollmentID = enrollmentID
+
+	// Validate the grade
+	if err := grade.Validate(); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, err.Error())
+		return
+	}
+
+	// Derive the letter grade if not provided
+	if grade.LetterGrade == "" {
+		grade.LetterGrade = h.scale.Letter(grade.Score)
+	}
+
+	grade.ID = uuid.New().String()
+	if grade.GradedAt.IsZero() {
+		grade.GradedAt = time.Now()
+	}
+
+	if err := h.grades.Create(&grade); err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to create grade")
+		return
+	}
+
+	respond(w, r, http.StatusCreated, h.present(&grade))
+}
+
+// GetGrades handles GET /api/enrollments/{id}/grades
+func (h *GradeHandler) GetGrades(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	if _, err := h.enrollments.GetByID(r.Context(), enrollmentID); err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve enrollment")
+		return
+	}
+
+	stored := h.grades.GetByEnrollment(enrollmentID)
+	grades := make([]models.Grade, len(stored))
+	for i, grade := range stored {
+		grades[i] = h.present(grade)
+	}
+	respond(w, r, http.StatusOK, grades)
+}
+
+// GetGrade handles GET /api/enrollments/{id}/grades/{gradeId}
+// A grade recorded against another enrollment is reported as not found.
+func (h *GradeHandler) GetGrade(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, gradeID, ok := h.parseGradePath(w, r)
+	if !ok {
+		return
+	}
+
+	grade, err := h.grades.GetByID(gradeID)
+	if err != nil || grade.EnrollmentID != enrollmentID {
+		respondWithError(w, r, http.StatusNotFound, models.CodeGradeNotFound, "Grade not found")
+		return
+	}
+
+	respond(w, r, http.StatusOK, h.present(grade))
+}
+
+// UpdateGrade handles PUT /api/enrollments/{id}/grades/{gradeId}
+// The body replaces the grade's score and letter grade. Without a
+// letter_grade the letter is derived again from the new score; without a
+// graded_at the original time is kept. The grade must belong to the
+// enrollment in the path, which the body cannot change.
+func (h *GradeHandler) UpdateGrade(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, gradeID, ok := h.parseGradePath(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.grades.GetByID(gradeID)
+	if err != nil || existing.EnrollmentID != enrollmentID {
+		respondWithError(w, r, http.StatusNotFound, models.CodeGradeNotFound, "Grade not found")
+		return
+	}
+
+	var grade models.Grade
+	if err := decodeJSON(r, &grade); err != nil {
+		respondWithDecodeError(w, r, err)
+		return
+	}
+
+	// The grade and its enrollment are always taken from the path
+	grade.ID = gradeID
+	grade.EnrollmentID = enrollmentID
+
+	if err := grade.Validate(); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeValidationFailed, err.Error())
+		return
+	}
+
+	if grade.LetterGrade == "" {
+		grade.LetterGrade = h.scale.Letter(grade.Score)
+	}
+	if grade.GradedAt.IsZero() {
+		grade.GradedAt = existing.GradedAt
+	}
+
+	if err := h.grades.Update(&grade); err != nil {
+		if err == repository.ErrGradeNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeGradeNotFound, "Grade not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to update grade")
+		return
+	}
+
+	respond(w, r, http.StatusOK, h.present(&grade))
+}
+
+// DeleteGrade handles DELETE /api/enrollments/{id}/grades/{gradeId}
+// A grade recorded against another enrollment is reported as not found and
+// left in place.
+func (h *GradeHandler) DeleteGrade(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, gradeID, ok := h.parseGradePath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.grades.Delete(enrollmentID, gradeID); err != nil {
+		if err == repository.ErrGradeNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeGradeNotFound, "Grade not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to delete grade")
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]string{"message": "Grade deleted successfully"})
+}
+
+// parseGradePath reads the {id} and {gradeId} path parameters and checks that
+// the enrollment exists. On failure it has already answered the request and
+// ok is false.
+func (h *GradeHandler) parseGradePath(w http.ResponseWriter, r *http.Request) (enrollmentID, gradeID string, ok bool) {
+	enrollmentID, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return "", "", false
+	}
+	parsed, err := uuid.Parse(mux.Vars(r)["gradeId"])
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, errInvalidGradeID.Error())
+		return "", "", false
+	}
+
+	if _, err := h.enrollments.GetByID(r.Context(), enrollmentID); err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return "", "", false
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve enrollment")
+		return "", "", false
+	}
+
+	return enrollmentID, parsed.String(), true
+}
+
+// GetGradeSummary handles GET /api/enrollments/{id}/grades/summary
+func (h *GradeHandler) GetGradeSummary(w http.ResponseWriter, r *http.Request) {
+	enrollmentID, err := parseID(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidID, err.Error())
+		return
+	}
+
+	if _, err := h.enrollments.GetByID(r.Context(), enrollmentID); err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeEnrollmentNotFound, "Enrollment not found")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve enrollment")
+		return
+	}
+
+	respond(w, r, http.StatusOK, h.grades.Summary(enrollmentID, h.passingScore))
+}
+
+// GetStudentGPA handles GET /api/students/{studentId}/gpa
+// Only completed enrollments with at least one grade are counted. Each course
+// contributes the grade points of its mean score, so every graded course
+// carries equal weight regardless of how many grades it has.
+func (h *GradeHandler) GetStudentGPA(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	studentID := vars["studentId"]
+
+	results, err := h.grades.GetGradesByStudent(studentID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			respondWithError(w, r, http.StatusNotFound, models.CodeStudentNotFound, "Student has no enrollments")
+			return
+		}
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to retrieve grades")
+		return
+	}
+
+	var totalPoints float64
+	gradedCourses := 0
+	for _, result := range results {
+		if result.Enrollment.Status != "completed" || len(result.Grades) == 0 {
+			continue
+		}
+
+		var sum float64
+		for _, grade := range result.Grades {
+			sum += grade.Score
+		}
+		mean := sum / float64(len(result.Grades))
+
+		totalPoints += models.GradePoints[h.scale.Letter(mean)]
+		gradedCourses++
+	}
+
+	gpa := 0.0
+	if gradedCourses > 0 {
+		gpa = math.Round(totalPoints/float64(gradedCourses)*100) / 100
+	}
+
+	respond(w, r, http.StatusOK, studentGPA{
+		StudentID:     studentID,
+		GPA:           gpa,
+		GradedCourses: gradedCourses,
+	})
+}
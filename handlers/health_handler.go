@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"techwave/cache"
+	"techwave/middleware"
+	"techwave/models"
+	"techwave/repository"
+)
+
+// Component check results reported by Health
+const (
+	checkOK       = "ok"
+	checkDown     = "down"
+	checkDisabled = "disabled"
+	checkFail     = "fail"
+)
+
+// pinger is implemented by stores backed by an external resource, such as
+// SQLiteRepository; stores without it are in-process and always reachable
+type pinger interface {
+	Ping() error
+}
+
+// selfTester is implemented by stores that can write, read back and remove a
+// throwaway record without it ever showing up in other calls
+type selfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// HealthHandler handles health, liveness and readiness probes
+type HealthHandler struct {
+	repo  repository.Store
+	cache *cache.EnrollmentCache
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(repo repository.Store, cache *cache.EnrollmentCache) *HealthHandler {
+	return &HealthHandler{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+// healthResponse is the body returned by GET /health
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Health handles GET /health
+// Reports the status of each dependency and returns 503 with a Retry-After
+// if any configured dependency is down. Redis counts only when caching is
+// enabled.
+// With deep=true a store that supports it also round-trips a throwaway
+// record, and the repository check is "fail" if that does not work.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	deep := false
+	if raw := r.URL.Query().Get("deep"); raw != "" {
+		var err error
+		if deep, err = strconv.ParseBool(raw); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, models.CodeInvalidParameter, "deep must be true or false")
+			return
+		}
+	}
+
+	checks := map[string]string{
+		"repository": checkOK,
+		"redis":      checkDisabled,
+	}
+
+	if p, ok := h.repo.(pinger); ok && p.Ping() != nil {
+		checks["repository"] = checkDown
+	}
+	if t, ok := h.repo.(selfTester); ok && deep && checks["repository"] == checkOK {
+		if err := t.SelfTest(r.Context()); err != nil {
+			log.Printf("WARNING: Repository self-test failed: %v", err)
+			checks["repository"] = checkFail
+		}
+	}
+	if h.cache != nil {
+		checks["redis"] = checkOK
+		if err := h.cache.Ping(r.Context()); err != nil {
+			checks["redis"] = checkDown
+		}
+	}
+
+	status, code := "healthy", http.StatusOK
+	for _, result := range checks {
+		if result == checkDown || result == checkFail {
+			status, code = "unhealthy", http.StatusServiceUnavailable
+			middleware.SetRetryAfter(w, middleware.RetryAfterSeconds(middleware.DefaultRetryAfter))
+			break
+		}
+	}
+
+	respond(w, r, code, healthResponse{Status: status, Checks: checks})
+}
+
+// Live handles GET /health/live
+// Always returns 200 while the process is able to serve requests
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// Ready handles GET /health/ready
+// Returns 503 with a Retry-After when the configured Redis cache is
+// unreachable. A server started without a cache is ready because it never
+// depends on Redis.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		respond(w, r, http.StatusOK, map[string]string{"status": "ready", "cache": "disabled"})
+		return
+	}
+
+	if err := h.cache.Ping(r.Context()); err != nil {
+		middleware.SetRetryAfter(w, middleware.RetryAfterSeconds(middleware.DefaultRetryAfter))
+		respond(w, r, http.StatusServiceUnavailable, map[string]string{
+			"status": "degraded",
+			"cache":  "unreachable",
+			"error":  "Redis is unreachable; enrollments are served without caching",
+		})
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]string{"status": "ready", "cache": "ok"})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"techwave/models"
+)
+
+// JSONAPIContentType is the media type of JSON:API responses
+// (https://jsonapi.org)
+const JSONAPIContentType = "application/vnd.api+json"
+
+// jsonAPIDocument is a JSON:API top-level document. Exactly one of Data and
+// Errors is set; Meta carries anything that is not a resource.
+type jsonAPIDocument struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []jsonAPIError `json:"errors,omitempty"`
+	Meta   interface{}    `json:"meta,omitempty"`
+}
+
+// jsonAPIResource is a JSON:API resource object
+type jsonAPIResource struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`
+	Attributes jsonAPIAttributes `json:"attributes"`
+	Links      *jsonAPILinks     `json:"links,omitempty"`
+}
+
+// jsonAPILinks holds a resource's links
+type jsonAPILinks struct {
+	Self string `json:"self"`
+}
+
+// jsonAPIError is a JSON:API error object
+type jsonAPIError struct {
+	Status string         `json:"status"`
+	Code   string         `json:"code,omitempty"`
+	Title  string         `json:"title"`
+	Detail string         `json:"detail"`
+	Source *jsonAPISource `json:"source,omitempty"`
+}
+
+// jsonAPISource points at the request member an error is about
+type jsonAPISource struct {
+	Pointer string `json:"pointer"`
+}
+
+// jsonAPIAttributes encodes a record as its plain JSON fields minus "id",
+// which a resource object carries outside its attributes
+type jsonAPIAttributes struct {
+	record interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (a jsonAPIAttributes) MarshalJSON() ([]byte, error) {
+	body, err := json.Marshal(a.record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "id")
+	return json.Marshal(fields)
+}
+
+// toJSONAPI wraps a response payload in a JSON:API document. Enrollments and
+// grades become resource objects, error responses become error objects, and
+// any other payload, such as counts or statistics, is sent as meta.
+func toJSONAPI(code int, payload interface{}) jsonAPIDocument {
+	switch p := payload.(type) {
+	case models.ErrorResponse:
+		return jsonAPIErrors(code, p)
+	case models.Enrollment:
+		return jsonAPIDocument{Data: enrollmentResource(&p)}
+	case *models.Enrollment:
+		return jsonAPIDocument{Data: enrollmentResource(p)}
+	case []*models.Enrollment:
+		return jsonAPIDocument{Data: enrollmentResources(p)}
+	case enrollmentPage:
+		return jsonAPIDocument{
+			Data: enrollmentResources(p.Data),
+			Meta: map[string]int{"total": p.Total, "limit": p.Limit, "offset": p.Offset},
+		}
+	case models.Grade:
+		return jsonAPIDocument{Data: gradeResource(p)}
+	case []models.Grade:
+		resources := make([]jsonAPIResource, len(p))
+		for i, grade := range p {
+			resources[i] = gradeResource(grade)
+		}
+		return jsonAPIDocument{Data: resources}
+	}
+	return jsonAPIDocument{Meta: payload}
+}
+
+// jsonAPIErrors converts an error response into one error object per invalid
+// field, or a single error object when there are no field errors
+func jsonAPIErrors(code int, response models.ErrorResponse) jsonAPIDocument {
+	status := strconv.Itoa(code)
+	title := http.StatusText(code)
+
+	var errs []jsonAPIError
+	for _, fieldErr := range response.Errors {
+		errs = append(errs, jsonAPIError{
+			Status: status,
+			Code:   string(response.Code),
+			Title:  title,
+			Detail: fieldErr.Message,
+			Source: &jsonAPISource{Pointer: "/data/attributes/" + fieldErr.Field},
+		})
+	}
+	if len(errs) == 0 {
+		errs = []jsonAPIError{{Status: status, Code: string(response.Code), Title: title, Detail: response.Error}}
+	}
+
+	doc := jsonAPIDocument{Errors: errs}
+	if response.RequestID != "" {
+		doc.Meta = map[string]string{"request_id": response.RequestID}
+	}
+	return doc
+}
+
+// enrollmentResource presents an enrollment as an "enrollments" resource
+func enrollmentResource(enrollment *models.Enrollment) jsonAPIResource {
+	return jsonAPIResource{
+		Type:       "enrollments",
+		ID:         enrollment.ID,
+		Attributes: jsonAPIAttributes{enrollment},
+		Links:      &jsonAPILinks{Self: "/api/enrollments/" + enrollment.ID},
+	}
+}
+
+// enrollmentResources presents a list of enrollments; an empty list stays an
+// empty array rather than null
+func enrollmentResources(enrollments []*models.Enrollment) []jsonAPIResource {
+	resources := make([]jsonAPIResource, len(enrollments))
+	for i, enrollment := range enrollments {
+		resources[i] = enrollmentResource(enrollment)
+	}
+	return resources
+}
+
+// gradeResource presents a grade as a "grades" resource
+func gradeResource(grade models.Grade) jsonAPIResource {
+	return jsonAPIResource{
+		Type:       "grades",
+		ID:         grade.ID,
+		Attributes: jsonAPIAttributes{grade},
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"techwave/models"
+)
+
+// DefaultLanguage is the language of error messages when the Accept-Language
+// header names no language in the catalog. English messages are the ones the
+// handlers write, so they keep their request-specific detail.
+const DefaultLanguage = "en"
+
+// messageCatalog holds the error message for each code in every language
+// other than DefaultLanguage. Translations are one generic message per code,
+// so detail such as the name of a bad query parameter is only in English;
+// the code and any field errors still identify it.
+var messageCatalog = map[string]map[models.ErrorCode]string{
+	"es": {
+		models.CodeInvalidID:            "El formato del ID de matrícula no es válido",
+		models.CodeInvalidParameter:     "Parámetro de consulta no válido",
+		models.CodeInvalidBody:          "El cuerpo de la solicitud no es válido",
+		models.CodeValidationFailed:     "La matrícula no superó la validación",
+		models.CodeBodyTooLarge:         "El cuerpo de la solicitud es demasiado grande",
+		models.CodeUnsupportedMediaType: "El Content-Type debe ser application/json",
+		models.CodeEnrollmentNotFound:   "Matrícula no encontrada",
+		models.CodeStudentNotFound:      "El estudiante no tiene matrículas",
+		models.CodeCourseNotFound:       "El curso no tiene matrículas",
+		models.CodeGradeNotFound:        "Calificación no encontrada",
+		models.CodeJobNotFound:          "Tarea no encontrada",
+		models.CodeNotFound:             "No encontrado",
+		models.CodeMethodNotAllowed:     "Método no permitido",
+		models.CodeEnrollmentExists:     "La matrícula ya existe",
+		models.CodeEnrollmentIDTaken:    "El ID de matrícula ya existe",
+		models.CodeEnrollmentNotDeleted: "La matrícula no está eliminada",
+		models.CodeInvalidTransition:    "Cambio de estado no permitido",
+		models.CodeVersionConflict:      "Conflicto de versión",
+		models.CodeVersionRequired:      "Se requiere version o la cabecera If-Match",
+		models.CodeCourseFull:           "El curso está completo",
+		models.CodeIdempotencyInFlight:  "Otra solicitud con esta Idempotency-Key está en curso",
+		models.CodeUnauthorized:         "Se requiere una cabecera X-API-Key válida",
+		models.CodeAdminDisabled:        "Los endpoints de administración están desactivados",
+		models.CodeResetForbidden:       "No se permite restablecer el almacenamiento en producción",
+		models.CodeRateLimited:          "Límite de solicitudes superado",
+		models.CodeSeatsBusy:            "Las plazas del curso están ocupadas, inténtelo de nuevo",
+		models.CodeJobQueueUnavailable:  "La cola de tareas no está disponible",
+		models.CodeCacheUnavailable:     "La caché no está disponible",
+		models.CodeTimeout:              "La solicitud ha excedido el tiempo de espera",
+		models.CodeInternal:             "Error interno del servidor",
+	},
+	"fr": {
+		models.CodeInvalidID:            "Format d'identifiant d'inscription invalide",
+		models.CodeInvalidParameter:     "Paramètre de requête invalide",
+		models.CodeInvalidBody:          "Corps de requête invalide",
+		models.CodeValidationFailed:     "L'inscription n'a pas passé la validation",
+		models.CodeBodyTooLarge:         "Corps de requête trop volumineux",
+		models.CodeUnsupportedMediaType: "Le Content-Type doit être application/json",
+		models.CodeEnrollmentNotFound:   "Inscription introuvable",
+		models.CodeStudentNotFound:      "L'étudiant n'a aucune inscription",
+		models.CodeCourseNotFound:       "Le cours n'a aucune inscription",
+		models.CodeGradeNotFound:        "Note introuvable",
+		models.CodeJobNotFound:          "Tâche introuvable",
+		models.CodeNotFound:             "Introuvable",
+		models.CodeMethodNotAllowed:     "Méthode non autorisée",
+		models.CodeEnrollmentExists:     "L'inscription existe déjà",
+		models.CodeEnrollmentIDTaken:    "L'identifiant d'inscription existe déjà",
+		models.CodeEnrollmentNotDeleted: "L'inscription n'est pas supprimée",
+		models.CodeInvalidTransition:    "Changement de statut non autorisé",
+		models.CodeVersionConflict:      "Conflit de version",
+		models.CodeVersionRequired:      "version ou l'en-tête If-Match est requis",
+		models.CodeCourseFull:           "Le cours est complet",
+		models.CodeIdempotencyInFlight:  "Une autre requête avec cette Idempotency-Key est en cours",
+		models.CodeUnauthorized:         "Un en-tête X-API-Key valide est requis",
+		models.CodeAdminDisabled:        "Les endpoints d'administration sont désactivés",
+		models.CodeResetForbidden:       "La réinitialisation du stockage est interdite en production",
+		models.CodeRateLimited:          "Limite de requêtes dépassée",
+		models.CodeSeatsBusy:            "Les places du cours sont occupées, réessayez",
+		models.CodeJobQueueUnavailable:  "La file de tâches est indisponible",
+		models.CodeCacheUnavailable:     "Le cache est indisponible",
+		models.CodeTimeout:              "La requête a expiré",
+		models.CodeInternal:             "Erreur interne du serveur",
+	},
+}
+
+// localize returns the message to send for errorCode in the language the
+// request prefers, along with that language. message, the English text, is
+// kept for DefaultLanguage and for codes the catalog does not translate.
+func localize(r *http.Request, errorCode models.ErrorCode, message string) (string, string) {
+	language := negotiateLanguage(r)
+	if translated, ok := messageCatalog[language][errorCode]; ok {
+		return translated, language
+	}
+	return message, DefaultLanguage
+}
+
+// localizeError returns the message to send for errorCode, as localize does,
+// and marks the response with the language chosen
+func localizeError(w http.ResponseWriter, r *http.Request, errorCode models.ErrorCode, message string) string {
+	message, language := localize(r, errorCode, message)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", language)
+	return message
+}
+
+// negotiateLanguage returns the catalog language the Accept-Language header
+// ranks highest, matching on the primary subtag so "es-MX" selects "es".
+// Ties go to the language listed first; "*", q=0 and unknown languages are
+// ignored, and no match at all gives DefaultLanguage.
+func negotiateLanguage(r *http.Request) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary != DefaultLanguage && messageCatalog[primary] == nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,28 @@
+//go:build seed
+// +build seed
+
+package handlers
+
+import (
+	"net/http"
+	"techwave/models"
+	"techwave/seed"
+)
+
+// SeedEnrollments handles POST /api/_seed
+// Loads the fixed seed enrollments; only compiled with -tags seed.
+func (h *EnrollmentHandler) SeedEnrollments(w http.ResponseWriter, r *http.Request) {
+	created, result, err := seed.Load(h.repo)
+	for _, enrollment := range created {
+		h.recordAudit(enrollment.ID, models.AuditActionCreate, "", enrollment.Status)
+	}
+	if len(created) > 0 {
+		h.invalidateList(r.Context())
+	}
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, models.CodeInternal, "Failed to load seed data")
+		return
+	}
+
+	respond(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLContentType is the media type of YAML responses
+const YAMLContentType = "application/yaml"
+
+// yamlMediaTypes are the Accept values that select a YAML response
+var yamlMediaTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+}
+
+// marshalYAML encodes payload as YAML with the same field names and order as
+// its JSON encoding. It goes through JSON so the json struct tags apply.
+func marshalYAML(payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML; decoding it as a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// clearStyle resets the flow and quoting styles the JSON input left on every
+// node so the output uses block style. Strings that would read back as
+// another type are still quoted.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
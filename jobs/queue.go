@@ -0,0 +1,275 @@
+// Package jobs runs long work such as grade recomputes in the background so
+// handlers can answer at once and let clients poll for the outcome.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job states reported in Status.State
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateRetrying  = "retrying"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+)
+
+const (
+	// DefaultWorkers is how many jobs run at once when no count is given
+	DefaultWorkers = 2
+	// queueSize is how many jobs may wait for a worker before Enqueue
+	// refuses new ones
+	queueSize = 64
+	// defaultMaxAttempts is how many times a failing job is run before it is
+	// marked failed
+	defaultMaxAttempts = 3
+	// defaultBaseDelay is the wait before the first retry; it doubles per attempt
+	defaultBaseDelay = time.Second
+	// retention is how long finished jobs stay queryable
+	retention = 24 * time.Hour
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue when every worker is busy and the
+	// queue has no room left
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrQueueClosed is returned by Enqueue after Close
+	ErrQueueClosed = errors.New("job queue is closed")
+)
+
+// Job is a unit of background work. Run may report progress through the
+// Progress it is given and is run again, with a fresh count, when it returns
+// an error and attempts remain, so it must be safe to repeat.
+type Job struct {
+	Type string
+	Run  func(ctx context.Context, progress *Progress) error
+}
+
+// Status is the queryable state of an enqueued job
+type Status struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	State      string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	Processed  int        `json:"processed"`
+	Updated    int        `json:"updated"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// finished reports whether the job will not run again
+func (s Status) finished() bool {
+	return s.State == StateCompleted || s.State == StateFailed
+}
+
+// Progress lets a running job publish how far it has got
+type Progress struct {
+	queue *Queue
+	id    string
+}
+
+// Add counts processed more items checked and updated more items changed
+func (p *Progress) Add(processed, updated int) {
+	p.queue.update(p.id, func(status *Status) {
+		status.Processed += processed
+		status.Updated += updated
+	})
+}
+
+// queued pairs a job with the ID its status is stored under
+type queued struct {
+	id  string
+	job Job
+}
+
+// Queue runs jobs on a fixed pool of in-process workers, retrying failures
+// with exponential backoff, and keeps every job's status for a day after it
+// finishes. Statuses live in memory and are lost on restart.
+type Queue struct {
+	maxAttempts int
+	baseDelay   time.Duration
+
+	jobs     chan queued
+	ctx      context.Context
+	cancel   context.CancelFunc
+	workers  sync.WaitGroup
+	mu       sync.Mutex
+	closed   bool
+	statuses map[string]Status
+}
+
+// NewQueue creates a queue and starts workers goroutines; zero or fewer
+// uses DefaultWorkers. Call Close on shutdown.
+func NewQueue(workers int) *Queue {
+	return NewQueueWithRetry(workers, defaultMaxAttempts, defaultBaseDelay)
+}
+
+// NewQueueWithRetry is like NewQueue with a custom attempt limit and initial
+// backoff delay
+func NewQueueWithRetry(workers, maxAttempts int, baseDelay time.Duration) *Queue {
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		jobs:        make(chan queued, queueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+		statuses:    make(map[string]Status),
+	}
+
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.run()
+	}
+	return q
+}
+
+// Enqueue schedules job and returns its initial status. It never blocks:
+// when the queue is full it returns ErrQueueFull.
+func (q *Queue) Enqueue(job Job) (Status, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Status{}, ErrQueueClosed
+	}
+	q.prune()
+
+	status := Status{
+		ID:        uuid.New().String(),
+		Type:      job.Type,
+		State:     StateQueued,
+		CreatedAt: time.Now(),
+	}
+	select {
+	case q.jobs <- queued{id: status.ID, job: job}:
+	default:
+		return Status{}, ErrQueueFull
+	}
+	q.statuses[status.ID] = status
+	return status, nil
+}
+
+// Status returns the current status of the job with id, and false when no
+// such job is known
+func (q *Queue) Status(id string) (Status, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status, exists := q.statuses[id]
+	return status, exists
+}
+
+// Close stops accepting jobs, cancels the context of running jobs and waits
+// for them to return. Jobs that have not started, or are waiting to be
+// retried, are marked failed without another attempt.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	q.cancel()
+	q.workers.Wait()
+}
+
+// run executes queued jobs until the queue is closed
+func (q *Queue) run() {
+	defer q.workers.Done()
+
+	for entry := range q.jobs {
+		if q.ctx.Err() != nil {
+			q.finish(entry, ErrQueueClosed)
+			continue
+		}
+		q.execute(entry)
+	}
+}
+
+// execute runs one job, retrying failures with backoff, and records the
+// outcome
+func (q *Queue) execute(entry queued) {
+	delay := q.baseDelay
+	for attempt := 1; ; attempt++ {
+		q.update(entry.id, func(status *Status) {
+			status.State = StateRunning
+			status.Attempts = attempt
+			status.Processed = 0
+			status.Updated = 0
+		})
+
+		err := entry.job.Run(q.ctx, &Progress{queue: q, id: entry.id})
+		if err == nil || attempt == q.maxAttempts {
+			q.finish(entry, err)
+			return
+		}
+
+		log.Printf("WARNING: Job %s (%s) attempt %d failed, retrying in %v: %v", entry.id, entry.job.Type, attempt, delay, err)
+		q.update(entry.id, func(status *Status) {
+			status.State = StateRetrying
+			status.Error = err.Error()
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-q.ctx.Done():
+			q.finish(entry, fmt.Errorf("%w before retry: %v", ErrQueueClosed, err))
+			return
+		}
+		delay *= 2
+	}
+}
+
+// finish records that a job completed, or failed with err
+func (q *Queue) finish(entry queued, err error) {
+	finished := time.Now()
+	q.update(entry.id, func(status *Status) {
+		status.State = StateCompleted
+		status.Error = ""
+		if err != nil {
+			status.State = StateFailed
+			status.Error = err.Error()
+		}
+		status.FinishedAt = &finished
+	})
+	if err != nil {
+		log.Printf("WARNING: Job %s (%s) failed: %v", entry.id, entry.job.Type, err)
+	}
+}
+
+// update applies fn to the stored status of job id
+func (q *Queue) update(id string, fn func(status *Status)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := q.statuses[id]
+	fn(&status)
+	q.statuses[id] = status
+}
+
+// prune forgets jobs that finished more than retention ago; q.mu must be held
+func (q *Queue) prune() {
+	cutoff := time.Now().Add(-retention)
+	for id, status := range q.statuses {
+		if status.finished() && status.FinishedAt.Before(cutoff) {
+			delete(q.statuses, id)
+		}
+	}
+}
@@ -2,91 +2,250 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"techwave/app"
 	"techwave/cache"
-	"techwave/handlers"
+	"techwave/config"
+	"techwave/notify"
 	"techwave/repository"
+	"techwave/tracing"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	// Initialize Redis client with connection pooling
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379" // Default to local Redis
+	settings, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		Password:     os.Getenv("REDIS_PASSWORD"), // No password by default
-		DB:           0,                           // Use default DB
-		PoolSize:     10,                          // Connection pool size
-		MinIdleConns: 5,                           // Minimum idle connections
-	})
+	// Tracing is a no-op unless OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Printf("WARNING: Tracing disabled: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
 
-	// Test Redis connection (graceful fallback if unavailable)
+	// Connect to Redis: bad credentials are fatal, an unreachable server
+	// only disables caching
 	ctx := context.Background()
-	if err := redisClient.Ping(ctx).Err(); err != nil {
+	redisClient, err := cache.NewClient(settings.Redis)
+	if errors.Is(err, cache.ErrAuthFailed) {
+		log.Fatalf("Redis rejected the configured credentials: %v", err)
+	} else if err != nil {
 		log.Printf("WARNING: Redis unavailable, running without cache: %v", err)
-		redisClient = nil // Disable caching
 	} else {
-		log.Println("✓ Redis connection established")
+		log.Printf("✓ Redis connection established (db %d, pool %d, tls %v)", settings.Redis.DB, settings.Redis.PoolSize, settings.Redis.TLS)
 	}
 
-	// Initialize repository
-	enrollmentRepo := repository.NewEnrollmentRepository()
+	// A read replica is optional: if it cannot be reached reads stay on the primary
+	var replicaClient *redis.Client
+	if redisClient != nil && settings.RedisReplicaAddr != "" {
+		replicaSettings := settings.Redis
+		replicaSettings.Addr = settings.RedisReplicaAddr
+		replicaClient, err = cache.NewClient(replicaSettings)
+		if err != nil {
+			log.Printf("WARNING: Redis replica unavailable, reading from the primary: %v", err)
+		} else {
+			log.Printf("✓ Redis replica connection established (%s)", settings.RedisReplicaAddr)
+		}
+	}
 
-	// Initialize cache (nil-safe, graceful degradation)
-	var enrollmentCache *cache.EnrollmentCache
-	if redisClient != nil {
-		enrollmentCache = cache.NewEnrollmentCache(redisClient)
-		log.Println("✓ Cache layer enabled (5-minute TTL)")
+	// Build the application from the loaded configuration
+	cfg := app.Config{
+		RedisClient:             redisClient,
+		RedisReplicaClient:      replicaClient,
+		CacheTTL:                settings.CacheTTL,
+		CacheFallbackSize:       settings.CacheFallbackSize,
+		CacheNamespace:          settings.CacheNamespace,
+		CacheCompressThreshold:  settings.CacheCompressThreshold,
+		LogOutput:               os.Stdout,
+		MaxBodyBytes:            settings.MaxBodyBytes,
+		RequestTimeout:          settings.RequestTimeout,
+		RateLimitRPS:            settings.RateLimitRPS,
+		RateLimitBurst:          settings.RateLimitBurst,
+		CORSAllowedOrigins:      settings.CORSAllowedOrigins,
+		CORSAllowCredentials:    settings.CORSAllowCredentials,
+		ValidateRequests:        settings.ValidateRequests,
+		ClampPageLimit:          settings.ClampPageLimit,
+		DefaultEnrollmentStatus: settings.DefaultEnrollmentStatus,
+		GradeScale:              settings.GradeScale,
+		PassingScore:            settings.PassingScore,
+		AdminAPIKey:             settings.AdminAPIKey,
+		Env:                     settings.Env,
+		AllowProductionReset:    settings.AllowProductionReset,
+	}
+	if settings.DebugBodies {
+		cfg.DebugBodyOutput = os.Stdout
+		cfg.DebugBodyBytes = settings.DebugBodyBytes
+		cfg.DebugRedactFields = settings.DebugRedactFields
+		log.Printf("WARNING: DEBUG_BODIES is on; request and response bodies are logged (up to %d bytes, redacting %s)",
+			cfg.DebugBodyBytes, strings.Join(cfg.DebugRedactFields, ", "))
+	}
+	if cfg.ValidateRequests {
+		log.Printf("✓ Request bodies validated against the OpenAPI spec")
+	}
+	if cfg.AdminAPIKey != "" {
+		log.Printf("✓ Admin endpoints enabled (env %q, production reset allowed: %v)", cfg.Env, cfg.AllowProductionReset)
+	}
+	if cfg.DefaultEnrollmentStatus != "" {
+		log.Printf("✓ Enrollments created without a status default to %q", cfg.DefaultEnrollmentStatus)
+	}
+	if cfg.RateLimitRPS > 0 {
+		log.Printf("✓ Rate limiting enabled (%.2f req/s, burst %d)", cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+
+	// Enrollment storage backend
+	var sqliteRepo *repository.SQLiteRepository
+	var filePersistence *repository.FilePersistence
+	switch settings.StorageBackend {
+	case config.StorageMemory:
+		log.Println("✓ Using in-memory enrollment storage")
+	case config.StorageFile:
+		filePersistence = repository.NewFilePersistence(settings.DataFile, settings.SnapshotInterval)
+		if err := filePersistence.Load(); err != nil {
+			log.Fatalf("Failed to load snapshot %q: %v", settings.DataFile, err)
+		}
+		filePersistence.Start()
+		cfg.Store = filePersistence
+		log.Printf("✓ Using file enrollment storage at %s (snapshot every %v)", settings.DataFile, settings.SnapshotInterval)
+	case config.StorageSQLite:
+		sqliteRepo, err = repository.NewSQLiteRepository(settings.SQLitePath)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database %q: %v", settings.SQLitePath, err)
+		}
+		cfg.Store = sqliteRepo
+		log.Printf("✓ Using SQLite enrollment storage at %s", settings.SQLitePath)
+	}
+
+	// Enrollment lifecycle webhooks (WEBHOOK_URL, signed with WEBHOOK_SECRET)
+	var webhookNotifier *notify.WebhookNotifier
+	if settings.WebhookURL != "" {
+		webhookNotifier = notify.NewWebhookNotifier(settings.WebhookURL, settings.WebhookSecret)
+		cfg.Notifier = webhookNotifier
+		log.Printf("✓ Webhook notifications enabled (signed: %v)", settings.WebhookSecret != "")
+	}
+
+	application := app.NewApp(cfg)
+	if application.Cache != nil {
+		log.Printf("✓ Cache layer enabled (TTL: %v)", application.Cache.TTL())
+	}
+
+	// Import a known dataset into an empty store (SEED_FILE)
+	if settings.SeedFile != "" {
+		imported, err := application.ImportSeedFile(ctx, settings.SeedFile)
+		if err != nil {
+			log.Fatalf("Failed to import seed file %q: %v", settings.SeedFile, err)
+		}
+		log.Printf("✓ Imported %d enrollment(s) from %s", imported, settings.SeedFile)
+	}
+
+	// Expire enrollments left pending too long (PENDING_EXPIRY, unset disables)
+	if settings.PendingExpiry > 0 {
+		application.StartPendingExpiry(settings.PendingExpiryInterval, settings.PendingExpiry)
+		log.Printf("✓ Pending enrollments expire after %v (checked every %v)", settings.PendingExpiry, settings.PendingExpiryInterval)
+	}
+
+	// Warm the cache with existing enrollments (CACHE_WARM_LIMIT caps the count, 0 disables)
+	if application.Cache != nil {
+		start := time.Now()
+		warmed, err := application.WarmCache(ctx, settings.CacheWarmLimit)
+		if err != nil {
+			log.Printf("WARNING: Cache warm-up failed: %v", err)
+		} else {
+			log.Printf("✓ Cache warmed with %d enrollment(s) in %v", warmed, time.Since(start))
+		}
+	}
+
+	shutdownTimeout := settings.ShutdownTimeout
+	server := &http.Server{
+		Addr:    settings.Addr(),
+		Handler: application.Routes(),
 	}
 
-	// Initialize handlers with cache
-	enrollmentHandler := handlers.NewEnrollmentHandler(enrollmentRepo, enrollmentCache)
+	go func() {
+		fmt.Printf("🚀 Starting Grade Management API on port %d\n", settings.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// Wait for SIGINT/SIGTERM, then drain in-flight requests
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+	log.Printf("🛑 Received %v, shutting down (drain timeout: %v)", sig, shutdownTimeout)
 
-	// Setup router
-	router := mux.NewRouter()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("WARNING: HTTP server did not drain cleanly: %v", err)
+	} else {
+		log.Println("✓ HTTP server drained")
+	}
+
+	// Stop expiring enrollments and running jobs before the webhook queue
+	// and storage close
+	application.StopPendingExpiry()
+	application.StopJobs()
+	log.Println("✓ Background jobs stopped")
 
-	// Root endpoint
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		cacheStatus := "disabled"
-		if redisClient != nil {
-			cacheStatus = "enabled"
+	// Deliver events raised by the drained requests, giving up at the
+	// shutdown deadline
+	if webhookNotifier != nil {
+		if err := webhookNotifier.Close(shutdownCtx); err != nil {
+			log.Printf("WARNING: Webhook queue not flushed before the shutdown deadline: %v", err)
+		} else {
+			log.Println("✓ Webhook queue flushed")
 		}
-		fmt.Fprintf(w, "Grade Management API - Cache: %s", cacheStatus)
-	}).Methods("GET")
-
-	// Health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		health := map[string]interface{}{
-			"status": "healthy",
-			"cache":  redisClient != nil,
+	}
+
+	// Flush any buffered spans before exiting
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("WARNING: Failed to flush traces: %v", err)
+	}
+
+	// Close Redis only after the last request has finished with it
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			log.Printf("WARNING: Failed to close Redis client: %v", err)
+		} else {
+			log.Println("✓ Redis connection closed")
+		}
+	}
+	if replicaClient != nil {
+		if err := replicaClient.Close(); err != nil {
+			log.Printf("WARNING: Failed to close Redis replica client: %v", err)
+		} else {
+			log.Println("✓ Redis replica connection closed")
 		}
-		if redisClient != nil {
-			health["redis"] = redisClient.Ping(ctx).Err() == nil
+	}
+
+	// Write the final snapshot once no more requests can modify enrollments
+	if filePersistence != nil {
+		if err := filePersistence.Stop(); err != nil {
+			log.Printf("WARNING: Failed to write final snapshot: %v", err)
+		} else {
+			log.Println("✓ Final snapshot written")
 		}
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"healthy","cache":%v}`, health["cache"])
-	}).Methods("GET")
-
-	// API routes with /api prefix
-	apiRouter := router.PathPrefix("/api").Subrouter()
-
-	// Enrollment routes
-	apiRouter.HandleFunc("/enrollments", enrollmentHandler.CreateEnrollment).Methods("POST")
-	apiRouter.HandleFunc("/enrollments", enrollmentHandler.GetAllEnrollments).Methods("GET")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.GetEnrollment).Methods("GET")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.UpdateEnrollment).Methods("PUT")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.DeleteEnrollment).Methods("DELETE")
-
-	port := ":8080"
-	fmt.Printf("🚀 Starting Grade Management API on port %s\n", port)
-	log.Fatal(http.ListenAndServe(port, router))
+	}
+
+	// Close the database last so in-flight requests could still write to it
+	if sqliteRepo != nil {
+		if err := sqliteRepo.Close(); err != nil {
+			log.Printf("WARNING: Failed to close SQLite database: %v", err)
+		} else {
+			log.Println("✓ SQLite database closed")
+		}
+	}
+
+	log.Println("👋 Shutdown complete")
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"techwave/models"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured (1MB)
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// BodyLimit returns middleware that caps request bodies at maxBytes.
+// Requests that declare a larger Content-Length are rejected with 413 up
+// front; bodies without a declared length are wrapped in http.MaxBytesReader
+// so reads fail once the limit is crossed.
+func BodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				writeBodyTooLarge(w, r)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeBodyTooLarge sends the 413 for a body over the limit, matching the
+// one handlers send when a read hits http.MaxBytesReader
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request) {
+	response, _ := json.Marshal(models.ErrorResponse{
+		Error:     "Request body too large",
+		Code:      models.CodeBodyTooLarge,
+		RequestID: GetRequestID(r.Context()),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write(response)
+}
@@ -17,16 +17,26 @@ const (
 // cacheContextKey is the key for storing cache status in request context
 type cacheContextKey struct{}
 
+// cacheStatusHolder is a mutable slot shared between the middleware and the
+// handler, so a status set deep in the handler is visible when headers are written
+type cacheStatusHolder struct {
+	status CacheStatus
+}
+
 // SetCacheStatus sets the cache status in the request context
 func SetCacheStatus(r *http.Request, status CacheStatus) *http.Request {
-	ctx := context.WithValue(r.Context(), cacheContextKey{}, status)
+	if holder, ok := r.Context().Value(cacheContextKey{}).(*cacheStatusHolder); ok {
+		holder.status = status
+		return r
+	}
+	ctx := context.WithValue(r.Context(), cacheContextKey{}, &cacheStatusHolder{status: status})
 	return r.WithContext(ctx)
 }
 
 // GetCacheStatus retrieves the cache status from request context
 func GetCacheStatus(r *http.Request) CacheStatus {
-	if status, ok := r.Context().Value(cacheContextKey{}).(CacheStatus); ok {
-		return status
+	if holder, ok := r.Context().Value(cacheContextKey{}).(*cacheStatusHolder); ok {
+		return holder.status
 	}
 	return CacheSkip
 }
@@ -34,26 +44,38 @@ func GetCacheStatus(r *http.Request) CacheStatus {
 // CacheStatusMiddleware adds X-Cache-Status header to responses
 func CacheStatusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Wrap response writer to capture status
+		// Share a status holder with the handler through the request context
+		ctx := context.WithValue(r.Context(), cacheContextKey{}, &cacheStatusHolder{status: CacheSkip})
+		r = r.WithContext(ctx)
+
+		// Wrap response writer so the header is added before it is flushed
 		wrapped := &cacheStatusWriter{
 			ResponseWriter: w,
 			request:        r,
 		}
-		
+
 		next.ServeHTTP(wrapped, r)
-		
-		// Add cache status header after handler completes
-		status := GetCacheStatus(r)
-		w.Header().Set("X-Cache-Status", string(status))
 	})
 }
 
 // cacheStatusWriter wraps http.ResponseWriter
 type cacheStatusWriter struct {
 	http.ResponseWriter
-	request *http.Request
+	request     *http.Request
+	wroteHeader bool
 }
 
 func (w *cacheStatusWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("X-Cache-Status", string(GetCacheStatus(w.request)))
+	}
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+func (w *cacheStatusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
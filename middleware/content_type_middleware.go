@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"techwave/models"
+)
+
+// RequireJSON rejects POST, PUT and PATCH requests whose body is not declared
+// as application/json with 415 Unsupported Media Type, before a handler tries
+// to decode it. Parameters such as "; charset=utf-8" are allowed. Requests
+// without a body, like POST /api/enrollments/{id}/restore, need no
+// Content-Type.
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasBody(r) || isJSON(r.Header.Get("Content-Type")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response, _ := json.Marshal(models.ErrorResponse{
+			Error:     "Content-Type must be application/json",
+			Code:      models.CodeUnsupportedMediaType,
+			RequestID: GetRequestID(r.Context()),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		w.Write(response)
+	})
+}
+
+// hasBody reports whether r is a write that carries a body. A body of unknown
+// length counts as present.
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.ContentLength != 0
+	}
+	return false
+}
+
+// isJSON reports whether a Content-Type header names application/json
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
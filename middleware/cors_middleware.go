@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// corsAllowedMethods lists the methods browsers may use cross-origin
+	corsAllowedMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	// corsAllowedHeaders lists the request headers browsers may send cross-origin
+	corsAllowedHeaders = "Content-Type, Idempotency-Key, If-Match, X-API-Key, X-Request-ID"
+	// corsExposedHeaders lists the response headers readable by browser scripts
+	corsExposedHeaders = "ETag, X-Cache-Status, X-Cache-Tier, X-Cache-Degraded, X-Request-ID, Idempotent-Replayed, Warning, Retry-After"
+	// corsMaxAge is how long (in seconds) browsers may cache a preflight result
+	corsMaxAge = "600"
+)
+
+// CORS returns middleware that adds CORS headers for the allowed origins.
+// An origin of "*" allows any origin. Credentials are not allowed.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	return newCORS(allowedOrigins, false)
+}
+
+// CORSWithCredentials is like CORS but also allows cookies and auth headers.
+// The "*" wildcard is ignored in this mode, so only listed origins match.
+func CORSWithCredentials(allowedOrigins []string) func(http.Handler) http.Handler {
+	return newCORS(allowedOrigins, true)
+}
+
+// newCORS builds the CORS middleware.
+// Preflight OPTIONS requests are answered with 204 without reaching the
+// wrapped handler, so it must wrap the router rather than be added with Use.
+func newCORS(allowedOrigins []string, allowCredentials bool) func(http.Handler) http.Handler {
+	wildcard := false
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == "*" {
+			wildcard = !allowCredentials
+			continue
+		}
+		if origin != "" {
+			origins[origin] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && (wildcard || origins[origin])
+
+			if allowed {
+				if wildcard {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Add("Vary", "Origin")
+				}
+				if allowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			}
+
+			// Short-circuit preflight requests before they reach the router
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if allowed {
+					w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+					w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+					w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDebugBodyBytes caps each body DebugBodies logs when no cap is
+// configured (4KB)
+const DefaultDebugBodyBytes = 4 << 10
+
+// DefaultRedactedFields are the JSON fields DebugBodies masks when no
+// redaction list is configured
+var DefaultRedactedFields = []string{"password", "token", "secret", "api_key", "authorization"}
+
+// redactedValue replaces the value of every redacted field
+const redactedValue = "[REDACTED]"
+
+// debugBodyEntry is the JSON line written for every request by DebugBodies
+type debugBodyEntry struct {
+	Time              time.Time `json:"time"`
+	Method            string    `json:"method"`
+	Path              string    `json:"path"`
+	Status            int       `json:"status"`
+	RequestID         string    `json:"request_id,omitempty"`
+	RequestBody       string    `json:"request_body,omitempty"`
+	RequestTruncated  bool      `json:"request_truncated,omitempty"`
+	ResponseBody      string    `json:"response_body,omitempty"`
+	ResponseTruncated bool      `json:"response_truncated,omitempty"`
+}
+
+// DebugBodies returns middleware that writes one JSON line per request to out
+// with the request and response bodies, for diagnosing integration issues.
+// The request body is read in full and put back so handlers still see it;
+// the response is teed as it is written. Each logged body is cut to maxBytes
+// (DefaultDebugBodyBytes when zero or less), and the value of any JSON field
+// named in redact, matched case-insensitively at any depth, is masked.
+//
+// Buffering every body is expensive, so this is meant to be switched on only
+// while debugging. It should run after BodyLimit so oversized bodies are
+// refused before they are buffered.
+func DebugBodies(out io.Writer, maxBytes int, redact []string) func(http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		maxBytes = DefaultDebugBodyBytes
+	}
+	redactor := newRedactor(redact)
+	var mu sync.Mutex
+	encoder := json.NewEncoder(out)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			var requestBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				requestBody, err = io.ReadAll(r.Body)
+				// Replay what was read, then the read error, such as the
+				// http.MaxBytesError of an oversized body, to the handler
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), errorReader{err}))
+			}
+
+			tee := &teeRecorder{
+				statusRecorder: statusRecorder{ResponseWriter: w, status: http.StatusOK},
+				limit:          maxBytes,
+			}
+			next.ServeHTTP(tee, r)
+
+			entry := debugBodyEntry{
+				Time:              start.UTC(),
+				Method:            r.Method,
+				Path:              r.URL.Path,
+				Status:            tee.status,
+				RequestID:         GetRequestID(r.Context()),
+				ResponseBody:      redactor.redact(tee.body.Bytes(), tee.truncated),
+				ResponseTruncated: tee.truncated,
+			}
+			if len(requestBody) > 0 {
+				entry.RequestBody = redactor.redact(requestBody, false)
+				if len(entry.RequestBody) > maxBytes {
+					entry.RequestBody, entry.RequestTruncated = entry.RequestBody[:maxBytes], true
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			encoder.Encode(entry)
+		})
+	}
+}
+
+// errorReader returns err from every read, or io.EOF when err is nil
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	if r.err == nil {
+		return 0, io.EOF
+	}
+	return 0, r.err
+}
+
+// teeRecorder is a statusRecorder that also keeps the first limit bytes of
+// the response body
+type teeRecorder struct {
+	statusRecorder
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *teeRecorder) Write(b []byte) (int, error) {
+	if room := w.limit - w.body.Len(); room < len(b) {
+		w.body.Write(b[:max(room, 0)])
+		w.truncated = true
+	} else {
+		w.body.Write(b)
+	}
+	return w.statusRecorder.Write(b)
+}
+
+// redactor masks the values of a set of JSON fields
+type redactor struct {
+	fields map[string]bool
+	// pattern matches "field": value for the fields in bodies that cannot be
+	// parsed, such as truncated ones
+	pattern *regexp.Regexp
+}
+
+// newRedactor builds a redactor for fields; with no fields it masks nothing
+func newRedactor(fields []string) *redactor {
+	r := &redactor{fields: make(map[string]bool)}
+	var quoted []string
+	for _, field := range fields {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" && !r.fields[field] {
+			r.fields[field] = true
+			quoted = append(quoted, regexp.QuoteMeta(field))
+		}
+	}
+	if len(quoted) > 0 {
+		r.pattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	return r
+}
+
+// redact returns body with the redacted fields masked. A body that is
+// complete JSON is walked so nested values are masked whole; anything else,
+// including a truncated body, is masked textually.
+func (r *redactor) redact(body []byte, truncated bool) string {
+	if r.pattern == nil || len(body) == 0 {
+		return string(body)
+	}
+
+	var value interface{}
+	if !truncated && json.Unmarshal(body, &value) == nil {
+		if masked, err := json.Marshal(r.mask(value)); err == nil {
+			return string(masked)
+		}
+	}
+	return r.pattern.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+}
+
+// mask replaces the redacted fields of a decoded JSON value
+func (r *redactor) mask(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if r.fields[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = r.mask(inner)
+			}
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = r.mask(inner)
+		}
+	}
+	return value
+}
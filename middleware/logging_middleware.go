@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestLogEntry is the JSON line written for every request
+type requestLogEntry struct {
+	Time        time.Time `json:"time"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Status      int       `json:"status"`
+	DurationMs  float64   `json:"duration_ms"`
+	CacheStatus string    `json:"cache_status,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+// RequestLogger returns middleware that writes one JSON line per request to out
+// with the method, path, status code, duration, cache status and request ID
+func RequestLogger(out io.Writer) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(out)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &statusRecorder{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			entry := requestLogEntry{
+				Time:        start.UTC(),
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Status:      wrapped.status,
+				DurationMs:  float64(time.Since(start).Microseconds()) / 1000,
+				CacheStatus: w.Header().Get("X-Cache-Status"),
+				RequestID:   GetRequestID(r.Context()),
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			encoder.Encode(entry)
+		})
+	}
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
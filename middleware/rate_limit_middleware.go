@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"techwave/models"
+	"time"
+)
+
+// APIKeyHeader identifies a client independently of its IP address
+const APIKeyHeader = "X-API-Key"
+
+// rateLimitIdleTTL is how long an unused bucket is kept before it is dropped
+const rateLimitIdleTTL = 10 * time.Minute
+
+// tokenBucket tracks the remaining request allowance for one client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter holds one token bucket per client key
+type rateLimiter struct {
+	mu        sync.Mutex
+	rps       float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// RateLimit returns middleware that limits each client to rps requests per
+// second with bursts of up to burst requests. Clients are keyed by the
+// X-API-Key header when present, otherwise by remote IP. Requests over the
+// limit receive 429 with a Retry-After of the whole seconds until their next
+// token.
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	limiter := &rateLimiter{
+		rps:     rps,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter := limiter.allow(clientKey(r))
+			if !allowed {
+				respondWithRetry(w, http.StatusTooManyRequests, retryAfter, models.ErrorResponse{
+					Error: "Rate limit exceeded",
+					Code:  models.CodeRateLimited,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and the number of whole seconds until a token is available.
+func (l *rateLimiter) allow(key string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	// Refill for the time elapsed since the client was last seen
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rps)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rps * float64(time.Second))
+		return false, RetryAfterSeconds(wait)
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets idle for longer than rateLimitIdleTTL.
+// It runs at most once per TTL so the cost is amortised across requests;
+// callers must hold the lock.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastSeen) > rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey identifies the client for rate limiting
+func clientKey(r *http.Request) string {
+	if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+		return "key:" + apiKey
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
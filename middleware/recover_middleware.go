@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"techwave/models"
+)
+
+// Recover returns middleware that turns a handler panic into a JSON 500
+// {"error":"internal server error","code":"INTERNAL_ERROR"} and logs the panic with its stack trace
+// and request ID. If the handler had already started its response, the
+// connection is aborted instead, since a status can no longer be sent.
+//
+// With repanic set the panic is logged and then re-raised rather than
+// answered, so tests that exercise a handler fail loudly instead of seeing
+// an ordinary 500.
+func Recover(repanic bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				// http.ErrAbortHandler is a deliberate abort, not a failure
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				requestID := GetRequestID(r.Context())
+				log.Printf("PANIC: %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID, p, debug.Stack())
+				if repanic {
+					panic(p)
+				}
+				if recorder.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				response, _ := json.Marshal(models.ErrorResponse{
+					Error:     "internal server error",
+					Code:      models.CodeInternal,
+					RequestID: requestID,
+				})
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write(response)
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header used to receive and echo request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they can't bloat logs
+const maxRequestIDLength = 128
+
+// requestIDContextKey is the key for storing the request ID in request context
+type requestIDContextKey struct{}
+
+// RequestID propagates a correlation ID for each request.
+// An incoming X-Request-ID is reused when present, otherwise a UUID is
+// generated. The ID is stored in the request context and echoed back in the
+// response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDContextKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetRequestID retrieves the request ID from the context.
+// Returns an empty string if the RequestID middleware did not run.
+func GetRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"techwave/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// RequestValidation returns middleware that checks request bodies against the
+// OpenAPI spec before they reach a handler. Only bodies are checked; paths
+// the spec does not describe and operations without a request body pass
+// through. A body that violates its schema is rejected with 400, listing
+// every violation under "errors" like handler validation errors.
+func RequestValidation(spec []byte) (func(http.Handler) http.Handler, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load OpenAPI spec: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+
+	// Match routes on the path alone, whatever host the server is reached on
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build OpenAPI router: %w", err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := validateRequestBody(router, r); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeBodyTooLarge(w, r)
+					return
+				}
+				writeSchemaViolations(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// validateRequestBody checks r's body against its operation's schema. The
+// body is restored so the handler can still read it.
+func validateRequestBody(router routers.Router, r *http.Request) error {
+	route, pathParams, err := router.FindRoute(r)
+	if err != nil || route.Operation.RequestBody == nil {
+		return nil
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:          true,
+			SkipSettingDefaults: true,
+		},
+	}
+	return openapi3filter.ValidateRequestBody(r.Context(), input, route.Operation.RequestBody.Value)
+}
+
+// writeSchemaViolations sends 400 with one entry per schema violation
+func writeSchemaViolations(w http.ResponseWriter, err error) {
+	var violations models.ValidationErrors
+	collectViolations(err, &violations)
+
+	response, _ := json.Marshal(models.ErrorResponse{
+		Error:     "request body does not match the API schema",
+		Code:      models.CodeValidationFailed,
+		Errors:    violations,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(response)
+}
+
+// collectViolations flattens a validation error into field errors. Fields are
+// dotted JSON paths such as "0.student_id"; "body" stands for the body as a whole.
+func collectViolations(err error, violations *models.ValidationErrors) {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, inner := range e {
+			collectViolations(inner, violations)
+		}
+	case *openapi3.SchemaError:
+		field := strings.Join(e.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		*violations = append(*violations, models.FieldError{Field: field, Message: e.Reason})
+	case *openapi3filter.RequestError:
+		if e.Err == nil {
+			*violations = append(*violations, models.FieldError{Field: "body", Message: e.Reason})
+			return
+		}
+		collectViolations(e.Err, violations)
+	default:
+		*violations = append(*violations, models.FieldError{Field: "body", Message: err.Error()})
+	}
+}
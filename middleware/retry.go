@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"techwave/models"
+	"time"
+)
+
+// RetryAfterHeader tells clients how many seconds to wait before retrying a
+// 429 or 503 response
+const RetryAfterHeader = "Retry-After"
+
+// DefaultRetryAfter is the wait suggested by 503 responses whose cause has no
+// known end, such as a dependency that is down or a request that timed out
+const DefaultRetryAfter = 5 * time.Second
+
+// RetryAfterSeconds converts d to the whole seconds sent in Retry-After,
+// rounding up so clients never retry early, and never less than 1
+func RetryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+// SetRetryAfter sets the Retry-After header to seconds, clamped to at least 1
+func SetRetryAfter(w http.ResponseWriter, seconds int) {
+	w.Header().Set(RetryAfterHeader, strconv.Itoa(max(seconds, 1)))
+}
+
+// respondWithRetry sends body as a JSON error with status and a Retry-After
+// of seconds, adding the request ID set by RequestID when present
+func respondWithRetry(w http.ResponseWriter, status int, seconds int, body models.ErrorResponse) {
+	if body.RequestID == "" {
+		body.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	response, _ := json.Marshal(body)
+
+	SetRetryAfter(w, seconds)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"techwave/models"
+	"time"
+)
+
+// DefaultRequestTimeout is the per-request deadline the server applies by default
+const DefaultRequestTimeout = 30 * time.Second
+
+// Timeout returns middleware that gives each request d to complete. The
+// handler runs under a context with that deadline, so repository and cache
+// calls give up once it passes. A handler that has not finished by then is
+// answered with 503 {"error":"request timed out","code":"REQUEST_TIMEOUT"}
+// and a Retry-After of DefaultRetryAfter, and anything it writes afterwards
+// is discarded.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(d)
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+
+			tw := &timeoutWriter{header: w.Header().Clone(), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+			}
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			// A handler that returned as the deadline passed most likely failed
+			// because of it, so its response is replaced by the timeout too.
+			// Contexts derived from ctx may expire a moment before ctx itself,
+			// hence the clock check.
+			if ctx.Err() == nil && time.Now().Before(deadline) {
+				for name, values := range tw.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+				return
+			}
+
+			tw.timedOut = true
+			respondWithRetry(w, http.StatusServiceUnavailable, RetryAfterSeconds(DefaultRetryAfter), models.ErrorResponse{
+				Error: "request timed out",
+				Code:  models.CodeTimeout,
+			})
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response so Timeout can send it whole,
+// or drop it once the deadline has passed
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
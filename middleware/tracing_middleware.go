@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates server spans; it follows the global provider set by tracing.Setup
+var tracer = otel.Tracer("techwave/middleware")
+
+// Tracing starts a server span for each request, continuing any trace
+// propagated in the incoming traceparent header. The span is named after the
+// route template and records the enrollment id, status code and cache status.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+
+		if id := mux.Vars(r)["id"]; id != "" {
+			span.SetAttributes(attribute.String("enrollment.id", id))
+		}
+
+		wrapped := &statusRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.status))
+		if cacheStatus := w.Header().Get("X-Cache-Status"); cacheStatus != "" {
+			span.SetAttributes(attribute.String("cache.status", cacheStatus))
+		}
+		if wrapped.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.status))
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Audit actions recorded for enrollment mutations
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	// AuditActionExpire soft-deletes a pending enrollment that was never
+	// activated in time
+	AuditActionExpire = "expire"
+)
+
+// AuditEntry records a single change to an enrollment
+type AuditEntry struct {
+	EnrollmentID string    `json:"enrollment_id"`
+	Action       string    `json:"action"`
+	OldStatus    string    `json:"old_status,omitempty"`
+	NewStatus    string    `json:"new_status,omitempty"`
+	At           time.Time `json:"at"`
+}
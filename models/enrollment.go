@@ -1,41 +1,159 @@
 package models
 
 import (
-	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Enrollment represents a student enrollment in a course
 type Enrollment struct {
-	ID             string    `json:"id"`
-	StudentID      string    `json:"student_id"`
-	CourseID       string    `json:"course_id"`
-	EnrollmentDate time.Time `json:"enrollment_date"`
-	Status         string    `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             string     `json:"id"`
+	StudentID      string     `json:"student_id"`
+	CourseID       string     `json:"course_id"`
+	EnrollmentDate time.Time  `json:"enrollment_date"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	// CompletedAt is when the enrollment moved to "completed"; it is
+	// maintained by the repository and null for any other status
+	CompletedAt *time.Time `json:"completed_at"`
+	// Version starts at 1 and increases on every update; updates must name
+	// the version they were based on so concurrent writes cannot clobber
+	// each other
+	Version int `json:"version"`
+	// CourseName is an optional display name for the course, stored as sent
+	// on create and update. It is a snapshot: renaming the course elsewhere
+	// does not change enrollments already written.
+	CourseName string `json:"course_name,omitempty"`
+}
+
+// IsDeleted reports whether the enrollment has been soft-deleted
+func (e *Enrollment) IsDeleted() bool {
+	return e.DeletedAt != nil
+}
+
+// Clone returns a deep copy of the enrollment that shares no memory with it
+func (e *Enrollment) Clone() *Enrollment {
+	clone := *e
+	if e.DeletedAt != nil {
+		deletedAt := *e.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+	if e.CompletedAt != nil {
+		completedAt := *e.CompletedAt
+		clone.CompletedAt = &completedAt
+	}
+	return &clone
+}
+
+// TrackCompletion maintains CompletedAt for the enrollment's current status.
+// previous is the stored enrollment before this change, or nil for a new one.
+// Moving to "completed" stamps at; staying completed keeps the original
+// time; any other status clears it.
+func (e *Enrollment) TrackCompletion(previous *Enrollment, at time.Time) {
+	switch {
+	case e.Status != "completed":
+		e.CompletedAt = nil
+	case previous != nil && previous.Status == "completed" && previous.CompletedAt != nil:
+		e.CompletedAt = previous.CompletedAt
+	default:
+		e.CompletedAt = &at
+	}
+}
+
+const (
+	// MinIDLength is the shortest student_id or course_id accepted, after trimming
+	MinIDLength = 1
+	// MaxIDLength is the longest student_id or course_id accepted, in characters
+	MaxIDLength = 64
+	// MaxCourseNameLength is the longest course_name accepted, in characters
+	MaxCourseNameLength = 200
+)
+
+// MaxEnrollmentDateSkew is how far in the future an enrollment_date may be,
+// allowing for client clock drift and time zones
+var MaxEnrollmentDateSkew = 24 * time.Hour
+
+// NormalizeStatus returns the canonical form of a status: trimmed and
+// lowercased. Statuses are always stored in this form.
+func NormalizeStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
 }
 
 // ValidStatuses contains the allowed status values
 var ValidStatuses = map[string]bool{
-	"pending":   true,
-	"active":    true,
-	"completed": true,
+	"pending":    true,
+	"active":     true,
+	"completed":  true,
+	"waitlisted": true,
 }
 
-// Validate checks if the enrollment data is valid
-func (e *Enrollment) Validate() error {
-	if e.StudentID == "" {
-		return errors.New("student_id is required")
+// StatusTransitions lists the statuses each status may move to.
+// Staying in the same status is always allowed. Moving to "active" also
+// needs a free seat when the course has a capacity; handlers check that.
+var StatusTransitions = map[string][]string{
+	"pending":    {"active"},
+	"waitlisted": {"active"},
+	"active":     {"completed"},
+	"completed":  {},
+}
+
+// CanTransition reports whether an enrollment may move from one status to another
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
 	}
-	if e.CourseID == "" {
-		return errors.New("course_id is required")
+	for _, allowed := range StatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a status change is not allowed
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot transition from %s to %s", e.From, e.To)
+}
+
+// Validate checks if the enrollment data is valid. It normalizes Status
+// first, so " Active " is accepted and stored as "active", and trims
+// CourseName. Every invalid field is reported, as ValidationErrors.
+func (e *Enrollment) Validate() error {
+	var errs ValidationErrors
+
+	e.Status = NormalizeStatus(e.Status)
+	validateID(&errs, "student_id", e.StudentID)
+	validateID(&errs, "course_id", e.CourseID)
+	e.CourseName = strings.TrimSpace(e.CourseName)
+	if utf8.RuneCountInString(e.CourseName) > MaxCourseNameLength {
+		errs.add("course_name", "course_name exceeds maximum length")
 	}
 	if e.Status == "" {
-		return errors.New("status is required")
+		errs.add("status", "status is required")
+	} else if !ValidStatuses[e.Status] {
+		errs.add("status", "status must be one of: pending, active, completed, waitlisted")
 	}
-	if !ValidStatuses[e.Status] {
-		return errors.New("status must be one of: pending, active, completed")
+	// A zero date is allowed; it defaults to the creation time
+	if !e.EnrollmentDate.IsZero() && e.EnrollmentDate.After(time.Now().Add(MaxEnrollmentDateSkew)) {
+		errs.add("enrollment_date", "enrollment_date cannot be in the future")
+	}
+	return errs.err()
+}
+
+// validateID checks that an ID is not blank and within the length limits
+func validateID(errs *ValidationErrors, field, value string) {
+	if utf8.RuneCountInString(strings.TrimSpace(value)) < MinIDLength {
+		errs.add(field, field+" is required")
+	} else if utf8.RuneCountInString(value) > MaxIDLength {
+		errs.add(field, field+" exceeds maximum length")
 	}
-	return nil
 }
@@ -0,0 +1,88 @@
+package models
+
+// ErrorCode is a stable, machine-readable identifier for an error response.
+// Clients should branch on the code rather than on the message, which is
+// meant for people and may be reworded.
+type ErrorCode string
+
+// Error codes sent in ErrorResponse.Code
+const (
+	// CodeInvalidID is a malformed enrollment ID in the path or body
+	CodeInvalidID ErrorCode = "INVALID_ID"
+	// CodeInvalidParameter is a missing or malformed query parameter
+	CodeInvalidParameter ErrorCode = "INVALID_PARAMETER"
+	// CodeInvalidBody is a body that is not valid JSON or has unknown fields
+	CodeInvalidBody ErrorCode = "INVALID_BODY"
+	// CodeValidationFailed is a well-formed body with invalid values; the
+	// failing fields are listed in ErrorResponse.Errors when known
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	// CodeBodyTooLarge is a body over the configured size limit
+	CodeBodyTooLarge ErrorCode = "BODY_TOO_LARGE"
+	// CodeUnsupportedMediaType is a body not sent as application/json
+	CodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+
+	// CodeEnrollmentNotFound is an enrollment that does not exist or is deleted
+	CodeEnrollmentNotFound ErrorCode = "ENROLLMENT_NOT_FOUND"
+	// CodeStudentNotFound is a student with no enrollments
+	CodeStudentNotFound ErrorCode = "STUDENT_NOT_FOUND"
+	// CodeCourseNotFound is a course with no enrollments
+	CodeCourseNotFound ErrorCode = "COURSE_NOT_FOUND"
+	// CodeGradeNotFound is a grade that does not exist or belongs to another
+	// enrollment
+	CodeGradeNotFound ErrorCode = "GRADE_NOT_FOUND"
+	// CodeJobNotFound is an unknown or expired background job
+	CodeJobNotFound ErrorCode = "JOB_NOT_FOUND"
+	// CodeNotFound is a path that matches no route
+	CodeNotFound ErrorCode = "NOT_FOUND"
+	// CodeMethodNotAllowed is a method the path does not accept
+	CodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+
+	// CodeEnrollmentExists is a student already enrolled in the course
+	CodeEnrollmentExists ErrorCode = "ENROLLMENT_EXISTS"
+	// CodeEnrollmentIDTaken is a supplied enrollment ID that is already used
+	CodeEnrollmentIDTaken ErrorCode = "ENROLLMENT_ID_TAKEN"
+	// CodeEnrollmentNotDeleted is a restore of an enrollment that is live
+	CodeEnrollmentNotDeleted ErrorCode = "ENROLLMENT_NOT_DELETED"
+	// CodeInvalidTransition is a status change the transition rules forbid
+	CodeInvalidTransition ErrorCode = "INVALID_STATUS_TRANSITION"
+	// CodeVersionConflict is an update based on a stale version or ETag
+	CodeVersionConflict ErrorCode = "VERSION_CONFLICT"
+	// CodeVersionRequired is an update that names neither version nor If-Match
+	CodeVersionRequired ErrorCode = "VERSION_REQUIRED"
+	// CodeCourseFull is a move to active in a course with no free seat
+	CodeCourseFull ErrorCode = "COURSE_FULL"
+	// CodeIdempotencyInFlight is a create whose Idempotency-Key is held by
+	// another request that has not finished
+	CodeIdempotencyInFlight ErrorCode = "IDEMPOTENCY_KEY_IN_USE"
+
+	// CodeUnauthorized is an admin request without the right X-API-Key
+	CodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// CodeAdminDisabled is an admin request while no admin key is configured
+	CodeAdminDisabled ErrorCode = "ADMIN_DISABLED"
+	// CodeResetForbidden is a storage reset in production
+	CodeResetForbidden ErrorCode = "RESET_FORBIDDEN"
+	// CodeRateLimited is a client over its request rate
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+
+	// CodeSeatsBusy is a course seat lock held too long by another instance
+	CodeSeatsBusy ErrorCode = "SEATS_BUSY"
+	// CodeJobQueueUnavailable is a job queue that is full or shutting down
+	CodeJobQueueUnavailable ErrorCode = "JOB_QUEUE_UNAVAILABLE"
+	// CodeCacheUnavailable is a cache that is disabled or cannot be reached
+	CodeCacheUnavailable ErrorCode = "CACHE_UNAVAILABLE"
+	// CodeTimeout is a request that ran past the server's deadline
+	CodeTimeout ErrorCode = "REQUEST_TIMEOUT"
+	// CodeInternal is an unexpected server-side failure
+	CodeInternal ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the body of every JSON error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code identifies the kind of error for programs; see ErrorCode
+	Code ErrorCode `json:"code"`
+	// Errors lists every invalid field; only validation errors set it
+	Errors ValidationErrors `json:"errors,omitempty"`
+	// RequestID matches the X-Request-ID header of the failed request
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Grade represents a score awarded for an enrollment
+type Grade struct {
+	ID           string    `json:"id"`
+	EnrollmentID string    `json:"enrollment_id"`
+	Score        float64   `json:"score"`
+	LetterGrade  string    `json:"letter_grade"`
+	GradedAt     time.Time `json:"graded_at"`
+	// Components are the weighted parts, such as assignments and exams, the
+	// score was computed from; a grade sent without any keeps its score as is
+	Components []GradeComponent `json:"components,omitempty"`
+	// Passed is derived from Score and the passing score whenever the grade
+	// is read; it is never stored
+	Passed bool `json:"passed"`
+}
+
+// GradeComponent is one weighted part of a grade
+type GradeComponent struct {
+	Name string `json:"name"`
+	// Weight is the component's share of the grade, above 0 and at most 1;
+	// the weights of a grade's components sum to 1
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+}
+
+// ComponentWeightTolerance is how far the weights of a grade's components
+// may sum from 1, so weights such as 0.333, 0.333 and 0.334 are accepted
+const ComponentWeightTolerance = 0.001
+
+// MaxComponentNameLength caps the name of a grade component
+const MaxComponentNameLength = 100
+
+// DefaultPassingScore is the lowest score that passes unless configured otherwise
+const DefaultPassingScore = 60.0
+
+// GradeSummary describes the spread of an enrollment's scores. Every
+// statistic is zero when Count is zero.
+type GradeSummary struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	// PassedCount is how many grades reach the passing score
+	PassedCount int `json:"passed_count"`
+	// Passed reports whether the mean reaches the passing score; it is
+	// false when there are no grades
+	Passed bool `json:"passed"`
+}
+
+// ValidLetterGrades contains the allowed letter grade values
+var ValidLetterGrades = map[string]bool{
+	"A": true,
+	"B": true,
+	"C": true,
+	"D": true,
+	"F": true,
+}
+
+// GradeThreshold awards Letter to scores of at least MinScore
+type GradeThreshold struct {
+	MinScore float64
+	Letter   string
+}
+
+// GradeScale maps scores to letter grades. Thresholds are ordered from the
+// highest MinScore down; Validate enforces this.
+type GradeScale []GradeThreshold
+
+// DefaultGradeScale is the standard US scale: 90 A, 80 B, 70 C, 60 D
+var DefaultGradeScale = GradeScale{
+	{MinScore: 90, Letter: "A"},
+	{MinScore: 80, Letter: "B"},
+	{MinScore: 70, Letter: "C"},
+	{MinScore: 60, Letter: "D"},
+	{MinScore: 0, Letter: "F"},
+}
+
+// Letter derives the letter grade for score: the letter of the first
+// threshold the score reaches, or the lowest letter when it reaches none
+func (s GradeScale) Letter(score float64) string {
+	for _, threshold := range s {
+		if score >= threshold.MinScore {
+			return threshold.Letter
+		}
+	}
+	return s[len(s)-1].Letter
+}
+
+// Validate checks that the scale is usable: at least one threshold, every
+// letter a valid grade used once, and MinScore within 0-100 and strictly
+// decreasing
+func (s GradeScale) Validate() error {
+	if len(s) == 0 {
+		return errors.New("at least one threshold is required")
+	}
+
+	seen := make(map[string]bool, len(s))
+	for i, threshold := range s {
+		if !ValidLetterGrades[threshold.Letter] {
+			return fmt.Errorf("letter %q must be one of: A, B, C, D, F", threshold.Letter)
+		}
+		if seen[threshold.Letter] {
+			return fmt.Errorf("letter %q appears more than once", threshold.Letter)
+		}
+		seen[threshold.Letter] = true
+
+		if threshold.MinScore < 0 || threshold.MinScore > 100 {
+			return fmt.Errorf("threshold for %s must be between 0 and 100", threshold.Letter)
+		}
+		if i > 0 && threshold.MinScore >= s[i-1].MinScore {
+			return fmt.Errorf("threshold for %s must be below the threshold for %s", threshold.Letter, s[i-1].Letter)
+		}
+	}
+	return nil
+}
+
+// ParseGradeScale reads a scale written as comma-separated LETTER:MIN_SCORE
+// pairs from the highest grade down, e.g. "A:93,B:85,C:77,D:70,F:0", and
+// validates it
+func ParseGradeScale(raw string) (GradeScale, error) {
+	var scale GradeScale
+	for _, pair := range strings.Split(raw, ",") {
+		letter, rawScore, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a LETTER:MIN_SCORE pair", pair)
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(rawScore), 64)
+		if err != nil {
+			return nil, fmt.Errorf("threshold %q is not a number", rawScore)
+		}
+		scale = append(scale, GradeThreshold{
+			MinScore: score,
+			Letter:   strings.ToUpper(strings.TrimSpace(letter)),
+		})
+	}
+
+	if err := scale.Validate(); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+// LetterForScore derives a letter grade from a score on the standard US scale
+func LetterForScore(score float64) string {
+	return DefaultGradeScale.Letter(score)
+}
+
+// GradePoints maps a letter grade to points on a 4.0 scale
+var GradePoints = map[string]float64{
+	"A": 4.0,
+	"B": 3.0,
+	"C": 2.0,
+	"D": 1.0,
+	"F": 0.0,
+}
+
+// Validate checks if the grade data is valid. A grade with components has
+// its score replaced by their weighted average, rounded to two decimal
+// places, once the components are found valid.
+func (g *Grade) Validate() error {
+	if g.EnrollmentID == "" {
+		return errors.New("enrollment_id is required")
+	}
+	if len(g.Components) > 0 {
+		if err := g.validateComponents(); err != nil {
+			return err
+		}
+		g.Score = g.weightedScore()
+	}
+	if g.Score < 0 || g.Score > 100 {
+		return errors.New("score must be between 0 and 100")
+	}
+	if g.LetterGrade != "" && !ValidLetterGrades[g.LetterGrade] {
+		return errors.New("letter_grade must be one of: A, B, C, D, F")
+	}
+	return nil
+}
+
+// validateComponents checks every component and that their weights sum to 1
+// within ComponentWeightTolerance
+func (g *Grade) validateComponents() error {
+	seen := make(map[string]bool, len(g.Components))
+	var total float64
+	for i := range g.Components {
+		component := &g.Components[i]
+		component.Name = strings.TrimSpace(component.Name)
+		switch {
+		case component.Name == "":
+			return fmt.Errorf("components[%d].name is required", i)
+		case utf8.RuneCountInString(component.Name) > MaxComponentNameLength:
+			return fmt.Errorf("components[%d].name exceeds maximum length", i)
+		case seen[strings.ToLower(component.Name)]:
+			return fmt.Errorf("component %q appears more than once", component.Name)
+		case component.Weight <= 0 || component.Weight > 1:
+			return fmt.Errorf("component %q weight must be above 0 and at most 1", component.Name)
+		case component.Score < 0 || component.Score > 100:
+			return fmt.Errorf("component %q score must be between 0 and 100", component.Name)
+		}
+		seen[strings.ToLower(component.Name)] = true
+		total += component.Weight
+	}
+
+	if math.Abs(total-1) > ComponentWeightTolerance {
+		return fmt.Errorf("component weights must sum to 1, got %s", strconv.FormatFloat(math.Round(total*1e6)/1e6, 'f', -1, 64))
+	}
+	return nil
+}
+
+// weightedScore is the weighted average of the component scores. Dividing
+// by the weight total keeps it within 0-100 when the weights are within
+// ComponentWeightTolerance of 1.
+func (g *Grade) weightedScore() float64 {
+	var weighted, total float64
+	for _, component := range g.Components {
+		weighted += component.Weight * component.Score
+		total += component.Weight
+	}
+	return math.Round(weighted/total*100) / 100
+}
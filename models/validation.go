@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes why a single field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every field that failed validation, in the order
+// the fields were checked
+type ValidationErrors []FieldError
+
+// Error summarizes the failures. A single failure reads as its own message,
+// so callers that only show one line keep seeing e.g. "status is required".
+func (v ValidationErrors) Error() string {
+	if len(v) == 1 {
+		return v[0].Message
+	}
+
+	messages := make([]string, len(v))
+	for i, fieldErr := range v {
+		messages[i] = fieldErr.Message
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(v), strings.Join(messages, "; "))
+}
+
+// add records a failure for field
+func (v *ValidationErrors) add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// err returns v as an error, or nil when nothing failed
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"techwave/models"
+	"time"
+)
+
+// Enrollment lifecycle event types
+const (
+	EventEnrollmentCreated = "enrollment.created"
+	EventEnrollmentUpdated = "enrollment.updated"
+	EventEnrollmentDeleted = "enrollment.deleted"
+)
+
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of
+	// the request body, keyed with the webhook secret
+	SignatureHeader = "X-Webhook-Signature"
+	// EventTypeHeader repeats the event type so receivers can route without parsing
+	EventTypeHeader = "X-Webhook-Event"
+
+	// webhookQueueSize is how many events may wait for delivery before new
+	// ones are dropped
+	webhookQueueSize = 256
+	// webhookMaxAttempts is how many times an event is sent before giving up
+	webhookMaxAttempts = 5
+	// webhookBaseDelay is the wait before the first retry; it doubles per attempt
+	webhookBaseDelay = 500 * time.Millisecond
+	// webhookTimeout bounds each delivery attempt
+	webhookTimeout = 5 * time.Second
+)
+
+// Event is the JSON body POSTed to the webhook
+type Event struct {
+	EventType  string             `json:"event_type"`
+	Enrollment *models.Enrollment `json:"enrollment"`
+	Timestamp  time.Time          `json:"timestamp"`
+}
+
+// Notifier publishes enrollment lifecycle events.
+// Notify must not block the caller on delivery.
+type Notifier interface {
+	Notify(eventType string, enrollment *models.Enrollment)
+}
+
+// WebhookNotifier delivers events to a URL from a background worker, in the
+// order they were raised. Failed deliveries (network errors, 429 and 5xx) are
+// retried with exponential backoff; other responses are final.
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+
+	maxAttempts int
+	baseDelay   time.Duration
+
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// ctx is cancelled when Close gives up waiting, which aborts the attempt
+	// in flight and any backoff so the worker can exit
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWebhookNotifier creates a notifier posting to url and starts its worker.
+// When secret is non-empty every request is signed in SignatureHeader.
+// Call Close on shutdown to deliver queued events.
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return NewWebhookNotifierWithRetry(url, secret, webhookMaxAttempts, webhookBaseDelay)
+}
+
+// NewWebhookNotifierWithRetry is like NewWebhookNotifier with a custom
+// attempt limit and initial backoff delay
+func NewWebhookNotifierWithRetry(url, secret string, maxAttempts int, baseDelay time.Duration) *WebhookNotifier {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &WebhookNotifier{
+		url:         url,
+		secret:      []byte(secret),
+		client:      &http.Client{Timeout: webhookTimeout},
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		events:      make(chan Event, webhookQueueSize),
+		done:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	go n.run()
+	return n
+}
+
+// Notify queues an event for delivery. It never blocks: when the queue is
+// full the event is dropped and a warning logged.
+func (n *WebhookNotifier) Notify(eventType string, enrollment *models.Enrollment) {
+	event := Event{
+		EventType:  eventType,
+		Enrollment: enrollment,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	select {
+	case n.events <- event:
+	default:
+		log.Printf("WARNING: Webhook queue full, dropping %s event for enrollment %s", eventType, enrollment.ID)
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered
+// until ctx ends. After that the delivery in flight is abandoned, retries
+// stop, the events still queued are dropped with a warning and ctx's error
+// is returned. Notify must not be called after Close.
+func (n *WebhookNotifier) Close(ctx context.Context) error {
+	n.closeOnce.Do(func() {
+		close(n.events)
+	})
+	defer n.cancel()
+
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+	}
+	n.cancel()
+	<-n.done
+	return ctx.Err()
+}
+
+// run delivers queued events until the queue is closed. Once the notifier is
+// cancelled the remaining events are only counted.
+func (n *WebhookNotifier) run() {
+	defer close(n.done)
+
+	dropped := 0
+	for event := range n.events {
+		if n.ctx.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := n.deliver(event); err != nil {
+			log.Printf("WARNING: Webhook delivery of %s for enrollment %s failed: %v", event.EventType, event.Enrollment.ID, err)
+		}
+	}
+	if dropped > 0 {
+		log.Printf("WARNING: Webhook shutdown deadline passed, dropped %d queued event(s)", dropped)
+	}
+}
+
+// deliver sends one event, retrying retryable failures with backoff until the
+// notifier is cancelled
+func (n *WebhookNotifier) deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delay := n.baseDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := n.send(event.EventType, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == n.maxAttempts {
+			return fmt.Errorf("after %d attempt(s): %w", attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-n.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("after %d attempt(s), retries stopped by shutdown: %w", attempt, err)
+		}
+		delay *= 2
+	}
+}
+
+// send makes a single delivery attempt and reports whether a failure is
+// worth retrying
+func (n *WebhookNotifier) send(eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, eventType)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the SignatureHeader value for body: "sha256=" followed by the
+// hex HMAC-SHA256 keyed with secret. Receivers recompute it to verify events.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookNotifier must satisfy Notifier
+var _ Notifier = (*WebhookNotifier)(nil)
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"sync"
+	"techwave/models"
+)
+
+// AuditRepository stores an append-only trail of enrollment changes
+type AuditRepository struct {
+	mu      sync.RWMutex
+	entries map[string][]models.AuditEntry
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		entries: make(map[string][]models.AuditEntry),
+	}
+}
+
+// Record appends an entry to an enrollment's history
+func (r *AuditRepository) Record(entry models.AuditEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[entry.EnrollmentID] = append(r.entries[entry.EnrollmentID], entry)
+}
+
+// GetByEnrollment retrieves an enrollment's history in the order it was recorded
+func (r *AuditRepository) GetByEnrollment(enrollmentID string) []models.AuditEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]models.AuditEntry, len(r.entries[enrollmentID]))
+	copy(history, r.entries[enrollmentID])
+	return history
+}
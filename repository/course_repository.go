@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrCourseFull is returned when an enrollment would become active in a
+// course whose active enrollments already fill its capacity
+var ErrCourseFull = errors.New("course is full")
+
+// CourseRepository holds per-course seat capacities. Courses without a
+// capacity have unlimited seats.
+type CourseRepository struct {
+	mu         sync.RWMutex
+	capacities map[string]int
+
+	// seats serializes each seat check with the write that depends on it,
+	// so concurrent requests cannot both take the last seat
+	seats sync.Mutex
+}
+
+// NewCourseRepository creates a course repository with no capacities set
+func NewCourseRepository() *CourseRepository {
+	return &CourseRepository{
+		capacities: make(map[string]int),
+	}
+}
+
+// SetCapacity sets how many active enrollments a course may hold. Lowering
+// it below the current number of active enrollments moves no one; new
+// enrollments wait until enough seats free up.
+func (r *CourseRepository) SetCapacity(courseID string, capacity int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.capacities[courseID] = capacity
+}
+
+// Capacity returns a course's capacity and whether one has been set
+func (r *CourseRepository) Capacity(courseID string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	capacity, ok := r.capacities[courseID]
+	return capacity, ok
+}
+
+// WithSeats runs fn while holding the seat lock. Callers count a course's
+// active enrollments and write any change that takes a seat inside fn.
+func (r *CourseRepository) WithSeats(fn func() error) error {
+	r.seats.Lock()
+	defer r.seats.Unlock()
+
+	return fn()
+}
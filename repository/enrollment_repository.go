@@ -1,95 +1,706 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"techwave/models"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer creates repository spans; it follows the global provider set by tracing.Setup
+var tracer = otel.Tracer("techwave/repository")
+
 var (
 	// ErrNotFound is returned when an enrollment is not found
 	ErrNotFound = errors.New("enrollment not found")
 	// ErrAlreadyExists is returned when an enrollment already exists
 	ErrAlreadyExists = errors.New("enrollment already exists")
+	// ErrIDTaken is returned when creating an enrollment whose ID is already
+	// used, as opposed to a second enrollment of the student in the course
+	ErrIDTaken = errors.New("enrollment id already exists")
+	// ErrNotDeleted is returned when restoring an enrollment that is not deleted
+	ErrNotDeleted = errors.New("enrollment is not deleted")
+	// ErrVersionConflict is returned when an update names a stale version
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrInvalidSortField is returned when sorting by an unsupported field
+	ErrInvalidSortField = errors.New("sort must be one of: enrollment_date, created_at, updated_at, status")
+	// ErrInvalidSortOrder is returned when the sort order is not asc or desc
+	ErrInvalidSortOrder = errors.New("order must be asc or desc")
+)
+
+const (
+	// SortAsc orders enrollments from smallest to largest
+	SortAsc = "asc"
+	// SortDesc orders enrollments from largest to smallest
+	SortDesc = "desc"
 )
 
+// ValidSortFields lists the fields enrollments can be sorted by
+var ValidSortFields = map[string]bool{
+	"enrollment_date": true,
+	"created_at":      true,
+	"updated_at":      true,
+	"status":          true,
+}
+
+// enrollmentSortKeys compares two enrollments by each sortable field,
+// returning a negative, zero or positive result
+var enrollmentSortKeys = map[string]func(a, b *models.Enrollment) int{
+	"enrollment_date": func(a, b *models.Enrollment) int { return a.EnrollmentDate.Compare(b.EnrollmentDate) },
+	"created_at":      func(a, b *models.Enrollment) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at":      func(a, b *models.Enrollment) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	"status":          func(a, b *models.Enrollment) int { return strings.Compare(a.Status, b.Status) },
+}
+
 // EnrollmentRepository manages enrollment data storage
 type EnrollmentRepository struct {
 	mu          sync.RWMutex
 	enrollments map[string]*models.Enrollment
+	// byStudentCourse indexes live enrollments by student and course,
+	// mapping studentCourseKey to enrollment ID
+	byStudentCourse map[string]string
+	// byCreated lists every enrollment ID, soft-deleted ones included, in
+	// creation order so Recent can read the newest from its end
+	byCreated []string
+	// byStatus indexes every enrollment, soft-deleted ones included, by
+	// status and then ID, so status filters only visit their matches. It
+	// holds the same pointers as enrollments and is kept in step by put.
+	byStatus map[string]map[string]*models.Enrollment
 }
 
 // NewEnrollmentRepository creates a new enrollment repository
 func NewEnrollmentRepository() *EnrollmentRepository {
 	return &EnrollmentRepository{
-		enrollments: make(map[string]*models.Enrollment),
+		enrollments:     make(map[string]*models.Enrollment),
+		byStudentCourse: make(map[string]string),
+		byStatus:        make(map[string]map[string]*models.Enrollment),
 	}
 }
 
-// Create adds a new enrollment to the repository
-func (r *EnrollmentRepository) Create(enrollment *models.Enrollment) error {
+// studentCourseKey builds the secondary index key for a student and course
+func studentCourseKey(studentID, courseID string) string {
+	return studentID + ":" + courseID
+}
+
+// Create adds a new enrollment to the repository at version 1
+func (r *EnrollmentRepository) Create(ctx context.Context, enrollment *models.Enrollment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.enrollments[enrollment.ID]; exists {
+		return ErrIDTaken
+	}
+	key := studentCourseKey(enrollment.StudentID, enrollment.CourseID)
+	if _, exists := r.byStudentCourse[key]; exists {
 		return ErrAlreadyExists
 	}
 
-	r.enrollments[enrollment.ID] = enrollment
+	enrollment.Version = 1
+	enrollment.TrackCompletion(nil, time.Now())
+	r.put(enrollment)
+	r.byStudentCourse[key] = enrollment.ID
+	r.indexCreated(enrollment)
 	return nil
 }
 
-// GetByID retrieves an enrollment by ID
-func (r *EnrollmentRepository) GetByID(id string) (*models.Enrollment, error) {
+// CreateBatch adds several enrollments under a single write lock.
+// The returned slice has one entry per input; nil means the insert succeeded.
+// A failed item does not prevent the remaining items from being inserted.
+func (r *EnrollmentRepository) CreateBatch(enrollments []*models.Enrollment) []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make([]error, len(enrollments))
+	for i, enrollment := range enrollments {
+		if _, exists := r.enrollments[enrollment.ID]; exists {
+			errs[i] = ErrIDTaken
+			continue
+		}
+		key := studentCourseKey(enrollment.StudentID, enrollment.CourseID)
+		if _, exists := r.byStudentCourse[key]; exists {
+			errs[i] = ErrAlreadyExists
+			continue
+		}
+		enrollment.Version = 1
+		enrollment.TrackCompletion(nil, time.Now())
+		r.put(enrollment)
+		r.byStudentCourse[key] = enrollment.ID
+		r.indexCreated(enrollment)
+	}
+
+	return errs
+}
+
+// ExistsForStudentCourse reports whether the student has a live enrollment
+// in the course. Soft-deleted enrollments are not counted.
+func (r *EnrollmentRepository) ExistsForStudentCourse(studentID, courseID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.byStudentCourse[studentCourseKey(studentID, courseID)]
+	return exists
+}
+
+// GetByID retrieves a copy of an enrollment by ID, recording a child span of
+// any trace carried by ctx. Changing the copy does not change the stored
+// enrollment; write changes back with Update.
+func (r *EnrollmentRepository) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	_, span := tracer.Start(ctx, "repository.GetByID", trace.WithAttributes(attribute.String("enrollment.id", id)))
+	defer span.End()
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	enrollment, exists := r.enrollments[id]
-	if !exists {
+	if !exists || enrollment.IsDeleted() {
 		return nil, ErrNotFound
 	}
 
-	return enrollment, nil
+	return enrollment.Clone(), nil
 }
 
-// GetAll retrieves all enrollments
-func (r *EnrollmentRepository) GetAll() []*models.Enrollment {
+// GetAll retrieves all enrollments that have not been soft-deleted.
+// The order is stable across calls: by creation time, then by ID. Each
+// enrollment is a deep copy, so the result is a stable snapshot that callers
+// may read or modify while other requests update the repository. The copy
+// is made from memory in one pass, so ctx is not consulted.
+func (r *EnrollmentRepository) GetAll(ctx context.Context) []*models.Enrollment {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	enrollments := make([]*models.Enrollment, 0, len(r.enrollments))
 	for _, enrollment := range r.enrollments {
-		enrollments = append(enrollments, enrollment)
+		if enrollment.IsDeleted() {
+			continue
+		}
+		enrollments = append(enrollments, enrollment.Clone())
 	}
+	sortByCreatedAt(enrollments)
 
 	return enrollments
 }
 
-// Update modifies an existing enrollment
-func (r *EnrollmentRepository) Update(id string, enrollment *models.Enrollment) error {
+// Recent retrieves up to limit enrollments that have not been soft-deleted,
+// newest first by creation time and then ID. It reads the creation index
+// from the newest end instead of sorting every enrollment, and returns deep
+// copies like GetAll.
+func (r *EnrollmentRepository) Recent(limit int) []*models.Enrollment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recent := make([]*models.Enrollment, 0, min(limit, len(r.byCreated)))
+	for i := len(r.byCreated) - 1; i >= 0 && len(recent) < limit; i-- {
+		enrollment := r.enrollments[r.byCreated[i]]
+		if enrollment.IsDeleted() {
+			continue
+		}
+		recent = append(recent, enrollment.Clone())
+	}
+
+	return recent
+}
+
+// SortEnrollments orders enrollments in place by field in the given order
+// (asc or desc, default desc). Ties are broken by creation time and then ID,
+// both ascending, so the result is deterministic.
+func SortEnrollments(enrollments []*models.Enrollment, field, order string) error {
+	compare, ok := enrollmentSortKeys[field]
+	if !ok {
+		return ErrInvalidSortField
+	}
+	if order == "" {
+		order = SortDesc
+	}
+	if order != SortAsc && order != SortDesc {
+		return ErrInvalidSortOrder
+	}
+
+	sort.SliceStable(enrollments, func(i, j int) bool {
+		if c := compare(enrollments[i], enrollments[j]); c != 0 {
+			if order == SortDesc {
+				return c > 0
+			}
+			return c < 0
+		}
+		if c := enrollments[i].CreatedAt.Compare(enrollments[j].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return enrollments[i].ID < enrollments[j].ID
+	})
+
+	return nil
+}
+
+// EnrollmentFilter narrows enrollment queries; empty fields match everything.
+// FromDate and ToDate bound EnrollmentDate inclusively when non-zero, and
+// CreatedBefore matches enrollments created strictly before it.
+// Soft-deleted enrollments are excluded unless IncludeDeleted is set.
+type EnrollmentFilter struct {
+	ID             string
+	StudentID      string
+	CourseID       string
+	Status         string
+	FromDate       time.Time
+	ToDate         time.Time
+	CreatedBefore  time.Time
+	IncludeDeleted bool
+}
+
+// IsEmpty reports whether the filter sets no criteria, so it would match
+// every live enrollment
+func (f EnrollmentFilter) IsEmpty() bool {
+	return f.ID == "" && f.StudentID == "" && f.CourseID == "" && f.Status == "" &&
+		f.FromDate.IsZero() && f.ToDate.IsZero() && f.CreatedBefore.IsZero()
+}
+
+// Matches reports whether an enrollment satisfies every set filter field
+func (f EnrollmentFilter) Matches(enrollment *models.Enrollment) bool {
+	if !f.IncludeDeleted && enrollment.IsDeleted() {
+		return false
+	}
+	if f.ID != "" && enrollment.ID != f.ID {
+		return false
+	}
+	if f.StudentID != "" && enrollment.StudentID != f.StudentID {
+		return false
+	}
+	if f.CourseID != "" && enrollment.CourseID != f.CourseID {
+		return false
+	}
+	if f.Status != "" && enrollment.Status != f.Status {
+		return false
+	}
+	if !f.FromDate.IsZero() && enrollment.EnrollmentDate.Before(f.FromDate) {
+		return false
+	}
+	if !f.ToDate.IsZero() && enrollment.EnrollmentDate.After(f.ToDate) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !enrollment.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// Find retrieves all enrollments matching the filter, ordered by creation time
+func (r *EnrollmentRepository) Find(filter EnrollmentFilter) []*models.Enrollment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(filter)
+}
+
+// Search finds live enrollments whose student or course ID contains q,
+// ignoring case. Enrollments where either ID starts with q come first; each
+// group keeps creation order.
+func (r *EnrollmentRepository) Search(q string) []*models.Enrollment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	needle := strings.ToLower(q)
+	matches := make([]*models.Enrollment, 0)
+	for _, enrollment := range r.enrollments {
+		if enrollment.IsDeleted() {
+			continue
+		}
+		if strings.Contains(strings.ToLower(enrollment.StudentID), needle) ||
+			strings.Contains(strings.ToLower(enrollment.CourseID), needle) {
+			matches = append(matches, enrollment)
+		}
+	}
+	sortByCreatedAt(matches)
+	rankSearchResults(matches, q)
+
+	return matches
+}
+
+// rankSearchResults stably moves enrollments whose student or course ID
+// starts with q (ignoring case) ahead of the other matches
+func rankSearchResults(matches []*models.Enrollment, q string) {
+	needle := strings.ToLower(q)
+	isPrefix := func(e *models.Enrollment) bool {
+		return strings.HasPrefix(strings.ToLower(e.StudentID), needle) ||
+			strings.HasPrefix(strings.ToLower(e.CourseID), needle)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return isPrefix(matches[i]) && !isPrefix(matches[j])
+	})
+}
+
+// CountByStatus counts enrollments matching the filter, grouped by status.
+// Every valid status is present (possibly zero) unless the filter narrows
+// the status, in which case only that status is returned.
+func (r *EnrollmentRepository) CountByStatus(filter EnrollmentFilter) map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	if filter.Status != "" {
+		counts[filter.Status] = 0
+	} else {
+		for status := range models.ValidStatuses {
+			counts[status] = 0
+		}
+	}
+
+	for _, enrollment := range r.candidates(filter) {
+		if filter.Matches(enrollment) {
+			counts[enrollment.Status]++
+		}
+	}
+
+	return counts
+}
+
+// Paginate returns the requested page of an ordered slice along with the
+// total number of items
+func Paginate(enrollments []*models.Enrollment, limit, offset int) ([]*models.Enrollment, int) {
+	total := len(enrollments)
+	if offset >= total {
+		return []*models.Enrollment{}, total
+	}
+
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return enrollments[offset:end], total
+}
+
+// Update modifies an existing enrollment.
+// CreatedAt and EnrollmentDate are carried over from the stored record onto
+// the incoming enrollment when they are zero-valued; DeletedAt always is, as
+// only Delete and Restore change it. Returns a
+// *models.TransitionError if the status change is not allowed, and
+// ErrAlreadyExists if the student is already enrolled in the new course.
+//
+// A non-zero enrollment.Version is compared against the stored version under
+// the write lock and ErrVersionConflict is returned on mismatch; zero skips
+// the check. On success enrollment.Version is set to the new version.
+func (r *EnrollmentRepository) Update(ctx context.Context, id string, enrollment *models.Enrollment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.enrollments[id]; !exists {
+	existing, exists := r.enrollments[id]
+	if !exists || existing.IsDeleted() {
 		return ErrNotFound
 	}
 
+	if enrollment.Version != 0 && enrollment.Version != existing.Version {
+		return ErrVersionConflict
+	}
+
+	if !models.CanTransition(existing.Status, enrollment.Status) {
+		return &models.TransitionError{From: existing.Status, To: enrollment.Status}
+	}
+
+	oldKey := studentCourseKey(existing.StudentID, existing.CourseID)
+	newKey := studentCourseKey(enrollment.StudentID, enrollment.CourseID)
+	if owner, exists := r.byStudentCourse[newKey]; exists && owner != id {
+		return ErrAlreadyExists
+	}
+
+	if enrollment.CreatedAt.IsZero() {
+		enrollment.CreatedAt = existing.CreatedAt
+	}
+	if enrollment.EnrollmentDate.IsZero() {
+		enrollment.EnrollmentDate = existing.EnrollmentDate
+	}
+	enrollment.Version = existing.Version + 1
+	enrollment.TrackCompletion(existing, time.Now())
+	enrollment.DeletedAt = existing.DeletedAt
+
 	// Create a copy to avoid modifying the input
 	updated := *enrollment
 	updated.ID = id
-	r.enrollments[id] = &updated
+	moved := !updated.CreatedAt.Equal(existing.CreatedAt)
+	if moved {
+		r.unindexCreated(existing)
+	}
+	r.put(&updated)
+	if moved {
+		r.indexCreated(&updated)
+	}
+	delete(r.byStudentCourse, oldKey)
+	r.byStudentCourse[newKey] = id
 	return nil
 }
 
-// Delete removes an enrollment from the repository
-func (r *EnrollmentRepository) Delete(id string) error {
+// UpdateStatusForCourse moves every live enrollment in the course from one
+// status to another under a single write lock, bumping each version, and
+// returns the updated enrollments in creation order. Returns a
+// *models.TransitionError, without changing anything, if the move is not
+// allowed. Moving to the same status changes nothing.
+func (r *EnrollmentRepository) UpdateStatusForCourse(courseID, fromStatus, toStatus string) ([]*models.Enrollment, error) {
+	if !models.CanTransition(fromStatus, toStatus) {
+		return nil, &models.TransitionError{From: fromStatus, To: toStatus}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated := make([]*models.Enrollment, 0)
+	if fromStatus == toStatus {
+		return updated, nil
+	}
+
+	now := time.Now()
+	for _, existing := range r.byStatus[fromStatus] {
+		if existing.IsDeleted() || existing.CourseID != courseID {
+			continue
+		}
+
+		// Replace rather than mutate so previously returned pointers stay unchanged
+		enrollment := *existing
+		enrollment.Status = toStatus
+		enrollment.UpdatedAt = now
+		enrollment.Version++
+		enrollment.TrackCompletion(existing, now)
+		r.put(&enrollment)
+		updated = append(updated, &enrollment)
+	}
+	sortByCreatedAt(updated)
+
+	return updated, nil
+}
+
+// Delete soft-deletes an enrollment by stamping its DeletedAt time.
+// The record is kept so it can be restored later.
+func (r *EnrollmentRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.enrollments[id]; !exists {
+	existing, exists := r.enrollments[id]
+	if !exists || existing.IsDeleted() {
 		return ErrNotFound
 	}
 
-	delete(r.enrollments, id)
+	// Replace rather than mutate so previously returned pointers stay unchanged
+	deleted := *existing
+	now := time.Now()
+	deleted.DeletedAt = &now
+	r.put(&deleted)
+	delete(r.byStudentCourse, studentCourseKey(existing.StudentID, existing.CourseID))
 	return nil
 }
+
+// DeleteWhere soft-deletes every live enrollment matching filter under a
+// single write lock and returns them, as they were before the delete, in
+// creation order. filter.IncludeDeleted is ignored: deleted enrollments never
+// match.
+func (r *EnrollmentRepository) DeleteWhere(filter EnrollmentFilter) ([]*models.Enrollment, error) {
+	filter.IncludeDeleted = false
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := r.find(filter)
+	now := time.Now()
+	for _, existing := range matches {
+		// Replace rather than mutate so previously returned pointers stay unchanged
+		deleted := *existing
+		deleted.DeletedAt = &now
+		r.put(&deleted)
+		delete(r.byStudentCourse, studentCourseKey(existing.StudentID, existing.CourseID))
+	}
+
+	return matches, nil
+}
+
+// Restore undoes a soft-delete and returns the restored enrollment.
+// Returns ErrAlreadyExists if the student has since re-enrolled in the course.
+func (r *EnrollmentRepository) Restore(id string) (*models.Enrollment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.enrollments[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if !existing.IsDeleted() {
+		return nil, ErrNotDeleted
+	}
+	key := studentCourseKey(existing.StudentID, existing.CourseID)
+	if _, exists := r.byStudentCourse[key]; exists {
+		return nil, ErrAlreadyExists
+	}
+
+	restored := *existing
+	restored.DeletedAt = nil
+	r.put(&restored)
+	r.byStudentCourse[key] = id
+	return &restored, nil
+}
+
+// find collects matching enrollments; callers must hold the read lock
+func (r *EnrollmentRepository) find(filter EnrollmentFilter) []*models.Enrollment {
+	matches := make([]*models.Enrollment, 0)
+	for _, enrollment := range r.candidates(filter) {
+		if filter.Matches(enrollment) {
+			matches = append(matches, enrollment)
+		}
+	}
+	sortByCreatedAt(matches)
+
+	return matches
+}
+
+// candidates returns the enrollments filter can match: the status index
+// bucket when the filter names a status, every enrollment otherwise.
+// Callers must hold the read lock and still apply the filter.
+func (r *EnrollmentRepository) candidates(filter EnrollmentFilter) map[string]*models.Enrollment {
+	if filter.Status != "" {
+		return r.byStatus[filter.Status]
+	}
+	return r.enrollments
+}
+
+// put stores enrollment under its ID, replacing any previous record, and
+// moves it to its status in the status index; callers must hold the write
+// lock
+func (r *EnrollmentRepository) put(enrollment *models.Enrollment) {
+	if previous, exists := r.enrollments[enrollment.ID]; exists {
+		delete(r.byStatus[previous.Status], previous.ID)
+	}
+	r.enrollments[enrollment.ID] = enrollment
+
+	bucket, exists := r.byStatus[enrollment.Status]
+	if !exists {
+		bucket = make(map[string]*models.Enrollment)
+		r.byStatus[enrollment.Status] = bucket
+	}
+	bucket[enrollment.ID] = enrollment
+}
+
+// sortByCreatedAt orders enrollments by creation time, breaking ties by ID
+func sortByCreatedAt(enrollments []*models.Enrollment) {
+	sort.Slice(enrollments, func(i, j int) bool {
+		return createdBefore(enrollments[i], enrollments[j])
+	})
+}
+
+// createdBefore reports whether a sorts before b in creation order
+func createdBefore(a, b *models.Enrollment) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID < b.ID
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// indexCreated adds an enrollment to the creation index; callers must hold
+// the write lock. New enrollments are normally the newest, so the insert is
+// usually an append.
+func (r *EnrollmentRepository) indexCreated(enrollment *models.Enrollment) {
+	i := sort.Search(len(r.byCreated), func(i int) bool {
+		return createdBefore(enrollment, r.enrollments[r.byCreated[i]])
+	})
+	r.byCreated = slices.Insert(r.byCreated, i, enrollment.ID)
+}
+
+// unindexCreated removes a stored enrollment from the creation index;
+// callers must hold the write lock and pass the record as it is stored
+func (r *EnrollmentRepository) unindexCreated(enrollment *models.Enrollment) {
+	i := sort.Search(len(r.byCreated), func(i int) bool {
+		return !createdBefore(r.enrollments[r.byCreated[i]], enrollment)
+	})
+	if i < len(r.byCreated) && r.byCreated[i] == enrollment.ID {
+		r.byCreated = slices.Delete(r.byCreated, i, i+1)
+	}
+}
+
+// SelfTest writes a throwaway enrollment, reads it back through the ID and
+// status indexes, and removes it again. The write lock is held throughout,
+// so no other call, including a snapshot, ever sees the probe.
+func (r *EnrollmentRepository) SelfTest(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	probe := newSelfTestProbe()
+	key := studentCourseKey(probe.StudentID, probe.CourseID)
+	r.put(probe)
+	r.byStudentCourse[key] = probe.ID
+	r.indexCreated(probe)
+
+	err := checkSelfTestProbe(r.enrollments[probe.ID], probe)
+	if err == nil {
+		err = checkSelfTestProbe(r.byStatus[probe.Status][probe.ID], probe)
+	}
+
+	r.unindexCreated(probe)
+	delete(r.byStudentCourse, key)
+	delete(r.byStatus[probe.Status], probe.ID)
+	delete(r.enrollments, probe.ID)
+	return err
+}
+
+// DeleteAll permanently removes every enrollment, soft-deleted ones
+// included, and returns how many were removed
+func (r *EnrollmentRepository) DeleteAll() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := len(r.enrollments)
+	r.enrollments = make(map[string]*models.Enrollment)
+	r.byStudentCourse = make(map[string]string)
+	r.byCreated = nil
+	r.byStatus = make(map[string]map[string]*models.Enrollment)
+	return removed, nil
+}
+
+// Load replaces the repository contents with the given enrollments,
+// including soft-deleted ones, and rebuilds the student/course, creation
+// and status indexes
+func (r *EnrollmentRepository) Load(enrollments []*models.Enrollment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.enrollments = make(map[string]*models.Enrollment, len(enrollments))
+	r.byStudentCourse = make(map[string]string, len(enrollments))
+	r.byCreated = make([]string, 0, len(enrollments))
+	r.byStatus = make(map[string]map[string]*models.Enrollment)
+	for _, enrollment := range enrollments {
+		// Records saved before versioning existed start at version 1
+		if enrollment.Version == 0 {
+			enrollment.Version = 1
+		}
+		r.put(enrollment)
+		if !enrollment.IsDeleted() {
+			r.byStudentCourse[studentCourseKey(enrollment.StudentID, enrollment.CourseID)] = enrollment.ID
+		}
+	}
+	for id := range r.enrollments {
+		r.byCreated = append(r.byCreated, id)
+	}
+	sort.Slice(r.byCreated, func(i, j int) bool {
+		return createdBefore(r.enrollments[r.byCreated[i]], r.enrollments[r.byCreated[j]])
+	})
+}
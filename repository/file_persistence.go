@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"techwave/models"
+	"time"
+)
+
+// DefaultSnapshotInterval is how often FilePersistence writes a snapshot
+// when no interval is configured
+const DefaultSnapshotInterval = 30 * time.Second
+
+// FilePersistence wraps an in-memory EnrollmentRepository and periodically
+// snapshots it, soft-deleted records included, to a JSON file. It satisfies
+// Store through the embedded repository, so handlers use it unchanged.
+type FilePersistence struct {
+	*EnrollmentRepository
+
+	path     string
+	interval time.Duration
+
+	saveMu sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewFilePersistence creates a file-backed repository that snapshots to path
+// every interval. A non-positive interval uses DefaultSnapshotInterval.
+// Call Load before serving traffic, Start to begin snapshotting and Stop on
+// shutdown to write the final snapshot.
+func NewFilePersistence(path string, interval time.Duration) *FilePersistence {
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+	return &FilePersistence{
+		EnrollmentRepository: NewEnrollmentRepository(),
+		path:                 path,
+		interval:             interval,
+	}
+}
+
+// Load reads the snapshot file into the repository. A missing file is not an
+// error; the repository simply starts empty.
+func (p *FilePersistence) Load() error {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var enrollments []*models.Enrollment
+	if err := json.Unmarshal(data, &enrollments); err != nil {
+		return err
+	}
+
+	p.EnrollmentRepository.Load(enrollments)
+	return nil
+}
+
+// Save writes a snapshot atomically: the data goes to a temporary file in the
+// same directory, which is then renamed over the previous snapshot so readers
+// never see a partial file.
+func (p *FilePersistence) Save() error {
+	p.saveMu.Lock()
+	defer p.saveMu.Unlock()
+
+	data, err := json.Marshal(p.Find(EnrollmentFilter{IncludeDeleted: true}))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Removing after a successful rename is a harmless no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p.path)
+}
+
+// Start snapshots the repository in the background every interval until Stop
+func (p *FilePersistence) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.Save(); err != nil {
+					log.Printf("WARNING: Failed to write snapshot to %s: %v", p.path, err)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background snapshotting and writes a final snapshot
+func (p *FilePersistence) Stop() error {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+	return p.Save()
+}
+
+// Interval returns how often snapshots are written
+func (p *FilePersistence) Interval() time.Duration {
+	return p.interval
+}
+
+// FilePersistence must satisfy Store
+var _ Store = (*FilePersistence)(nil)
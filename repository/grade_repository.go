@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"techwave/models"
+)
+
+// ErrGradeNotFound is returned when a grade is not found
+var ErrGradeNotFound = errors.New("grade not found")
+
+// GradeRepository manages grade data storage
+type GradeRepository struct {
+	mu          sync.RWMutex
+	grades      map[string]*models.Grade
+	enrollments Store
+}
+
+// EnrollmentGrades pairs an enrollment with the grades recorded against it
+type EnrollmentGrades struct {
+	Enrollment *models.Enrollment
+	Grades     []*models.Grade
+}
+
+// NewGradeRepository creates a new grade repository backed by the given
+// enrollment repository for student-level queries
+func NewGradeRepository(enrollments Store) *GradeRepository {
+	return &GradeRepository{
+		grades:      make(map[string]*models.Grade),
+		enrollments: enrollments,
+	}
+}
+
+// Create adds a new grade to the repository
+func (r *GradeRepository) Create(grade *models.Grade) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.grades[grade.ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	r.grades[grade.ID] = grade
+	return nil
+}
+
+// Update replaces a stored grade with grade. The stored grade must belong to
+// grade.EnrollmentID; otherwise, or when it does not exist, ErrGradeNotFound
+// is returned, so a grade cannot be reached through another enrollment. The
+// grade is replaced rather than modified, so copies already handed out keep
+// their old values.
+func (r *GradeRepository) Update(grade *models.Grade) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.grades[grade.ID]
+	if !exists || existing.EnrollmentID != grade.EnrollmentID {
+		return ErrGradeNotFound
+	}
+
+	r.grades[grade.ID] = grade
+	return nil
+}
+
+// Delete removes the grade id recorded against enrollmentID, returning
+// ErrGradeNotFound when there is no such grade for that enrollment
+func (r *GradeRepository) Delete(enrollmentID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.grades[id]
+	if !exists || existing.EnrollmentID != enrollmentID {
+		return ErrGradeNotFound
+	}
+
+	delete(r.grades, id)
+	return nil
+}
+
+// DeleteAll removes every grade and returns how many were removed
+func (r *GradeRepository) DeleteAll() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := len(r.grades)
+	r.grades = make(map[string]*models.Grade)
+	return removed
+}
+
+// GetByID retrieves a grade by ID
+func (r *GradeRepository) GetByID(id string) (*models.Grade, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grade, exists := r.grades[id]
+	if !exists {
+		return nil, ErrGradeNotFound
+	}
+
+	return grade, nil
+}
+
+// IDs returns the ID of every grade in sorted order, so callers can walk all
+// grades in batches without holding the lock throughout
+func (r *GradeRepository) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.grades))
+	for id := range r.grades {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Relabel sets the letter grade of each listed grade to letter(score) under
+// a single write lock and returns how many letters changed. Changed grades
+// are replaced rather than modified, so grades already handed out keep their
+// old letter. IDs that no longer exist are skipped.
+func (r *GradeRepository) Relabel(ids []string, letter func(score float64) string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := 0
+	for _, id := range ids {
+		grade, exists := r.grades[id]
+		if !exists {
+			continue
+		}
+		if l := letter(grade.Score); l != grade.LetterGrade {
+			relabeled := *grade
+			relabeled.LetterGrade = l
+			r.grades[id] = &relabeled
+			changed++
+		}
+	}
+	return changed
+}
+
+// GetByEnrollment retrieves all grades for an enrollment, oldest first
+func (r *GradeRepository) GetByEnrollment(enrollmentID string) []*models.Grade {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grades := make([]*models.Grade, 0)
+	for _, grade := range r.grades {
+		if grade.EnrollmentID == enrollmentID {
+			grades = append(grades, grade)
+		}
+	}
+
+	sort.Slice(grades, func(i, j int) bool {
+		if grades[i].GradedAt.Equal(grades[j].GradedAt) {
+			return grades[i].ID < grades[j].ID
+		}
+		return grades[i].GradedAt.Before(grades[j].GradedAt)
+	})
+
+	return grades
+}
+
+// Summary computes score statistics across an enrollment's grades
+func (r *GradeRepository) Summary(enrollmentID string, passingScore float64) models.GradeSummary {
+	return SummarizeGrades(r.GetByEnrollment(enrollmentID), passingScore)
+}
+
+// SummarizeGrades computes the count, min, max, mean and median score of
+// grades and how they compare to passingScore. Mean and median are rounded to
+// two decimal places; an empty slice yields the zero summary.
+func SummarizeGrades(grades []*models.Grade, passingScore float64) models.GradeSummary {
+	if len(grades) == 0 {
+		return models.GradeSummary{}
+	}
+
+	scores := make([]float64, len(grades))
+	var sum float64
+	passed := 0
+	for i, grade := range grades {
+		scores[i] = grade.Score
+		sum += grade.Score
+		if grade.Score >= passingScore {
+			passed++
+		}
+	}
+	sort.Float64s(scores)
+
+	middle := len(scores) / 2
+	median := scores[middle]
+	if len(scores)%2 == 0 {
+		median = (scores[middle-1] + scores[middle]) / 2
+	}
+
+	mean := sum / float64(len(scores))
+	return models.GradeSummary{
+		Count:       len(scores),
+		Min:         scores[0],
+		Max:         scores[len(scores)-1],
+		Mean:        roundScore(mean),
+		Median:      roundScore(median),
+		PassedCount: passed,
+		Passed:      mean >= passingScore,
+	}
+}
+
+// roundScore rounds a derived score to two decimal places
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// GetGradesByCourse joins a course's live enrollments to their grades.
+// Returns ErrNotFound if the course has no enrollments.
+func (r *GradeRepository) GetGradesByCourse(courseID string) ([]EnrollmentGrades, error) {
+	return r.joinGrades(r.enrollments.Find(EnrollmentFilter{CourseID: courseID}))
+}
+
+// GetGradesByStudent joins a student's enrollments to their grades.
+// Returns ErrNotFound if the student has no enrollments.
+func (r *GradeRepository) GetGradesByStudent(studentID string) ([]EnrollmentGrades, error) {
+	return r.joinGrades(r.enrollments.Find(EnrollmentFilter{StudentID: studentID}))
+}
+
+// joinGrades pairs each enrollment with its grades, returning ErrNotFound
+// when there are no enrollments
+func (r *GradeRepository) joinGrades(enrollments []*models.Enrollment) ([]EnrollmentGrades, error) {
+	if len(enrollments) == 0 {
+		return nil, ErrNotFound
+	}
+
+	results := make([]EnrollmentGrades, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		results = append(results, EnrollmentGrades{
+			Enrollment: enrollment,
+			Grades:     r.GetByEnrollment(enrollment.ID),
+		})
+	}
+
+	return results, nil
+}
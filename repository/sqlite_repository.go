@@ -0,0 +1,670 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"techwave/models"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqliteMigrations are applied in order on startup; each entry's index + 1 is
+// its schema version. Append new migrations, never edit existing ones.
+var sqliteMigrations = []string{
+	`CREATE TABLE enrollments (
+		id              TEXT PRIMARY KEY,
+		student_id      TEXT NOT NULL,
+		course_id       TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		enrollment_date INTEGER NOT NULL,
+		created_at      INTEGER NOT NULL,
+		updated_at      INTEGER NOT NULL,
+		deleted_at      INTEGER
+	);
+	CREATE UNIQUE INDEX idx_enrollments_live_student_course
+		ON enrollments (student_id, course_id) WHERE deleted_at IS NULL;
+	CREATE INDEX idx_enrollments_created_at ON enrollments (created_at, id);`,
+	`ALTER TABLE enrollments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE enrollments ADD COLUMN completed_at INTEGER;`,
+	`ALTER TABLE enrollments ADD COLUMN course_name TEXT NOT NULL DEFAULT '';`,
+}
+
+// enrollmentColumns is the column list matching scanEnrollment
+const enrollmentColumns = "id, student_id, course_id, status, enrollment_date, created_at, updated_at, deleted_at, version, completed_at, course_name"
+
+// SQLiteRepository is a Store persisted in a SQLite database.
+// Timestamps are stored as Unix nanoseconds and read back in UTC.
+type SQLiteRepository struct {
+	db *sql.DB
+
+	insertStmt  *sql.Stmt
+	getByIDStmt *sql.Stmt
+	deleteStmt  *sql.Stmt
+}
+
+// NewSQLiteRepository opens (creating if needed) the SQLite database at path,
+// applies pending migrations and prepares the common statements
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked"
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	r := &SQLiteRepository{db: db}
+	statements := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&r.insertStmt, "INSERT INTO enrollments (" + enrollmentColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"},
+		{&r.getByIDStmt, "SELECT " + enrollmentColumns + " FROM enrollments WHERE id = ? AND deleted_at IS NULL"},
+		{&r.deleteStmt, "UPDATE enrollments SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"},
+	}
+	for _, s := range statements {
+		if *s.stmt, err = db.Prepare(s.query); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// migrateSQLite applies every migration newer than the recorded schema version
+func migrateSQLite(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close releases the prepared statements and the database handle
+func (r *SQLiteRepository) Close() error {
+	for _, stmt := range []*sql.Stmt{r.insertStmt, r.getByIDStmt, r.deleteStmt} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return r.db.Close()
+}
+
+// Ping checks that the database is reachable
+func (r *SQLiteRepository) Ping() error {
+	return r.db.Ping()
+}
+
+// SelfTest writes a throwaway enrollment, reads it back and deletes it inside
+// a transaction that is always rolled back, so the probe is never committed
+// or visible to other queries
+func (r *SQLiteRepository) SelfTest(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	probe := newSelfTestProbe()
+	if _, err := tx.StmtContext(ctx, r.insertStmt).ExecContext(ctx, enrollmentArgs(probe)...); err != nil {
+		return err
+	}
+	stored, err := scanEnrollment(tx.StmtContext(ctx, r.getByIDStmt).QueryRowContext(ctx, probe.ID))
+	if err != nil {
+		return err
+	}
+	if err := checkSelfTestProbe(stored, probe); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM enrollments WHERE id = ?`, probe.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected != 1 {
+		return fmt.Errorf("self-test deleted %d rows, want 1", affected)
+	}
+	return nil
+}
+
+// Create adds a new enrollment at version 1.
+// Returns ErrIDTaken if the ID is taken, or ErrAlreadyExists if the student
+// already has a live enrollment in the course.
+func (r *SQLiteRepository) Create(ctx context.Context, enrollment *models.Enrollment) error {
+	enrollment.Version = 1
+	enrollment.TrackCompletion(nil, time.Now())
+	_, err := r.insertStmt.ExecContext(ctx, enrollmentArgs(enrollment)...)
+	return mapSQLiteError(err)
+}
+
+// CreateBatch adds several enrollments in one transaction.
+// The returned slice has one entry per input; nil means the insert succeeded.
+// A failed item does not prevent the remaining items from being inserted.
+func (r *SQLiteRepository) CreateBatch(enrollments []*models.Enrollment) []error {
+	errs := make([]error, len(enrollments))
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	stmt := tx.Stmt(r.insertStmt)
+	for i, enrollment := range enrollments {
+		enrollment.Version = 1
+		enrollment.TrackCompletion(nil, time.Now())
+		if _, err := stmt.Exec(enrollmentArgs(enrollment)...); err != nil {
+			errs[i] = mapSQLiteError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+
+	return errs
+}
+
+// GetByID retrieves a live enrollment by ID, recording a child span of any
+// trace carried by ctx
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetByID", trace.WithAttributes(attribute.String("enrollment.id", id)))
+	defer span.End()
+
+	enrollment, err := scanEnrollment(r.getByIDStmt.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return enrollment, err
+}
+
+// GetAll retrieves all enrollments that have not been soft-deleted, ordered
+// by creation time and then ID. A cancelled ctx yields an empty result.
+func (r *SQLiteRepository) GetAll(ctx context.Context) []*models.Enrollment {
+	return r.find(ctx, EnrollmentFilter{})
+}
+
+// Find retrieves all enrollments matching the filter, ordered by creation time
+func (r *SQLiteRepository) Find(filter EnrollmentFilter) []*models.Enrollment {
+	return r.find(context.Background(), filter)
+}
+
+// find is Find under ctx
+func (r *SQLiteRepository) find(ctx context.Context, filter EnrollmentFilter) []*models.Enrollment {
+	where, args := sqliteWhere(filter)
+	rows, err := r.db.QueryContext(ctx, "SELECT "+enrollmentColumns+" FROM enrollments"+where+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return []*models.Enrollment{}
+	}
+	defer rows.Close()
+
+	enrollments := make([]*models.Enrollment, 0)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			continue
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+
+	return enrollments
+}
+
+// Recent retrieves up to limit live enrollments, newest first by creation
+// time and then ID, reading idx_enrollments_created_at backwards
+func (r *SQLiteRepository) Recent(limit int) []*models.Enrollment {
+	rows, err := r.db.Query("SELECT "+enrollmentColumns+` FROM enrollments
+		WHERE deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return []*models.Enrollment{}
+	}
+	defer rows.Close()
+
+	recent := make([]*models.Enrollment, 0, limit)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			continue
+		}
+		recent = append(recent, enrollment)
+	}
+
+	return recent
+}
+
+// Search finds live enrollments whose student or course ID contains q,
+// ignoring case. Enrollments where either ID starts with q come first; each
+// group keeps creation order.
+func (r *SQLiteRepository) Search(q string) []*models.Enrollment {
+	rows, err := r.db.Query("SELECT "+enrollmentColumns+` FROM enrollments
+		WHERE deleted_at IS NULL AND (instr(lower(student_id), lower(?)) > 0 OR instr(lower(course_id), lower(?)) > 0)
+		ORDER BY created_at, id`, q, q)
+	if err != nil {
+		return []*models.Enrollment{}
+	}
+	defer rows.Close()
+
+	matches := make([]*models.Enrollment, 0)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, enrollment)
+	}
+	rankSearchResults(matches, q)
+
+	return matches
+}
+
+// CountByStatus counts enrollments matching the filter, grouped by status.
+// Every valid status is present (possibly zero) unless the filter narrows
+// the status, in which case only that status is returned.
+func (r *SQLiteRepository) CountByStatus(filter EnrollmentFilter) map[string]int {
+	counts := make(map[string]int)
+	if filter.Status != "" {
+		counts[filter.Status] = 0
+	} else {
+		for status := range models.ValidStatuses {
+			counts[status] = 0
+		}
+	}
+
+	where, args := sqliteWhere(filter)
+	rows, err := r.db.Query("SELECT status, COUNT(*) FROM enrollments"+where+" GROUP BY status", args...)
+	if err != nil {
+		return counts
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err == nil {
+			counts[status] = count
+		}
+	}
+
+	return counts
+}
+
+// Update modifies an existing enrollment.
+// CreatedAt and EnrollmentDate are carried over from the stored record onto
+// the incoming enrollment when they are zero-valued; DeletedAt always is, as
+// only Delete and Restore change it. Returns a
+// *models.TransitionError if the status change is not allowed, and
+// ErrAlreadyExists if the student is already enrolled in the new course.
+//
+// A non-zero enrollment.Version must match the stored version or
+// ErrVersionConflict is returned; zero skips the check. On success
+// enrollment.Version is set to the new version.
+func (r *SQLiteRepository) Update(ctx context.Context, id string, enrollment *models.Enrollment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := scanEnrollment(tx.StmtContext(ctx, r.getByIDStmt).QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if enrollment.Version != 0 && enrollment.Version != existing.Version {
+		return ErrVersionConflict
+	}
+
+	if !models.CanTransition(existing.Status, enrollment.Status) {
+		return &models.TransitionError{From: existing.Status, To: enrollment.Status}
+	}
+
+	if enrollment.CreatedAt.IsZero() {
+		enrollment.CreatedAt = existing.CreatedAt
+	}
+	if enrollment.EnrollmentDate.IsZero() {
+		enrollment.EnrollmentDate = existing.EnrollmentDate
+	}
+	enrollment.TrackCompletion(existing, time.Now())
+	enrollment.DeletedAt = existing.DeletedAt
+
+	// The version guard keeps the write a compare-and-swap even if another
+	// connection is ever allowed to write between the read and the update
+	result, err := tx.ExecContext(ctx, `UPDATE enrollments
+		SET student_id = ?, course_id = ?, status = ?, enrollment_date = ?, created_at = ?, updated_at = ?,
+			completed_at = ?, course_name = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		enrollment.StudentID, enrollment.CourseID, enrollment.Status,
+		enrollment.EnrollmentDate.UnixNano(), enrollment.CreatedAt.UnixNano(), enrollment.UpdatedAt.UnixNano(),
+		nullableUnixNano(enrollment.CompletedAt), enrollment.CourseName, id, existing.Version)
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrVersionConflict
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	enrollment.Version = existing.Version + 1
+	return nil
+}
+
+// UpdateStatusForCourse moves every live enrollment in the course from one
+// status to another in a single transaction, bumping each version, and
+// returns the updated enrollments in creation order. Returns a
+// *models.TransitionError, without changing anything, if the move is not
+// allowed. Moving to the same status changes nothing.
+func (r *SQLiteRepository) UpdateStatusForCourse(courseID, fromStatus, toStatus string) ([]*models.Enrollment, error) {
+	if !models.CanTransition(fromStatus, toStatus) {
+		return nil, &models.TransitionError{From: fromStatus, To: toStatus}
+	}
+
+	updated := make([]*models.Enrollment, 0)
+	if fromStatus == toStatus {
+		return updated, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const match = " WHERE course_id = ? AND status = ? AND deleted_at IS NULL"
+	rows, err := tx.Query("SELECT "+enrollmentColumns+" FROM enrollments"+match+" ORDER BY created_at, id", courseID, fromStatus)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		updated = append(updated, enrollment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Every matching row moves between the same two statuses, so they share
+	// one completion time (set only when moving to completed)
+	now := time.Unix(0, time.Now().UnixNano()).UTC()
+	var completedAt *time.Time
+	if toStatus == "completed" {
+		completedAt = &now
+	}
+	if _, err := tx.Exec("UPDATE enrollments SET status = ?, updated_at = ?, completed_at = ?, version = version + 1"+match,
+		toStatus, now.UnixNano(), nullableUnixNano(completedAt), courseID, fromStatus); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, enrollment := range updated {
+		enrollment.Status = toStatus
+		enrollment.UpdatedAt = now
+		enrollment.CompletedAt = completedAt
+		enrollment.Version++
+	}
+	return updated, nil
+}
+
+// Delete soft-deletes an enrollment by stamping its DeletedAt time
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.deleteStmt.ExecContext(ctx, time.Now().UnixNano(), id)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWhere soft-deletes every live enrollment matching filter in a single
+// transaction and returns them, as they were before the delete, in creation
+// order. filter.IncludeDeleted is ignored: deleted enrollments never match.
+func (r *SQLiteRepository) DeleteWhere(filter EnrollmentFilter) ([]*models.Enrollment, error) {
+	filter.IncludeDeleted = false
+	where, args := sqliteWhere(filter)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT "+enrollmentColumns+" FROM enrollments"+where+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]*models.Enrollment, 0)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deleted = append(deleted, enrollment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE enrollments SET deleted_at = ?"+where, append([]interface{}{time.Now().UnixNano()}, args...)...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// DeleteAll permanently removes every enrollment, soft-deleted ones
+// included, and returns how many were removed
+func (r *SQLiteRepository) DeleteAll() (int, error) {
+	result, err := r.db.Exec(`DELETE FROM enrollments`)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// Restore undoes a soft-delete and returns the restored enrollment.
+// Returns ErrAlreadyExists if the student has since re-enrolled in the course.
+func (r *SQLiteRepository) Restore(id string) (*models.Enrollment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := scanEnrollment(tx.QueryRow("SELECT "+enrollmentColumns+" FROM enrollments WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !existing.IsDeleted() {
+		return nil, ErrNotDeleted
+	}
+
+	if _, err := tx.Exec("UPDATE enrollments SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	existing.DeletedAt = nil
+	return existing, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEnrollment reads one row selected with enrollmentColumns
+func scanEnrollment(row rowScanner) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	var enrollmentDate, createdAt, updatedAt int64
+	var deletedAt, completedAt sql.NullInt64
+
+	err := row.Scan(&enrollment.ID, &enrollment.StudentID, &enrollment.CourseID, &enrollment.Status,
+		&enrollmentDate, &createdAt, &updatedAt, &deletedAt, &enrollment.Version, &completedAt, &enrollment.CourseName)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment.EnrollmentDate = time.Unix(0, enrollmentDate).UTC()
+	enrollment.CreatedAt = time.Unix(0, createdAt).UTC()
+	enrollment.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	if deletedAt.Valid {
+		deleted := time.Unix(0, deletedAt.Int64).UTC()
+		enrollment.DeletedAt = &deleted
+	}
+	if completedAt.Valid {
+		completed := time.Unix(0, completedAt.Int64).UTC()
+		enrollment.CompletedAt = &completed
+	}
+
+	return &enrollment, nil
+}
+
+// enrollmentArgs returns insert arguments in enrollmentColumns order
+func enrollmentArgs(enrollment *models.Enrollment) []interface{} {
+	return []interface{}{
+		enrollment.ID, enrollment.StudentID, enrollment.CourseID, enrollment.Status,
+		enrollment.EnrollmentDate.UnixNano(), enrollment.CreatedAt.UnixNano(), enrollment.UpdatedAt.UnixNano(),
+		nullableUnixNano(enrollment.DeletedAt), enrollment.Version, nullableUnixNano(enrollment.CompletedAt),
+		enrollment.CourseName,
+	}
+}
+
+// nullableUnixNano converts an optional time to Unix nanoseconds or SQL NULL
+func nullableUnixNano(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+// sqliteWhere translates a filter into a WHERE clause and its arguments
+func sqliteWhere(filter EnrollmentFilter) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	if !filter.IncludeDeleted {
+		clauses = append(clauses, "deleted_at IS NULL")
+	}
+	if filter.ID != "" {
+		clauses = append(clauses, "id = ?")
+		args = append(args, filter.ID)
+	}
+	if filter.StudentID != "" {
+		clauses = append(clauses, "student_id = ?")
+		args = append(args, filter.StudentID)
+	}
+	if filter.CourseID != "" {
+		clauses = append(clauses, "course_id = ?")
+		args = append(args, filter.CourseID)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.FromDate.IsZero() {
+		clauses = append(clauses, "enrollment_date >= ?")
+		args = append(args, filter.FromDate.UnixNano())
+	}
+	if !filter.ToDate.IsZero() {
+		clauses = append(clauses, "enrollment_date <= ?")
+		args = append(args, filter.ToDate.UnixNano())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		clauses = append(clauses, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UnixNano())
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// mapSQLiteError converts constraint violations into repository errors
+func mapSQLiteError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintPrimaryKey:
+			return ErrIDTaken
+		case sqlite3.ErrConstraintUnique:
+			return ErrAlreadyExists
+		}
+	}
+	return err
+}
+
+// SQLiteRepository must satisfy Store
+var _ Store = (*SQLiteRepository)(nil)
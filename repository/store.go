@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"techwave/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is the enrollment storage the handlers depend on.
+// EnrollmentRepository is the in-memory implementation; other backends must
+// return the same sentinel errors (ErrNotFound, ErrAlreadyExists, ErrIDTaken,
+// ErrNotDeleted) and *models.TransitionError so handlers map them to the
+// same HTTP responses.
+//
+// The single-enrollment methods and GetAll take the request's context and
+// give up with its error once it is cancelled or its deadline passes.
+// GetByID and GetAll return copies of the stored enrollments; changing them
+// never changes what is stored.
+type Store interface {
+	Create(ctx context.Context, enrollment *models.Enrollment) error
+	GetByID(ctx context.Context, id string) (*models.Enrollment, error)
+	GetAll(ctx context.Context) []*models.Enrollment
+	Update(ctx context.Context, id string, enrollment *models.Enrollment) error
+	Delete(ctx context.Context, id string) error
+
+	// CreateBatch inserts each enrollment independently, returning one error per input
+	CreateBatch(enrollments []*models.Enrollment) []error
+	// Find returns matching enrollments ordered by creation time
+	Find(filter EnrollmentFilter) []*models.Enrollment
+	// CountByStatus counts matching enrollments per status
+	CountByStatus(filter EnrollmentFilter) map[string]int
+	// Restore undoes a soft-delete
+	Restore(id string) (*models.Enrollment, error)
+	// DeleteWhere soft-deletes every live enrollment matching a filter
+	// atomically and returns them as they were before the delete
+	DeleteWhere(filter EnrollmentFilter) ([]*models.Enrollment, error)
+	// UpdateStatusForCourse moves a course's live enrollments between statuses atomically
+	UpdateStatusForCourse(courseID, fromStatus, toStatus string) ([]*models.Enrollment, error)
+	// Recent returns up to limit live enrollments, newest first
+	Recent(limit int) []*models.Enrollment
+	// Search finds live enrollments by case-insensitive substring of student
+	// or course ID, prefix matches first
+	Search(q string) []*models.Enrollment
+	// DeleteAll permanently removes every enrollment, soft-deleted ones
+	// included, and returns how many were removed
+	DeleteAll() (int, error)
+}
+
+// EnrollmentRepository must satisfy Store
+var _ Store = (*EnrollmentRepository)(nil)
+
+// newSelfTestProbe builds the throwaway enrollment a SelfTest writes. Its IDs
+// are random so it never collides with a real enrollment.
+func newSelfTestProbe() *models.Enrollment {
+	id := uuid.NewString()
+	now := time.Now().UTC()
+	return &models.Enrollment{
+		ID:             id,
+		StudentID:      "health-check-" + id,
+		CourseID:       "health-check-" + id,
+		Status:         "pending",
+		EnrollmentDate: now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	}
+}
+
+// checkSelfTestProbe reports whether got, as read back, matches the probe written
+func checkSelfTestProbe(got, want *models.Enrollment) error {
+	if got == nil || got.ID != want.ID || got.StudentID != want.StudentID ||
+		got.CourseID != want.CourseID || got.Status != want.Status {
+		return fmt.Errorf("self-test read back %+v, want %+v", got, want)
+	}
+	return nil
+}
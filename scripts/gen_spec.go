@@ -0,0 +1,272 @@
+//go:build genspec
+// +build genspec
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"techwave/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// component ties a schema in the spec to the Go type that produces or
+// consumes it
+type component struct {
+	name string
+	typ  reflect.Type
+	// request components omit the fields the response schema marks readOnly
+	readOnlyFrom string
+}
+
+var components = []component{
+	{name: "Enrollment", typ: reflect.TypeOf(models.Enrollment{})},
+	{name: "EnrollmentRequest", typ: reflect.TypeOf(models.Enrollment{}), readOnlyFrom: "Enrollment"},
+	{name: "ErrorResponse", typ: reflect.TypeOf(models.ErrorResponse{})},
+	{name: "FieldError", typ: reflect.TypeOf(models.FieldError{})},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// field is one JSON property of a Go struct
+type field struct {
+	name      string
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+// genSpec checks the spec's schema components against the Go models they
+// describe, or with -emit prints the components generated from the models
+func main() {
+	emit := flag.Bool("emit", false, "print the schema components generated from the Go models instead of checking the spec")
+	specPath := flag.String("spec", "api/openapi.yaml", "OpenAPI specification to check")
+	flag.Parse()
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(*specPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to load OpenAPI spec: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		log.Fatalf("❌ Invalid OpenAPI specification: %v", err)
+	}
+
+	if *emit {
+		if err := emitComponents(doc); err != nil {
+			log.Fatalf("❌ Failed to generate schemas: %v", err)
+		}
+		return
+	}
+
+	violations := 0
+	for _, c := range components {
+		problems := checkComponent(doc, c)
+		for _, problem := range problems {
+			log.Printf("❌ %s: %s", c.name, problem)
+		}
+		if len(problems) == 0 {
+			log.Printf("✓ Schema matches %s: %s", c.typ, c.name)
+		}
+		violations += len(problems)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	if violations > 0 {
+		log.Printf("❌ SPEC DRIFT: %d mismatch(es) between the models and %s", violations, *specPath)
+		os.Exit(1)
+	}
+	log.Println("✅ SPEC IN SYNC: All schema components match the models")
+}
+
+// checkComponent lists every difference between a schema component and the
+// JSON fields of its Go type
+func checkComponent(doc *openapi3.T, c component) []string {
+	ref, ok := doc.Components.Schemas[c.name]
+	if !ok || ref.Value == nil {
+		return []string{"schema is missing"}
+	}
+	schema := ref.Value
+
+	fields, err := componentFields(doc, c)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.name] = true
+
+		prop, ok := schema.Properties[f.name]
+		if !ok || prop.Value == nil {
+			problems = append(problems, fmt.Sprintf("field %q has no schema property", f.name))
+			continue
+		}
+		if problem := checkType(f, prop.Value); problem != "" {
+			problems = append(problems, fmt.Sprintf("property %q %s", f.name, problem))
+		}
+	}
+
+	for _, name := range sortedKeys(schema.Properties) {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("property %q has no field on %s", name, c.typ))
+		}
+	}
+	for _, name := range schema.Required {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("required property %q has no field on %s", name, c.typ))
+		}
+	}
+	return problems
+}
+
+// componentFields returns the JSON fields of c's type, less any the
+// component's response schema marks readOnly
+func componentFields(doc *openapi3.T, c component) ([]field, error) {
+	fields := jsonFields(c.typ)
+	if c.readOnlyFrom == "" {
+		return fields, nil
+	}
+
+	ref, ok := doc.Components.Schemas[c.readOnlyFrom]
+	if !ok || ref.Value == nil {
+		return nil, fmt.Errorf("schema %s, which marks its read-only fields, is missing", c.readOnlyFrom)
+	}
+
+	writable := fields[:0]
+	for _, f := range fields {
+		if prop, ok := ref.Value.Properties[f.name]; ok && prop.Value != nil && prop.Value.ReadOnly {
+			continue
+		}
+		writable = append(writable, f)
+	}
+	return writable, nil
+}
+
+// jsonFields lists the exported fields of a struct type under their JSON
+// names, skipping fields tagged "-"
+func jsonFields(typ reflect.Type) []field {
+	var fields []field
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			typ:       sf.Type,
+			omitEmpty: strings.Contains(options, "omitempty"),
+		})
+	}
+	return fields
+}
+
+// checkType reports how a property's type disagrees with the Go field, or ""
+// when they agree
+func checkType(f field, prop *openapi3.Schema) string {
+	want := schemaFor(f.typ)
+	if !prop.Type.Is(want.Type.Slice()[0]) {
+		return fmt.Sprintf("has type %v, want %v for Go type %s", prop.Type.Slice(), want.Type.Slice(), f.typ)
+	}
+	if want.Format != "" && prop.Format != want.Format {
+		return fmt.Sprintf("has format %q, want %q for Go type %s", prop.Format, want.Format, f.typ)
+	}
+	if want.Nullable && !prop.Nullable {
+		return fmt.Sprintf("must be nullable for Go type %s", f.typ)
+	}
+	return ""
+}
+
+// schemaFor maps a Go type onto the OpenAPI type that encoding/json produces
+// for it
+func schemaFor(typ reflect.Type) *openapi3.Schema {
+	if typ.Kind() == reflect.Pointer {
+		schema := schemaFor(typ.Elem())
+		schema.Nullable = true
+		return schema
+	}
+	if typ == timeType {
+		return openapi3.NewDateTimeSchema()
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openapi3.NewIntegerSchema()
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema()
+	case reflect.Slice, reflect.Array:
+		return openapi3.NewArraySchema().WithItems(schemaFor(typ.Elem()))
+	default:
+		return openapi3.NewObjectSchema()
+	}
+}
+
+// emitComponents prints the components generated from the models as YAML.
+// Fields without omitempty are always sent, so they are listed as required.
+func emitComponents(doc *openapi3.T) error {
+	schemas := make(openapi3.Schemas, len(components))
+	for _, c := range components {
+		fields, err := componentFields(doc, c)
+		if err != nil {
+			return err
+		}
+
+		schema := openapi3.NewObjectSchema()
+		for _, f := range fields {
+			schema.WithPropertyRef(f.name, openapi3.NewSchemaRef("", schemaFor(f.typ)))
+			if !f.omitEmpty && f.typ.Kind() != reflect.Pointer {
+				schema.Required = append(schema.Required, f.name)
+			}
+		}
+		schemas[c.name] = openapi3.NewSchemaRef("", schema)
+	}
+
+	// Round-trip through JSON so the output uses the spec's field names
+	raw, err := json.Marshal(map[string]interface{}{"components": map[string]interface{}{"schemas": schemas}})
+	if err != nil {
+		return err
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	return encoder.Encode(generic)
+}
+
+// sortedKeys returns the property names of a schema in a stable order
+func sortedKeys(properties openapi3.Schemas) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
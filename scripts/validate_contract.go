@@ -4,17 +4,27 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
 )
 
+// liveBaseURLEnv names the environment variable holding the base URL of a
+// running server. When set, live responses are validated against the spec.
+const liveBaseURLEnv = "CONTRACT_BASE_URL"
+
 // validateContract validates the API implementation against the OpenAPI spec
 func main() {
 	ctx := context.Background()
@@ -99,6 +109,13 @@ func main() {
 		log.Println("✓ X-Cache-Status header documented")
 	}
 
+	// Validate live responses when a running server is configured
+	if baseURL := os.Getenv(liveBaseURLEnv); baseURL != "" {
+		violations += validateLiveResponses(ctx, doc, strings.TrimRight(baseURL, "/"))
+	} else {
+		log.Printf("- Live response validation skipped (set %s to enable)", liveBaseURLEnv)
+	}
+
 	// Summary
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	if violations > 0 {
@@ -122,3 +139,119 @@ func validateSchemas(doc *openapi3.T) error {
 
 	return nil
 }
+
+// validateLiveResponses performs a create/read/update/delete round trip
+// against the server at baseURL and validates every response status, header
+// and body against the spec. It returns the number of failed requests.
+func validateLiveResponses(ctx context.Context, doc *openapi3.T, baseURL string) int {
+	// Match paths on any host: the spec's servers need not include baseURL
+	liveDoc := *doc
+	liveDoc.Servers = nil
+	router, err := gorillamux.NewRouter(&liveDoc)
+	if err != nil {
+		log.Printf("❌ Failed to create router for live validation: %v", err)
+		return 1
+	}
+
+	v := &liveValidator{
+		ctx:     ctx,
+		router:  router,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+	}
+	log.Printf("Validating live responses from %s", baseURL)
+
+	for _, path := range []string{"/", "/health", "/health/live", "/health/ready", "/api/enrollments", "/api/enrollments/count", "/api/cache/stats"} {
+		v.check(http.MethodGet, path, nil)
+	}
+	v.check(http.MethodPost, "/api/enrollments", []byte(`{}`))
+
+	studentID := fmt.Sprintf("contract-%d", time.Now().UnixNano())
+	body := v.check(http.MethodPost, "/api/enrollments", []byte(fmt.Sprintf(`{"student_id":%q,"course_id":"contract","status":"pending"}`, studentID)))
+	var created struct {
+		ID      string `json:"id"`
+		Version int    `json:"version"`
+	}
+	if body == nil || json.Unmarshal(body, &created) != nil || created.ID == "" {
+		log.Printf("❌ Live validation stopped: could not create an enrollment to exercise /api/enrollments/{id}")
+		return v.failures + 1
+	}
+
+	path := "/api/enrollments/" + created.ID
+	v.check(http.MethodGet, path, nil)
+	v.check(http.MethodGet, path+"/history", nil)
+	v.check(http.MethodPut, path, []byte(fmt.Sprintf(`{"student_id":%q,"course_id":"contract","status":"active","version":%d}`, studentID, created.Version)))
+	v.check(http.MethodDelete, path, nil)
+	v.check(http.MethodGet, path, nil)
+
+	return v.failures
+}
+
+// liveValidator sends requests to a running server and validates each
+// response against the operation the spec defines for it
+type liveValidator struct {
+	ctx      context.Context
+	router   routers.Router
+	client   *http.Client
+	baseURL  string
+	failures int
+}
+
+// check performs one request, logs whether its response matches the spec
+// and returns the response body, or nil when the request itself failed
+func (v *liveValidator) check(method, path string, body []byte) []byte {
+	label := fmt.Sprintf("%s %s", method, path)
+
+	req, err := http.NewRequestWithContext(v.ctx, method, v.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return v.fail(label, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return v.fail(label, fmt.Errorf("route not found in spec: %w", err))
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return v.fail(label, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return v.fail(label, err)
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   io.NopCloser(bytes.NewReader(respBody)),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	}
+	if err := openapi3filter.ValidateResponse(v.ctx, input); err != nil {
+		v.fail(fmt.Sprintf("%s (%d)", label, resp.StatusCode), err)
+		return respBody
+	}
+
+	log.Printf("✓ Response validated: %s (%d)", label, resp.StatusCode)
+	return respBody
+}
+
+// fail records a failed check and always returns nil
+func (v *liveValidator) fail(label string, err error) []byte {
+	log.Printf("❌ Response invalid: %s - %v", label, err)
+	v.failures++
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build seed
+// +build seed
+
+// Package seed holds a fixed set of enrollments with stable IDs so demos and
+// end-to-end tests start from the same data every time. It is only compiled
+// into builds made with -tags seed.
+package seed
+
+import (
+	"techwave/models"
+	"techwave/repository"
+	"time"
+)
+
+// Epoch is the enrollment date of every seeded enrollment. Creation times
+// follow it one minute apart so list ordering is stable too.
+var Epoch = time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+
+// record is one seeded enrollment
+type record struct {
+	id        string
+	studentID string
+	courseID  string
+	status    string
+}
+
+var records = []record{
+	{"00000000-0000-4000-8000-000000000001", "student-001", "CS101", "active"},
+	{"00000000-0000-4000-8000-000000000002", "student-002", "CS101", "active"},
+	{"00000000-0000-4000-8000-000000000003", "student-003", "CS101", "pending"},
+	{"00000000-0000-4000-8000-000000000004", "student-001", "MATH201", "completed"},
+	{"00000000-0000-4000-8000-000000000005", "student-002", "MATH201", "active"},
+	{"00000000-0000-4000-8000-000000000006", "student-004", "HIST110", "waitlisted"},
+}
+
+// Result reports how many seed enrollments a Load inserted and how many were
+// already present
+type Result struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+}
+
+// Enrollments returns new copies of the seed enrollments, oldest first
+func Enrollments() []*models.Enrollment {
+	enrollments := make([]*models.Enrollment, len(records))
+	for i, rec := range records {
+		createdAt := Epoch.Add(time.Duration(i) * time.Minute)
+		enrollments[i] = &models.Enrollment{
+			ID:             rec.id,
+			StudentID:      rec.studentID,
+			CourseID:       rec.courseID,
+			Status:         rec.status,
+			EnrollmentDate: Epoch,
+			CreatedAt:      createdAt,
+			UpdatedAt:      createdAt,
+		}
+	}
+	return enrollments
+}
+
+// Load inserts the seed enrollments into store. Enrollments that already
+// exist are left as they are, so loading twice is harmless. It returns the
+// inserted enrollments and the first error other than
+// repository.ErrAlreadyExists or repository.ErrIDTaken.
+func Load(store repository.Store) ([]*models.Enrollment, Result, error) {
+	enrollments := Enrollments()
+
+	var created []*models.Enrollment
+	var result Result
+	for i, err := range store.CreateBatch(enrollments) {
+		switch err {
+		case nil:
+			created = append(created, enrollments[i])
+			result.Created++
+		case repository.ErrAlreadyExists, repository.ErrIDTaken:
+			result.Existing++
+		default:
+			return created, result, err
+		}
+	}
+	return created, result, nil
+}
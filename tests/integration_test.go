@@ -4,56 +4,72 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"techwave/app"
 	"techwave/cache"
+	"techwave/client"
+	"techwave/config"
 	"techwave/handlers"
+	"techwave/jobs"
+	"techwave/middleware"
 	"techwave/models"
+	"techwave/notify"
 	"techwave/repository"
 
 	"github.com/alicebob/miniredis/v2"
-	"github.com/gorilla/mux"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/yaml.v3"
 )
 
+// enrollmentPage mirrors the paginated list response
+type enrollmentPage struct {
+	Data   []models.Enrollment `json:"data"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
 // setupTestServer creates a test server with mock Redis
 func setupTestServer(t *testing.T) (*httptest.Server, *miniredis.Miniredis, *cache.EnrollmentCache) {
 	// Start mini Redis
 	mr, err := miniredis.Run()
 	require.NoError(t, err)
 
-	// Create Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: mr.Addr(),
+	// Each test gets its own isolated app
+	application := app.NewApp(app.Config{
+		RedisClient: redis.NewClient(&redis.Options{
+			Addr: mr.Addr(),
+		}),
 	})
 
-	// Initialize components
-	enrollmentRepo := repository.NewEnrollmentRepository()
-	enrollmentCache := cache.NewEnrollmentCache(redisClient)
-	enrollmentHandler := handlers.NewEnrollmentHandler(enrollmentRepo, enrollmentCache)
-
-	// Setup router
-	router := mux.NewRouter()
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Grade Management API - Cache: enabled")
-	}).Methods("GET")
-
-	apiRouter := router.PathPrefix("/api").Subrouter()
-	apiRouter.HandleFunc("/enrollments", enrollmentHandler.CreateEnrollment).Methods("POST")
-	apiRouter.HandleFunc("/enrollments", enrollmentHandler.GetAllEnrollments).Methods("GET")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.GetEnrollment).Methods("GET")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.UpdateEnrollment).Methods("PUT")
-	apiRouter.HandleFunc("/enrollments/{id}", enrollmentHandler.DeleteEnrollment).Methods("DELETE")
+	server := httptest.NewServer(application.Routes())
+	return server, mr, application.Cache
+}
 
-	server := httptest.NewServer(router)
-	return server, mr, enrollmentCache
+// setupTestServerWithoutCache creates a test server with caching disabled
+func setupTestServerWithoutCache(t *testing.T) *httptest.Server {
+	return httptest.NewServer(app.NewApp(app.Config{}).Routes())
 }
 
 // TestCompleteCRUDWorkflow tests the complete CRUD workflow
@@ -89,7 +105,7 @@ func TestCompleteCRUDWorkflow(t *testing.T) {
 	resp, err = http.Get(server.URL + "/api/enrollments/" + enrollmentID)
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
 
 	var fetched models.Enrollment
 	err = json.NewDecoder(resp.Body).Decode(&fetched)
@@ -109,6 +125,7 @@ func TestCompleteCRUDWorkflow(t *testing.T) {
 		"student_id": "student-123",
 		"course_id":  "course-456",
 		"status":     "active",
+		"version":    1,
 	}
 	updateBody, _ := json.Marshal(updatePayload)
 
@@ -128,7 +145,7 @@ func TestCompleteCRUDWorkflow(t *testing.T) {
 	resp, err = http.Get(server.URL + "/api/enrollments/" + enrollmentID)
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
 	resp.Body.Close()
 
 	// 6. GET all enrollments
@@ -136,10 +153,11 @@ func TestCompleteCRUDWorkflow(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var allEnrollments []models.Enrollment
+	var allEnrollments enrollmentPage
 	err = json.NewDecoder(resp.Body).Decode(&allEnrollments)
 	require.NoError(t, err)
-	assert.NotEmpty(t, allEnrollments)
+	assert.NotEmpty(t, allEnrollments.Data)
+	assert.Equal(t, 1, allEnrollments.Total)
 	resp.Body.Close()
 
 	// 7. DELETE enrollment (should invalidate cache)
@@ -219,7 +237,7 @@ func TestCacheInvalidation(t *testing.T) {
 
 	// GET to populate cache
 	resp, _ = http.Get(server.URL + "/api/enrollments/" + enrollmentID)
-	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
 	resp.Body.Close()
 
 	// GET again to confirm cache HIT
@@ -232,6 +250,7 @@ func TestCacheInvalidation(t *testing.T) {
 		"student_id": "cache-test",
 		"course_id":  "cache-course",
 		"status":     "active",
+		"version":    1,
 	}
 	updateBody, _ := json.Marshal(updatePayload)
 	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+enrollmentID, bytes.NewBuffer(updateBody))
@@ -241,7 +260,7 @@ func TestCacheInvalidation(t *testing.T) {
 
 	// GET after update should be cache MISS
 	resp, _ = http.Get(server.URL + "/api/enrollments/" + enrollmentID)
-	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
 	resp.Body.Close()
 
 	// GET again to populate cache
@@ -294,7 +313,7 @@ func TestValidationErrors(t *testing.T) {
 			name:           "invalid status",
 			payload:        map[string]interface{}{"student_id": "student-1", "course_id": "course-1", "status": "invalid"},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "status must be one of: pending, active, completed",
+			expectedError:  "status must be one of: pending, active, completed, waitlisted",
 		},
 	}
 
@@ -313,6 +332,68 @@ func TestValidationErrors(t *testing.T) {
 	}
 }
 
+// TestDefaultEnrollmentStatus validates that a configured default status
+// fills in for a status omitted on create, and only on create
+func TestDefaultEnrollmentStatus(t *testing.T) {
+	post := func(server *httptest.Server, path, body string) *http.Response {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Without a default, status stays required even with schema validation on
+	strict := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true}).Routes())
+	defer strict.Close()
+	resp := post(strict, "/api/enrollments", `{"student_id": "default-student", "course_id": "default-course"}`)
+	var errResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "status is required", errResp.Error)
+
+	server := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true, DefaultEnrollmentStatus: "Pending"}).Routes())
+	defer server.Close()
+
+	resp = post(server, "/api/enrollments", `{"student_id": "default-student", "course_id": "default-course"}`)
+	var created models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "pending", created.Status)
+
+	// An explicit status is kept
+	resp = post(server, "/api/enrollments", `{"student_id": "default-student-2", "course_id": "default-course", "status": "active"}`)
+	var explicit models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&explicit))
+	resp.Body.Close()
+	assert.Equal(t, "active", explicit.Status)
+
+	// Bulk creates get the default too
+	resp = post(server, "/api/enrollments/bulk", `[{"student_id": "default-student-3", "course_id": "default-course"}]`)
+	var results []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	require.Len(t, results, 1)
+	require.NotEmpty(t, results[0].ID)
+	resp, err := http.Get(server.URL + "/api/enrollments/" + results[0].ID)
+	require.NoError(t, err)
+	var bulkCreated models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bulkCreated))
+	resp.Body.Close()
+	assert.Equal(t, "pending", bulkCreated.Status)
+
+	// Updates still need a status
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID,
+		strings.NewReader(fmt.Sprintf(`{"student_id": "default-student", "course_id": "default-course", "version": %d}`, created.Version)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 // TestNotFoundErrors tests 404 error handling
 func TestNotFoundErrors(t *testing.T) {
 	server, mr, _ := setupTestServer(t)
@@ -349,6 +430,114 @@ func TestNotFoundErrors(t *testing.T) {
 	resp.Body.Close()
 }
 
+// TestErrorCodes validates the machine-readable code sent with each error
+func TestErrorCodes(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	send := func(method, path, contentType, body string) models.ErrorResponse {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.NotEmpty(t, errResp.Error)
+		return errResp
+	}
+
+	enrollment := `{"student_id": "code-student", "course_id": "code-course", "status": "active"}`
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json", strings.NewReader(enrollment))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		code        models.ErrorCode
+	}{
+		{"missing enrollment", http.MethodGet, "/api/enrollments/00000000-0000-0000-0000-000000000000", "", "", models.CodeEnrollmentNotFound},
+		{"malformed id", http.MethodGet, "/api/enrollments/not-a-uuid", "", "", models.CodeInvalidID},
+		{"bad limit", http.MethodGet, "/api/enrollments?limit=0", "", "", models.CodeInvalidParameter},
+		{"malformed body", http.MethodPost, "/api/enrollments", "application/json", "{", models.CodeInvalidBody},
+		{"invalid fields", http.MethodPost, "/api/enrollments", "application/json", `{"course_id": "code-course", "status": "active"}`, models.CodeValidationFailed},
+		{"duplicate", http.MethodPost, "/api/enrollments", "application/json", enrollment, models.CodeEnrollmentExists},
+		{"wrong media type", http.MethodPost, "/api/enrollments", "text/plain", enrollment, models.CodeUnsupportedMediaType},
+		{"unknown path", http.MethodGet, "/api/nowhere", "", "", models.CodeNotFound},
+		{"wrong method", http.MethodPatch, "/api/enrollments", "", "", models.CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, send(tt.method, tt.path, tt.contentType, tt.body).Code)
+		})
+	}
+}
+
+// TestErrorLocalization validates that Accept-Language selects the language
+// of error messages
+func TestErrorLocalization(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	missing := "/api/enrollments/00000000-0000-0000-0000-000000000000"
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		language       string
+		message        string
+	}{
+		{"no header", "", "en", "Enrollment not found"},
+		{"english", "en-US", "en", "Enrollment not found"},
+		{"spanish", "es", "es", "Matrícula no encontrada"},
+		{"regional variant", "fr-CA", "fr", "Inscription introuvable"},
+		{"highest q wins", "es;q=0.5, fr;q=0.9, en;q=0.1", "fr", "Inscription introuvable"},
+		{"unknown skipped", "de, es;q=0.8", "es", "Matrícula no encontrada"},
+		{"unknown only", "de-DE", "en", "Enrollment not found"},
+		{"excluded", "es;q=0", "en", "Enrollment not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+missing, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			assert.Equal(t, tt.language, resp.Header.Get("Content-Language"))
+			assert.Contains(t, resp.Header.Values("Vary"), "Accept-Language")
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, tt.message, errResp.Error)
+			assert.Equal(t, models.CodeEnrollmentNotFound, errResp.Code)
+		})
+	}
+
+	// Validation errors translate the summary but keep the field messages
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/enrollments", strings.NewReader(`{"course_id": "l10n-course", "status": "active"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "es")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var errResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "La matrícula no superó la validación", errResp.Error)
+	require.NotEmpty(t, errResp.Errors)
+	assert.Equal(t, "student_id is required", errResp.Errors[0].Message)
+}
+
 // TestResponseSchemaValidation ensures all responses match expected schemas
 func TestResponseSchemaValidation(t *testing.T) {
 	server, mr, _ := setupTestServer(t)
@@ -380,3 +569,5846 @@ func TestResponseSchemaValidation(t *testing.T) {
 	
 	resp.Body.Close()
 }
+
+// TestCRUDWithoutCache ensures handlers work when no cache is configured
+func TestCRUDWithoutCache(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	createPayload := map[string]interface{}{
+		"student_id": "nocache-student",
+		"course_id":  "nocache-course",
+		"status":     "pending",
+	}
+	createBody, _ := json.Marshal(createPayload)
+
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(createBody))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+
+	// GET without a cache is always served from the repository
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "SKIP", resp.Header.Get("X-Cache-Status"))
+	resp.Body.Close()
+
+	// UPDATE and DELETE must not touch the missing cache
+	updatePayload := map[string]interface{}{
+		"student_id": "nocache-student",
+		"course_id":  "nocache-course",
+		"status":     "active",
+		"version":    1,
+	}
+	updateBody, _ := json.Marshal(updatePayload)
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID, bytes.NewBuffer(updateBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// createTestEnrollment posts an enrollment and returns the decoded response
+func createTestEnrollment(t *testing.T, serverURL string, payload map[string]interface{}) models.Enrollment {
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(serverURL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	return created
+}
+
+// TestPagination validates limit/offset handling on the list endpoint
+func TestPagination(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	for i := 0; i < 5; i++ {
+		createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": fmt.Sprintf("page-student-%d", i),
+			"course_id":  "page-course",
+			"status":     "pending",
+		})
+	}
+
+	// Defaults apply when no parameters are given
+	resp, err := http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, 50, page.Limit)
+	assert.Equal(t, 0, page.Offset)
+	assert.Len(t, page.Data, 5)
+
+	// Pages do not overlap and cover every record
+	seen := map[string]bool{}
+	for _, offset := range []int{0, 2, 4} {
+		resp, err = http.Get(fmt.Sprintf("%s/api/enrollments?limit=2&offset=%d", server.URL, offset))
+		require.NoError(t, err)
+		page = enrollmentPage{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		resp.Body.Close()
+		assert.Equal(t, 5, page.Total)
+		for _, e := range page.Data {
+			assert.False(t, seen[e.ID], "enrollment %s returned twice", e.ID)
+			seen[e.ID] = true
+		}
+	}
+	assert.Len(t, seen, 5)
+
+	// Offset past the end yields an empty page
+	resp, err = http.Get(server.URL + "/api/enrollments?offset=10")
+	require.NoError(t, err)
+	page = enrollmentPage{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Empty(t, page.Data)
+	assert.NotNil(t, page.Data)
+
+	// Invalid parameters are rejected
+	for _, query := range []string{"limit=0", "limit=-1", "limit=abc", "limit=501", "offset=-1"} {
+		resp, err = http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		resp.Body.Close()
+	}
+}
+
+// TestPageLimitCap validates that list limits above handlers.MaxPageLimit
+// are rejected, or clamped when configured
+func TestPageLimitCap(t *testing.T) {
+	list := func(server *httptest.Server, query string) (*http.Response, enrollmentPage) {
+		resp, err := http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var page enrollmentPage
+		json.NewDecoder(resp.Body).Decode(&page)
+		return resp, page
+	}
+
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	resp, page := list(server, fmt.Sprintf("limit=%d", handlers.MaxPageLimit))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, handlers.MaxPageLimit, page.Limit)
+
+	resp, err := http.Get(server.URL + "/api/enrollments?limit=1000000")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var errorBody models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+	resp.Body.Close()
+	assert.Equal(t, fmt.Sprintf("limit must not exceed %d", handlers.MaxPageLimit), errorBody.Error)
+	assert.Empty(t, resp.Header.Get("Warning"))
+
+	// Clamping serves the maximum and says so
+	clamping := httptest.NewServer(app.NewApp(app.Config{ClampPageLimit: true}).Routes())
+	defer clamping.Close()
+
+	resp, page = list(clamping, "limit=1000000")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, handlers.MaxPageLimit, page.Limit)
+	assert.Equal(t, fmt.Sprintf(`299 - "limit clamped to %d"`, handlers.MaxPageLimit), resp.Header.Get("Warning"))
+
+	resp, page = list(clamping, "limit=20")
+	assert.Equal(t, 20, page.Limit)
+	assert.Empty(t, resp.Header.Get("Warning"))
+
+	// Malformed limits are still rejected
+	resp, _ = list(clamping, "limit=0")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestFiltering validates query-parameter filtering on the list endpoint
+func TestFiltering(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	fixtures := []map[string]interface{}{
+		{"student_id": "s1", "course_id": "c1", "status": "active"},
+		{"student_id": "s1", "course_id": "c2", "status": "pending"},
+		{"student_id": "s2", "course_id": "c1", "status": "active"},
+		{"student_id": "s2", "course_id": "c2", "status": "completed"},
+	}
+	for _, payload := range fixtures {
+		createTestEnrollment(t, server.URL, payload)
+	}
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"", 4},
+		{"student_id=s1", 2},
+		{"course_id=c1", 2},
+		{"status=active", 2},
+		{"status=active&student_id=s1", 1},
+		{"status=completed&course_id=c1", 0},
+		{"student_id=unknown", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/api/enrollments?" + tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var page enrollmentPage
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			resp.Body.Close()
+			assert.Equal(t, tt.expected, page.Total)
+			assert.Len(t, page.Data, tt.expected)
+		})
+	}
+
+	// Unknown status values are rejected
+	resp, err := http.Get(server.URL + "/api/enrollments?status=bogus")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestGrades validates grade creation, letter derivation and listing
+func TestGrades(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "grade-student",
+		"course_id":  "grade-course",
+		"status":     "active",
+	})
+	gradesURL := server.URL + "/api/enrollments/" + enrollment.ID + "/grades"
+
+	// Letter grade is derived from the score when omitted
+	body, _ := json.Marshal(map[string]interface{}{"score": 85.5})
+	resp, err := http.Post(gradesURL, "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var grade models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&grade))
+	resp.Body.Close()
+	assert.NotEmpty(t, grade.ID)
+	assert.Equal(t, enrollment.ID, grade.EnrollmentID)
+	assert.Equal(t, "B", grade.LetterGrade)
+	assert.NotZero(t, grade.GradedAt)
+	assert.True(t, grade.Passed)
+
+	// An explicit letter grade is kept
+	body, _ = json.Marshal(map[string]interface{}{"score": 59, "letter_grade": "D"})
+	resp, err = http.Post(gradesURL, "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	// Scores outside 0-100 are rejected
+	for _, score := range []float64{-1, 100.5} {
+		body, _ = json.Marshal(map[string]interface{}{"score": score})
+		resp, err = http.Post(gradesURL, "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// Listing returns both grades
+	resp, err = http.Get(gradesURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var grades []models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&grades))
+	resp.Body.Close()
+	require.Len(t, grades, 2)
+	assert.True(t, grades[0].Passed)
+	assert.False(t, grades[1].Passed)
+
+	// Unknown enrollments return 404
+	resp, err = http.Get(server.URL + "/api/enrollments/00000000-0000-0000-0000-000000000000/grades")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestGradeUpdateDelete validates updating and deleting single grades and
+// that a grade cannot be reached through another enrollment
+func TestGradeUpdateDelete(t *testing.T) {
+	server := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true}).Routes())
+	defer server.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "regrade-student",
+		"course_id":  "regrade-course",
+		"status":     "completed",
+	})
+	other := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "regrade-student-2",
+		"course_id":  "regrade-course",
+		"status":     "completed",
+	})
+	gradesURL := server.URL + "/api/enrollments/" + enrollment.ID + "/grades"
+	otherGradesURL := server.URL + "/api/enrollments/" + other.ID + "/grades"
+
+	resp, err := http.Post(gradesURL, "application/json", strings.NewReader(`{"score": 55}`))
+	require.NoError(t, err)
+	var grade models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&grade))
+	resp.Body.Close()
+	require.Equal(t, "F", grade.LetterGrade)
+
+	send := func(method, url, body string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	gpa := func() float64 {
+		resp, err := http.Get(server.URL + "/api/students/regrade-student/gpa")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body struct {
+			GPA float64 `json:"gpa"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body.GPA
+	}
+	assert.Equal(t, 0.0, gpa())
+
+	// Updating re-derives the letter and keeps the grading time
+	resp = send(http.MethodPut, gradesURL+"/"+grade.ID, `{"score": 91}`)
+	var updated models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, grade.ID, updated.ID)
+	assert.Equal(t, enrollment.ID, updated.EnrollmentID)
+	assert.Equal(t, 91.0, updated.Score)
+	assert.Equal(t, "A", updated.LetterGrade)
+	assert.True(t, updated.Passed)
+	assert.True(t, grade.GradedAt.Equal(updated.GradedAt))
+	assert.Equal(t, 4.0, gpa())
+
+	resp, err = http.Get(gradesURL + "/" + grade.ID)
+	require.NoError(t, err)
+	var fetched models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "A", fetched.LetterGrade)
+
+	// Invalid scores are rejected
+	resp = send(http.MethodPut, gradesURL+"/"+grade.ID, `{"score": 101}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The grade cannot be read, changed or deleted through another enrollment
+	for _, attempt := range []struct{ method, body string }{
+		{http.MethodGet, ""},
+		{http.MethodPut, `{"score": 10}`},
+		{http.MethodDelete, ""},
+	} {
+		resp = send(attempt.method, otherGradesURL+"/"+grade.ID, attempt.body)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, attempt.method)
+		assert.Equal(t, models.CodeGradeNotFound, errResp.Code, attempt.method)
+	}
+	resp, err = http.Get(gradesURL + "/" + grade.ID)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.Equal(t, 91.0, fetched.Score)
+
+	// Malformed ids and unknown enrollments
+	resp = send(http.MethodDelete, gradesURL+"/not-a-uuid", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = send(http.MethodDelete, server.URL+"/api/enrollments/00000000-0000-0000-0000-000000000000/grades/"+grade.ID, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Deleting removes the grade from listings and the GPA
+	resp = send(http.MethodDelete, gradesURL+"/"+grade.ID, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = send(http.MethodDelete, gradesURL+"/"+grade.ID, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(gradesURL)
+	require.NoError(t, err)
+	var grades []models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&grades))
+	resp.Body.Close()
+	assert.Empty(t, grades)
+	assert.Equal(t, 0.0, gpa())
+}
+
+// TestGradeComponents validates scores computed from weighted components
+func TestGradeComponents(t *testing.T) {
+	server := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true}).Routes())
+	defer server.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "component-student",
+		"course_id":  "component-course",
+		"status":     "active",
+	})
+	gradesURL := server.URL + "/api/enrollments/" + enrollment.ID + "/grades"
+
+	post := func(body string) (*http.Response, []byte) {
+		resp, err := http.Post(gradesURL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+
+	// The score is the weighted average, and the components are returned
+	resp, data := post(`{"components": [
+		{"name": "Assignments", "weight": 0.3, "score": 95},
+		{"name": "Midterm", "weight": 0.3, "score": 70},
+		{"name": "Final exam", "weight": 0.4, "score": 84.5}
+	]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	var grade models.Grade
+	require.NoError(t, json.Unmarshal(data, &grade))
+	assert.Equal(t, 83.3, grade.Score)
+	assert.Equal(t, "B", grade.LetterGrade)
+	require.Len(t, grade.Components, 3)
+	assert.Equal(t, models.GradeComponent{Name: "Final exam", Weight: 0.4, Score: 84.5}, grade.Components[2])
+
+	// Weights within the tolerance of 1 are accepted, and a score sent
+	// alongside components is replaced
+	resp, data = post(`{"score": 10, "components": [
+		{"name": "A", "weight": 0.333, "score": 90},
+		{"name": "B", "weight": 0.333, "score": 90},
+		{"name": "C", "weight": 0.3335, "score": 90}
+	]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	require.NoError(t, json.Unmarshal(data, &grade))
+	assert.Equal(t, 90.0, grade.Score)
+
+	// Components are kept on reads and replaced on update
+	resp, err := http.Get(gradesURL + "/" + grade.ID)
+	require.NoError(t, err)
+	var fetched models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.Len(t, fetched.Components, 3)
+
+	req, _ := http.NewRequest(http.MethodPut, gradesURL+"/"+grade.ID, strings.NewReader(`{"components": [{"name": "Retake", "weight": 1, "score": 55}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.Equal(t, 55.0, fetched.Score)
+	assert.Equal(t, "F", fetched.LetterGrade)
+	assert.Len(t, fetched.Components, 1)
+
+	// Plain scores carry no components
+	resp, data = post(`{"score": 77}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotContains(t, string(data), "components")
+
+	invalid := map[string]struct {
+		body    string
+		message string
+	}{
+		"weights under 1":  {`{"components": [{"name": "A", "weight": 0.5, "score": 90}, {"name": "B", "weight": 0.4, "score": 80}]}`, "component weights must sum to 1, got 0.9"},
+		"weights over 1":   {`{"components": [{"name": "A", "weight": 0.7, "score": 90}, {"name": "B", "weight": 0.7, "score": 80}]}`, "component weights must sum to 1, got 1.4"},
+		"zero weight":      {`{"components": [{"name": "A", "weight": 1, "score": 90}, {"name": "B", "weight": 0, "score": 80}]}`, ""},
+		"missing name":     {`{"components": [{"name": " ", "weight": 1, "score": 90}]}`, "components[0].name is required"},
+		"duplicate name":   {`{"components": [{"name": "Exam", "weight": 0.5, "score": 90}, {"name": "exam", "weight": 0.5, "score": 80}]}`, "component \"exam\" appears more than once"},
+		"component score":  {`{"components": [{"name": "A", "weight": 1, "score": 120}]}`, ""},
+		"neither provided": {`{"letter_grade": "A"}`, ""},
+	}
+	for name, tt := range invalid {
+		t.Run(name, func(t *testing.T) {
+			resp, data := post(tt.body)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			if tt.message != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(data, &errResp))
+				assert.Equal(t, tt.message, errResp.Error)
+			}
+		})
+	}
+
+	// Handler validation catches the same errors without schema validation
+	unchecked := httptest.NewServer(app.NewApp(app.Config{}).Routes())
+	defer unchecked.Close()
+	other := createTestEnrollment(t, unchecked.URL, map[string]interface{}{
+		"student_id": "component-student",
+		"course_id":  "component-course",
+		"status":     "active",
+	})
+	for _, body := range []string{
+		`{"components": [{"name": "A", "weight": 1, "score": 90}, {"name": "B", "weight": 0, "score": 80}]}`,
+		`{"components": [{"name": "A", "weight": 1, "score": 120}]}`,
+	} {
+		resp, err := http.Post(unchecked.URL+"/api/enrollments/"+other.ID+"/grades", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+// TestGradeSummary validates score statistics for an enrollment's grades
+func TestGradeSummary(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "summary-student",
+		"course_id":  "summary-course",
+		"status":     "active",
+	})
+	summaryURL := server.URL + "/api/enrollments/" + enrollment.ID + "/grades/summary"
+
+	getSummary := func() models.GradeSummary {
+		resp, err := http.Get(summaryURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var summary models.GradeSummary
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+		return summary
+	}
+
+	// No grades yet gives an empty summary
+	assert.Equal(t, models.GradeSummary{}, getSummary())
+
+	// An odd number of scores uses the middle one as the median
+	postGrade(t, server.URL, enrollment.ID, 90)
+	postGrade(t, server.URL, enrollment.ID, 50)
+	postGrade(t, server.URL, enrollment.ID, 81)
+	assert.Equal(t, models.GradeSummary{Count: 3, Min: 50, Max: 90, Mean: 73.67, Median: 81, PassedCount: 2, Passed: true}, getSummary())
+
+	// An even number averages the two middle scores
+	postGrade(t, server.URL, enrollment.ID, 100)
+	assert.Equal(t, models.GradeSummary{Count: 4, Min: 50, Max: 100, Mean: 80.25, Median: 85.5, PassedCount: 3, Passed: true}, getSummary())
+
+	// Unknown enrollments return 404
+	resp, err := http.Get(server.URL + "/api/enrollments/00000000-0000-0000-0000-000000000000/grades/summary")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestCustomGradeScale validates letter grades derived on a configured scale
+func TestCustomGradeScale(t *testing.T) {
+	scale, err := models.ParseGradeScale("A:93,B:85,C:77,D:70,F:0")
+	require.NoError(t, err)
+	server := httptest.NewServer(app.NewApp(app.Config{GradeScale: scale}).Routes())
+	defer server.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "scale-student",
+		"course_id":  "scale-course",
+		"status":     "active",
+	})
+	gradesURL := server.URL + "/api/enrollments/" + enrollment.ID + "/grades"
+
+	for score, letter := range map[float64]string{95: "A", 92: "B", 85: "B", 76.5: "D", 65: "F"} {
+		body, _ := json.Marshal(map[string]interface{}{"score": score})
+		resp, err := http.Post(gradesURL, "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var grade models.Grade
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&grade))
+		resp.Body.Close()
+		assert.Equal(t, letter, grade.LetterGrade, "score %v", score)
+	}
+
+	// Thresholds must decrease from the highest grade down
+	for _, raw := range []string{"A:80,B:90", "A:90,B:90", "A:90,E:50", "A:90,A:80", "A:101", "A90", ""} {
+		_, err := models.ParseGradeScale(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+// TestPassingScore validates that pass/fail is derived from the configured
+// threshold when grades are read, including grades stored beforehand
+func TestPassingScore(t *testing.T) {
+	application := app.NewApp(app.Config{PassingScore: 80})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "passing-student",
+		"course_id":  "passing-course",
+		"status":     "active",
+	})
+
+	// A grade stored before the threshold changed carries no pass/fail state
+	stored := &models.Grade{ID: uuid.NewString(), EnrollmentID: enrollment.ID, Score: 75, LetterGrade: "C", GradedAt: time.Now()}
+	require.NoError(t, application.Grades.Create(stored))
+	postGrade(t, server.URL, enrollment.ID, 85)
+
+	resp, err := http.Get(server.URL + "/api/enrollments/" + enrollment.ID + "/grades")
+	require.NoError(t, err)
+	var grades []models.Grade
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&grades))
+	resp.Body.Close()
+	require.Len(t, grades, 2)
+	assert.False(t, grades[0].Passed)
+	assert.True(t, grades[1].Passed)
+	assert.False(t, stored.Passed, "reading must not modify the stored grade")
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + enrollment.ID + "/grades/summary")
+	require.NoError(t, err)
+	var summary models.GradeSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	resp.Body.Close()
+	assert.Equal(t, 1, summary.PassedCount)
+	assert.True(t, summary.Passed)
+}
+
+// postGrade records a grade for an enrollment
+func postGrade(t *testing.T, serverURL, enrollmentID string, score float64) {
+	body, _ := json.Marshal(map[string]interface{}{"score": score})
+	resp, err := http.Post(serverURL+"/api/enrollments/"+enrollmentID+"/grades", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestStudentGPA validates GPA aggregation across completed enrollments
+func TestStudentGPA(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	completedA := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "gpa-student", "course_id": "math", "status": "completed",
+	})
+	completedB := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "gpa-student", "course_id": "physics", "status": "completed",
+	})
+	active := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "gpa-student", "course_id": "art", "status": "active",
+	})
+
+	// math averages 92 (A = 4.0), physics is 75 (C = 2.0), art is not completed
+	postGrade(t, server.URL, completedA.ID, 90)
+	postGrade(t, server.URL, completedA.ID, 94)
+	postGrade(t, server.URL, completedB.ID, 75)
+	postGrade(t, server.URL, active.ID, 10)
+
+	resp, err := http.Get(server.URL + "/api/students/gpa-student/gpa")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		StudentID     string  `json:"student_id"`
+		GPA           float64 `json:"gpa"`
+		GradedCourses int     `json:"graded_courses"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Equal(t, "gpa-student", result.StudentID)
+	assert.Equal(t, 3.0, result.GPA)
+	assert.Equal(t, 2, result.GradedCourses)
+
+	// Students without enrollments return 404
+	resp, err = http.Get(server.URL + "/api/students/nobody/gpa")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestCourseStats validates per-course enrollment counts and average score
+func TestCourseStats(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	type courseStats struct {
+		CourseID     string         `json:"course_id"`
+		Total        int            `json:"total"`
+		ByStatus     map[string]int `json:"by_status"`
+		GradeCount   int            `json:"grade_count"`
+		AverageScore *float64       `json:"average_score"`
+	}
+	getStats := func(courseID string) (int, courseStats) {
+		resp, err := http.Get(server.URL + "/api/courses/" + courseID + "/stats")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var stats courseStats
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		}
+		return resp.StatusCode, stats
+	}
+
+	var ids []string
+	for i, status := range []string{"pending", "active", "active", "completed"} {
+		enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": fmt.Sprintf("stats-student-%d", i),
+			"course_id":  "stats-course",
+			"status":     status,
+		})
+		ids = append(ids, enrollment.ID)
+	}
+	createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "stats-student-0",
+		"course_id":  "other-course",
+		"status":     "active",
+	})
+
+	// No grades yet: counts only
+	code, stats := getStats("stats-course")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "stats-course", stats.CourseID)
+	assert.Equal(t, 4, stats.Total)
+	assert.Equal(t, map[string]int{"pending": 1, "active": 2, "completed": 1, "waitlisted": 0}, stats.ByStatus)
+	assert.Zero(t, stats.GradeCount)
+	assert.Nil(t, stats.AverageScore)
+
+	// The average covers every grade in the course
+	postGrade(t, server.URL, ids[1], 80)
+	postGrade(t, server.URL, ids[1], 91)
+	postGrade(t, server.URL, ids[3], 70)
+	code, stats = getStats("stats-course")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, stats.GradeCount)
+	require.NotNil(t, stats.AverageScore)
+	assert.Equal(t, 80.33, *stats.AverageScore)
+
+	// Soft-deleted enrollments drop out along with their grades
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+ids[3], nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	code, stats = getStats("stats-course")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, stats.Total)
+	assert.Equal(t, 0, stats.ByStatus["completed"])
+	assert.Equal(t, 85.5, *stats.AverageScore)
+
+	// Courses without enrollments return 404
+	code, _ = getStats("unknown-course")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// TestCourseCapacity validates waitlisting once a course's seats are taken
+// and that promotion to active needs a free seat
+func TestCourseCapacity(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	setCapacity := func(capacity interface{}) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{"capacity": capacity})
+		resp, err := http.Post(server.URL+"/api/courses/seat-course/capacity", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+	enroll := func(student, status string) models.Enrollment {
+		return createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": student,
+			"course_id":  "seat-course",
+			"status":     status,
+		})
+	}
+
+	code, result := setCapacity(2)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"course_id": "seat-course", "capacity": 2.0, "active": 0.0, "available": 2.0}, result)
+
+	// Seats fill up; later pending or active enrollments are waitlisted
+	first := enroll("seat-1", "active")
+	assert.Equal(t, "active", first.Status)
+	pending := enroll("seat-2", "pending")
+	assert.Equal(t, "pending", pending.Status)
+	enroll("seat-3", "active")
+	waitlisted := enroll("seat-4", "active")
+	assert.Equal(t, "waitlisted", waitlisted.Status)
+	assert.Equal(t, "waitlisted", enroll("seat-5", "pending").Status)
+	assert.Equal(t, "completed", enroll("seat-6", "completed").Status)
+
+	// Promotion needs a free seat, whether waitlisted or pending
+	for _, e := range []models.Enrollment{waitlisted, pending} {
+		resp := putEnrollment(t, server.URL, e.ID, map[string]interface{}{
+			"student_id": e.StudentID, "course_id": "seat-course", "status": "active", "version": e.Version,
+		})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+		assert.Equal(t, "course is full", body["error"])
+	}
+
+	// Waitlisted enrollments may only move to active
+	resp := putEnrollment(t, server.URL, waitlisted.ID, map[string]interface{}{
+		"student_id": waitlisted.StudentID, "course_id": "seat-course", "status": "completed", "version": waitlisted.Version,
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// Completing an active enrollment frees its seat for the waitlist
+	resp = putEnrollment(t, server.URL, first.ID, map[string]interface{}{
+		"student_id": first.StudentID, "course_id": "seat-course", "status": "completed", "version": first.Version,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	resp = putEnrollment(t, server.URL, waitlisted.ID, map[string]interface{}{
+		"student_id": waitlisted.StudentID, "course_id": "seat-course", "status": "active", "version": waitlisted.Version,
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Bulk promotion is all or nothing: one seat for one waitlisted enrollment
+	body, _ := json.Marshal(map[string]string{"course_id": "seat-course", "from_status": "waitlisted", "to_status": "active"})
+	resp, err := http.Post(server.URL+"/api/enrollments/bulk-status", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	code, result = setCapacity(3)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1.0, result["available"])
+	resp, err = http.Post(server.URL+"/api/enrollments/bulk-status", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Bulk creates seat items in order
+	code, _ = setCapacity(4)
+	require.Equal(t, http.StatusOK, code)
+	body, _ = json.Marshal([]map[string]interface{}{
+		{"student_id": "seat-7", "course_id": "seat-course", "status": "active"},
+		{"student_id": "seat-8", "course_id": "seat-course", "status": "active"},
+	})
+	resp, err = http.Post(server.URL+"/api/enrollments/bulk", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	var results []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	require.Len(t, results, 2)
+	statuses := make([]string, len(results))
+	for i, result := range results {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + result.ID)
+		require.NoError(t, err)
+		var e models.Enrollment
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&e))
+		resp.Body.Close()
+		statuses[i] = e.Status
+	}
+	assert.Equal(t, []string{"active", "waitlisted"}, statuses)
+
+	// Capacity must be a positive integer
+	for _, capacity := range []interface{}{0, -1, nil} {
+		code, _ = setCapacity(capacity)
+		assert.Equal(t, http.StatusBadRequest, code, "capacity %v", capacity)
+	}
+}
+
+// TestCacheLock validates acquiring, waiting for and safely releasing a
+// distributed lock
+func TestCacheLock(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	enrollmentCache := cache.NewEnrollmentCacheWithOptions(client, cache.Options{Namespace: "locks"})
+	ctx := context.Background()
+
+	lock, err := enrollmentCache.Lock(ctx, "report", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"locks:lock:report"}, mr.Keys())
+	assert.Equal(t, time.Second, mr.TTL("locks:lock:report"))
+
+	// A second holder waits until its context ends
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, err = enrollmentCache.Lock(waitCtx, "report", time.Second)
+	cancel()
+	assert.Equal(t, cache.ErrLockNotAcquired, err)
+
+	// ...and gets the lock once it is released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Unlock(ctx)
+	}()
+	next, err := enrollmentCache.Lock(ctx, "report", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, cache.ErrLockNotHeld, lock.Unlock(ctx))
+
+	// A holder whose lock expired never releases the next holder's
+	mr.FastForward(2 * time.Second)
+	last, err := enrollmentCache.Lock(ctx, "report", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, cache.ErrLockNotHeld, next.Unlock(ctx))
+	assert.True(t, mr.Exists("locks:lock:report"))
+	require.NoError(t, last.Unlock(ctx))
+	assert.Empty(t, mr.Keys())
+}
+
+// slowCountStore slows down seat counting so that seat decisions on
+// different instances overlap
+type slowCountStore struct {
+	*repository.SQLiteRepository
+}
+
+func (s slowCountStore) CountByStatus(filter repository.EnrollmentFilter) map[string]int {
+	time.Sleep(20 * time.Millisecond)
+	return s.SQLiteRepository.CountByStatus(filter)
+}
+
+// TestSeatLockAcrossInstances validates that instances sharing a store and
+// Redis never give away more seats than a course has
+func TestSeatLockAcrossInstances(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "seats.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+	store := slowCountStore{sqliteStore}
+
+	var servers []*httptest.Server
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(app.NewApp(app.Config{
+			Store:       store,
+			RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		}).Routes())
+		defer server.Close()
+		servers = append(servers, server)
+
+		resp, err := http.Post(server.URL+"/api/courses/shared-course/capacity", "application/json", strings.NewReader(`{"capacity": 1}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	post := func(server *httptest.Server, student, course string) (int, models.Enrollment) {
+		body, _ := json.Marshal(map[string]string{"student_id": student, "course_id": course, "status": "active"})
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var created models.Enrollment
+		json.NewDecoder(resp.Body).Decode(&created)
+		return resp.StatusCode, created
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, _ := post(servers[i%2], fmt.Sprintf("shared-%d", i), "shared-course")
+			assert.Equal(t, http.StatusCreated, code)
+		}(i)
+	}
+	wg.Wait()
+
+	counts := store.CountByStatus(repository.EnrollmentFilter{CourseID: "shared-course"})
+	assert.Equal(t, 1, counts["active"])
+	assert.Equal(t, 19, counts["waitlisted"])
+
+	// A request waits for another instance's lock...
+	holder := cache.NewEnrollmentCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	lock, err := holder.Lock(context.Background(), "seats:shared-course", time.Minute)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Unlock(context.Background())
+	}()
+	code, created := post(servers[0], "shared-late", "shared-course")
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "waitlisted", created.Status)
+
+	// ...but gives up with 503 once it has waited SeatLockWait
+	lock, err = holder.Lock(context.Background(), "seats:shared-course", time.Minute)
+	require.NoError(t, err)
+	defer lock.Unlock(context.Background())
+	code, _ = post(servers[1], "shared-busy", "shared-course")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Empty(t, store.Find(repository.EnrollmentFilter{StudentID: "shared-busy"}))
+
+	// Other courses are not blocked by it
+	code, _ = post(servers[1], "shared-busy", "open-course")
+	assert.Equal(t, http.StatusCreated, code)
+
+	// Courses without a capacity here are locked too, since another instance
+	// may have one
+	openLock, err := holder.Lock(context.Background(), "seats:uncapped-course", time.Minute)
+	require.NoError(t, err)
+	code, _ = post(servers[0], "shared-uncapped", "uncapped-course")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	require.NoError(t, openLock.Unlock(context.Background()))
+	code, _ = post(servers[0], "shared-uncapped", "uncapped-course")
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+// TestUpdateIgnoresDeletedAt validates that a deleted_at sent with an update
+// cannot soft-delete the enrollment on either backend
+func TestUpdateIgnoresDeletedAt(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "deleted-at.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+			defer server.Close()
+
+			created := createTestEnrollment(t, server.URL, map[string]interface{}{
+				"student_id": "deleted-at-student",
+				"course_id":  "deleted-at-course",
+				"status":     "pending",
+			})
+			resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+				"student_id": "deleted-at-student",
+				"course_id":  "deleted-at-course",
+				"status":     "active",
+				"version":    1,
+				"deleted_at": "2026-01-01T00:00:00Z",
+			})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			var updated models.Enrollment
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+			resp.Body.Close()
+			assert.Equal(t, "active", updated.Status)
+			assert.Nil(t, updated.DeletedAt)
+
+			resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+			require.NoError(t, err)
+			var fetched models.Enrollment
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Nil(t, fetched.DeletedAt)
+			assert.Equal(t, 1, store.CountByStatus(repository.EnrollmentFilter{CourseID: "deleted-at-course"})["active"])
+		})
+	}
+}
+
+// TestSoftDeleteAndRestore validates soft-delete visibility and restore
+func TestSoftDeleteAndRestore(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	enrollment := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "soft-student",
+		"course_id":  "soft-course",
+		"status":     "active",
+	})
+
+	// Prime the cache, then soft-delete
+	resp, _ := http.Get(server.URL + "/api/enrollments/" + enrollment.ID)
+	resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+enrollment.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// The cached copy must not be served after deletion
+	resp, err = http.Get(server.URL + "/api/enrollments/" + enrollment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// Deleted records are hidden from listings by default
+	resp, err = http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 0, page.Total)
+
+	// ...but visible with include_deleted
+	resp, err = http.Get(server.URL + "/api/enrollments?include_deleted=true")
+	require.NoError(t, err)
+	page = enrollmentPage{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	require.Equal(t, 1, page.Total)
+	assert.NotNil(t, page.Data[0].DeletedAt)
+
+	// Deleting twice is a 404
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+enrollment.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// Restore brings the record back
+	resp, err = http.Post(server.URL+"/api/enrollments/"+enrollment.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var restored models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&restored))
+	resp.Body.Close()
+	assert.Nil(t, restored.DeletedAt)
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + enrollment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Restoring a live record is a conflict
+	resp, err = http.Post(server.URL+"/api/enrollments/"+enrollment.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestBulkCreate validates per-item results of bulk enrollment creation
+func TestBulkCreate(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	payload := []map[string]interface{}{
+		{"student_id": "bulk-1", "course_id": "bulk-course", "status": "pending"},
+		{"student_id": "", "course_id": "bulk-course", "status": "pending"},
+		{"student_id": "bulk-3", "course_id": "bulk-course", "status": "active"},
+	}
+	body, _ := json.Marshal(payload)
+
+	resp, err := http.Post(server.URL+"/api/enrollments/bulk", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+
+	var results []struct {
+		Index int    `json:"index"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+
+	require.Len(t, results, 3)
+	assert.NotEmpty(t, results[0].ID)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, 1, results[1].Index)
+	assert.Empty(t, results[1].ID)
+	assert.Equal(t, "student_id is required", results[1].Error)
+	assert.NotEmpty(t, results[2].ID)
+
+	// Successful rows were persisted despite the failed row
+	resp, err = http.Get(server.URL + "/api/enrollments?course_id=bulk-course")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 2, page.Total)
+
+	// A fully valid batch returns 201
+	body, _ = json.Marshal([]map[string]interface{}{
+		{"student_id": "bulk-4", "course_id": "bulk-course", "status": "pending"},
+	})
+	resp, err = http.Post(server.URL+"/api/enrollments/bulk", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	// A student already enrolled in the course is reported per item
+	body, _ = json.Marshal(payload[:1])
+	resp, err = http.Post(server.URL+"/api/enrollments/bulk", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	resp.Body.Close()
+
+	// An empty batch is rejected
+	resp, err = http.Post(server.URL+"/api/enrollments/bulk", "application/json", bytes.NewBufferString("[]"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestUpdatePreservesCreatedAt ensures PUT keeps server-managed timestamps
+func TestUpdatePreservesCreatedAt(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "ts-student",
+		"course_id":  "ts-course",
+		"status":     "pending",
+	})
+
+	time.Sleep(10 * time.Millisecond)
+
+	updateBody, _ := json.Marshal(map[string]interface{}{
+		"student_id": "ts-student",
+		"course_id":  "ts-course",
+		"status":     "active",
+		"version":    1,
+	})
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID, bytes.NewBuffer(updateBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var updated models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	resp.Body.Close()
+
+	assert.True(t, created.CreatedAt.Equal(updated.CreatedAt), "CreatedAt changed on update")
+	assert.True(t, created.EnrollmentDate.Equal(updated.EnrollmentDate), "EnrollmentDate changed on update")
+	assert.True(t, updated.UpdatedAt.After(created.UpdatedAt), "UpdatedAt did not advance")
+
+	// The stored record agrees with the update response
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	var fetched models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.True(t, created.CreatedAt.Equal(fetched.CreatedAt))
+}
+
+// putEnrollment sends a PUT request and returns the response
+func putEnrollment(t *testing.T, serverURL, id string, payload map[string]interface{}) *http.Response {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(http.MethodPut, serverURL+"/api/enrollments/"+id, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// TestStatusTransitions validates the pending -> active -> completed flow
+func TestStatusTransitions(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "flow-student",
+		"course_id":  "flow-course",
+		"status":     "pending",
+	})
+	version := created.Version
+	withStatus := func(status string) map[string]interface{} {
+		return map[string]interface{}{"student_id": "flow-student", "course_id": "flow-course", "status": status, "version": version}
+	}
+
+	steps := []struct {
+		status         string
+		expectedStatus int
+	}{
+		{"completed", http.StatusConflict}, // cannot skip active
+		{"pending", http.StatusOK},         // same state is allowed
+		{"active", http.StatusOK},
+		{"pending", http.StatusConflict}, // cannot go backwards
+		{"completed", http.StatusOK},
+		{"active", http.StatusConflict},
+	}
+
+	for _, step := range steps {
+		resp := putEnrollment(t, server.URL, created.ID, withStatus(step.status))
+		assert.Equal(t, step.expectedStatus, resp.StatusCode, "transition to %s", step.status)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			version++
+		}
+	}
+
+	resp := putEnrollment(t, server.URL, created.ID, withStatus("pending"))
+	var errorResp map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResp))
+	resp.Body.Close()
+	assert.Equal(t, "cannot transition from completed to pending", errorResp["error"])
+}
+
+// TestEnrollmentHistory validates the audit trail across mutations
+func TestEnrollmentHistory(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "audit-student",
+		"course_id":  "audit-course",
+		"status":     "pending",
+	})
+
+	resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "audit-student", "course_id": "audit-course", "status": "active", "version": 1,
+	})
+	resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/api/enrollments/"+created.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID + "/history")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var history []models.AuditEntry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	resp.Body.Close()
+
+	require.Len(t, history, 4)
+	assert.Equal(t, models.AuditActionCreate, history[0].Action)
+	assert.Equal(t, "pending", history[0].NewStatus)
+	assert.Equal(t, models.AuditActionUpdate, history[1].Action)
+	assert.Equal(t, "pending", history[1].OldStatus)
+	assert.Equal(t, "active", history[1].NewStatus)
+	assert.Equal(t, models.AuditActionDelete, history[2].Action)
+	assert.Equal(t, "active", history[2].OldStatus)
+	assert.Equal(t, models.AuditActionRestore, history[3].Action)
+	for i := 1; i < len(history); i++ {
+		assert.False(t, history[i].At.Before(history[i-1].At))
+	}
+
+	// Unknown enrollments have no history
+	resp, err = http.Get(server.URL + "/api/enrollments/00000000-0000-0000-0000-000000000000/history")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestCacheWarmUp validates that warmed enrollments are served as cache hits
+func TestCacheWarmUp(t *testing.T) {
+	server, mr, enrollmentCache := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "warm-student",
+		"course_id":  "warm-course",
+		"status":     "active",
+	})
+
+	warmed, err := enrollmentCache.WarmUp(context.Background(), []*models.Enrollment{&created})
+	require.NoError(t, err)
+	assert.Equal(t, 1, warmed)
+	assert.True(t, mr.Exists(cache.EnrollmentCachePrefix+created.ID))
+
+	// The first GET is already a HIT
+	resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "HIT", resp.Header.Get("X-Cache-Status"))
+	resp.Body.Close()
+
+	// Warming nothing is a no-op
+	warmed, err = enrollmentCache.WarmUp(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, warmed)
+}
+
+// TestCacheTTL validates default and custom per-instance TTLs
+func TestCacheTTL(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	defaultCache := cache.NewEnrollmentCache(redisClient)
+	assert.Equal(t, cache.EnrollmentCacheTTL, defaultCache.TTL())
+	require.NoError(t, defaultCache.Set(context.Background(), &models.Enrollment{ID: "ttl-default"}))
+	assert.Equal(t, cache.EnrollmentCacheTTL, mr.TTL(cache.EnrollmentCachePrefix+"ttl-default"))
+
+	customCache := cache.NewEnrollmentCacheWithTTL(redisClient, 42*time.Second)
+	require.NoError(t, customCache.Set(context.Background(), &models.Enrollment{ID: "ttl-custom"}))
+	assert.Equal(t, 42*time.Second, mr.TTL(cache.EnrollmentCachePrefix+"ttl-custom"))
+
+	// Entries expire once the instance TTL elapses
+	mr.FastForward(43 * time.Second)
+	cached, err := customCache.Get(context.Background(), "ttl-custom")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+}
+
+// TestListCaching validates list caching and invalidation on mutation
+func TestListCaching(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	listStatus := func(query string) string {
+		resp, err := http.Get(server.URL + "/api/enrollments" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.Header.Get("X-Cache-Status")
+	}
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "list-student",
+		"course_id":  "list-course",
+		"status":     "pending",
+	})
+
+	assert.Equal(t, "MISS", listStatus(""))
+	assert.True(t, mr.Exists(cache.EnrollmentListCacheKey))
+	assert.Equal(t, cache.EnrollmentListCacheTTL, mr.TTL(cache.EnrollmentListCacheKey))
+	assert.Equal(t, "HIT", listStatus("?status=pending"))
+
+	// Create clears the list key
+	createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "list-student-2",
+		"course_id":  "list-course",
+		"status":     "pending",
+	})
+	assert.False(t, mr.Exists(cache.EnrollmentListCacheKey))
+
+	// Cached results reflect the new record once rebuilt
+	resp, err := http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 2, page.Total)
+
+	// Update clears the list key
+	assert.Equal(t, "HIT", listStatus(""))
+	resp = putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "list-student", "course_id": "list-course", "status": "active", "version": 1,
+	})
+	resp.Body.Close()
+	assert.False(t, mr.Exists(cache.EnrollmentListCacheKey))
+
+	// Delete clears the list key
+	assert.Equal(t, "MISS", listStatus(""))
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.False(t, mr.Exists(cache.EnrollmentListCacheKey))
+
+	// include_deleted bypasses the cached list
+	assert.Equal(t, "SKIP", listStatus("?include_deleted=true"))
+}
+
+// TestCacheStats validates hit/miss counters exposed by the stats endpoint
+func TestCacheStats(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "stats-student",
+		"course_id":  "stats-course",
+		"status":     "active",
+	})
+
+	// One miss followed by two hits
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(server.URL + "/api/cache/stats")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var stats struct {
+		Connected bool    `json:"connected"`
+		HitCount  int64   `json:"hit_count"`
+		MissCount int64   `json:"miss_count"`
+		HitRatio  float64 `json:"hit_ratio"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	resp.Body.Close()
+	assert.True(t, stats.Connected)
+	assert.Equal(t, int64(2), stats.HitCount)
+	assert.Equal(t, int64(1), stats.MissCount)
+	assert.InDelta(t, 2.0/3.0, stats.HitRatio, 0.0001)
+}
+
+// TestCacheStatsConcurrent ensures counters are safe under concurrent access
+func TestCacheStatsConcurrent(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	enrollmentCache := cache.NewEnrollmentCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	require.NoError(t, enrollmentCache.Set(context.Background(), &models.Enrollment{ID: "concurrent"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				enrollmentCache.Get(context.Background(), "concurrent")
+			} else {
+				enrollmentCache.Get(context.Background(), "missing")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats, err := enrollmentCache.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats["hit_count"])
+	assert.Equal(t, int64(10), stats["miss_count"])
+	assert.Equal(t, 0.5, stats["hit_ratio"])
+}
+
+// TestClearCache validates that only enrollment keys are flushed
+func TestClearCache(t *testing.T) {
+	server, mr, enrollmentCache := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	// Enough keys to need several SCAN iterations
+	for i := 0; i < 1200; i++ {
+		require.NoError(t, enrollmentCache.Set(context.Background(), &models.Enrollment{ID: fmt.Sprintf("clear-%d", i)}))
+	}
+	require.NoError(t, enrollmentCache.SetList(context.Background(), []*models.Enrollment{}))
+	require.NoError(t, mr.Set("unrelated:key", "keep me"))
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/cache", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]int64
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Equal(t, int64(1201), result["removed"])
+
+	assert.Equal(t, []string{"unrelated:key"}, mr.Keys())
+}
+
+// TestCacheDeleteMany validates batch invalidation in a few pipelined commands
+func TestCacheDeleteMany(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	enrollmentCache := cache.NewEnrollmentCacheWithOptions(client, cache.Options{FallbackSize: 10})
+	ctx := context.Background()
+
+	// Enough keys to need several UNLINK batches
+	enrollments := make([]*models.Enrollment, 1200)
+	ids := make([]string, len(enrollments))
+	for i := range enrollments {
+		enrollments[i] = &models.Enrollment{ID: fmt.Sprintf("many-%d", i)}
+		ids[i] = enrollments[i].ID
+	}
+	_, err = enrollmentCache.WarmUp(ctx, enrollments)
+	require.NoError(t, err)
+	require.NoError(t, mr.Set("unrelated:key", "keep me"))
+
+	before := mr.CommandCount()
+	require.NoError(t, enrollmentCache.DeleteMany(ctx, append(ids, "missing")))
+	assert.Equal(t, 3, mr.CommandCount()-before)
+	assert.Equal(t, []string{"unrelated:key"}, mr.Keys())
+
+	// Nothing to delete sends nothing
+	before = mr.CommandCount()
+	require.NoError(t, enrollmentCache.DeleteMany(ctx, nil))
+	assert.Equal(t, before, mr.CommandCount())
+
+	// Fallback entries are removed even while Redis is failing
+	mr.SetError("ERR unavailable")
+	require.NoError(t, enrollmentCache.Set(ctx, enrollments[0]))
+	cached, tier, err := enrollmentCache.Lookup(ctx, enrollments[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	require.Equal(t, cache.TierMemory, tier)
+	assert.Error(t, enrollmentCache.DeleteMany(ctx, ids[:1]))
+	cached, _, _ = enrollmentCache.Lookup(ctx, enrollments[0].ID)
+	assert.Nil(t, cached)
+}
+
+// TestCacheReplica validates that reads go to the replica and writes to the
+// primary, with reads falling back to the primary while the replica fails
+func TestCacheReplica(t *testing.T) {
+	primaryServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer primaryServer.Close()
+	replicaServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer replicaServer.Close()
+	primary := redis.NewClient(&redis.Options{Addr: primaryServer.Addr()})
+	defer primary.Close()
+	replica := redis.NewClient(&redis.Options{Addr: replicaServer.Addr()})
+	defer replica.Close()
+	enrollmentCache := cache.NewEnrollmentCacheWithOptions(primary, cache.Options{Replica: replica})
+	ctx := context.Background()
+
+	enrollment := &models.Enrollment{ID: "replica-1", StudentID: "replica-student", CourseID: "replica-course"}
+	require.NoError(t, enrollmentCache.Set(ctx, enrollment))
+	require.NoError(t, enrollmentCache.SetList(ctx, []*models.Enrollment{enrollment}))
+	assert.ElementsMatch(t, []string{"enrollment:replica-1", "enrollments:all"}, primaryServer.Keys())
+	assert.Empty(t, replicaServer.Keys())
+
+	// Until the write replicates the replica still misses
+	cached, err := enrollmentCache.Get(ctx, enrollment.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	list, err := enrollmentCache.GetList(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, list)
+
+	// Replicate by hand
+	for _, key := range primaryServer.Keys() {
+		value, err := primaryServer.Get(key)
+		require.NoError(t, err)
+		require.NoError(t, replicaServer.Set(key, value))
+	}
+	primaryReads := primaryServer.CommandCount()
+	cached, err = enrollmentCache.Get(ctx, enrollment.ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, "replica-student", cached.StudentID)
+	list, err = enrollmentCache.GetList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, primaryReads, primaryServer.CommandCount())
+
+	// Deletes go to the primary; the replica serves the old entry until it catches up
+	require.NoError(t, enrollmentCache.Delete(ctx, enrollment.ID))
+	assert.Equal(t, []string{"enrollments:all"}, primaryServer.Keys())
+	cached, err = enrollmentCache.Get(ctx, enrollment.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, cached)
+
+	// A failing replica is bypassed rather than reported as an error
+	replicaServer.SetError("ERR replica unavailable")
+	cached, err = enrollmentCache.Get(ctx, enrollment.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	list, err = enrollmentCache.GetList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+// TestCacheCompression validates that values over the threshold are stored
+// gzipped and that both forms read back unchanged
+func TestCacheCompression(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	enrollmentCache := cache.NewEnrollmentCacheWithOptions(client, cache.Options{CompressThreshold: 512})
+	ctx := context.Background()
+
+	small := &models.Enrollment{ID: "small", StudentID: "student-small", CourseID: "course-small", Status: "active", Version: 1}
+	large := &models.Enrollment{ID: "large", StudentID: strings.Repeat("student-large-", 100), CourseID: "course-large", Status: "active", Version: 1}
+	require.NoError(t, enrollmentCache.Set(ctx, small))
+	require.NoError(t, enrollmentCache.Set(ctx, large))
+
+	raw, err := mr.Get("enrollment:small")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "{"), "small values are stored as plain JSON")
+	raw, err = mr.Get("enrollment:large")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "\x00gz"), "large values are stored compressed")
+	assert.Less(t, len(raw), len(large.StudentID))
+
+	for _, want := range []*models.Enrollment{small, large} {
+		cached, err := enrollmentCache.Get(ctx, want.ID)
+		require.NoError(t, err)
+		require.NotNil(t, cached)
+		assert.Equal(t, want.StudentID, cached.StudentID)
+		assert.Equal(t, want.CourseID, cached.CourseID)
+		assert.Equal(t, want.Version, cached.Version)
+	}
+
+	// Lists and warmed entries go through the same encoding
+	list := make([]*models.Enrollment, 50)
+	for i := range list {
+		list[i] = &models.Enrollment{ID: fmt.Sprintf("listed-%d", i), StudentID: fmt.Sprintf("student-%d", i), CourseID: "course-listed"}
+	}
+	require.NoError(t, enrollmentCache.SetList(ctx, list))
+	raw, err = mr.Get("enrollments:all")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "\x00gz"))
+	cachedList, err := enrollmentCache.GetList(ctx)
+	require.NoError(t, err)
+	require.Len(t, cachedList, len(list))
+	assert.Equal(t, "student-49", cachedList[49].StudentID)
+
+	warmed, err := enrollmentCache.WarmUp(ctx, []*models.Enrollment{large})
+	require.NoError(t, err)
+	assert.Equal(t, 1, warmed)
+	cached, err := enrollmentCache.Get(ctx, large.ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, large.StudentID, cached.StudentID)
+
+	// A cache with compression off still reads compressed entries
+	plainCache := cache.NewEnrollmentCache(client)
+	cached, err = plainCache.Get(ctx, large.ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, large.StudentID, cached.StudentID)
+	require.NoError(t, plainCache.Set(ctx, large))
+	raw, err = mr.Get("enrollment:large")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "{"))
+
+	// A corrupt compressed value is a miss-with-error, not a panic
+	require.NoError(t, mr.Set("enrollment:corrupt", "\x00gznot gzip"))
+	cached, err = enrollmentCache.Get(ctx, "corrupt")
+	assert.Error(t, err)
+	assert.Nil(t, cached)
+}
+
+// BenchmarkCacheDeleteMany compares invalidating 100 enrollments one Delete
+// at a time with a single DeleteMany
+func BenchmarkCacheDeleteMany(b *testing.B) {
+	mr, err := miniredis.Run()
+	require.NoError(b, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	enrollmentCache := cache.NewEnrollmentCache(client)
+	ctx := context.Background()
+
+	enrollments := make([]*models.Enrollment, 100)
+	ids := make([]string, len(enrollments))
+	for i := range enrollments {
+		enrollments[i] = &models.Enrollment{ID: fmt.Sprintf("bench-%d", i)}
+		ids[i] = enrollments[i].ID
+	}
+
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			enrollmentCache.WarmUp(ctx, enrollments)
+			b.StartTimer()
+			for _, id := range ids {
+				enrollmentCache.Delete(ctx, id)
+			}
+		}
+	})
+	b.Run("pipeline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			enrollmentCache.WarmUp(ctx, enrollments)
+			b.StartTimer()
+			enrollmentCache.DeleteMany(ctx, ids)
+		}
+	})
+}
+
+// TestCacheNamespace validates that namespaced caches sharing one Redis keep
+// their keys apart and only clear their own
+func TestCacheNamespace(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	prod := cache.NewEnrollmentCacheWithOptions(redisClient, cache.Options{Namespace: "prod"})
+	staging := cache.NewEnrollmentCacheWithOptions(redisClient, cache.Options{Namespace: "staging"})
+	plain := cache.NewEnrollmentCache(redisClient)
+
+	for name, c := range map[string]*cache.EnrollmentCache{"prod": prod, "staging": staging, "plain": plain} {
+		require.NoError(t, c.Set(context.Background(), &models.Enrollment{ID: "shared-id", StudentID: name}))
+		require.NoError(t, c.SetList(context.Background(), []*models.Enrollment{}))
+		require.NoError(t, c.SetIdempotent(context.Background(), "retry-1", name))
+	}
+
+	assert.True(t, mr.Exists("prod:enrollment:shared-id"))
+	assert.True(t, mr.Exists("staging:enrollment:shared-id"))
+	assert.True(t, mr.Exists("enrollment:shared-id"))
+	assert.True(t, mr.Exists("prod:enrollments:all"))
+	assert.True(t, mr.Exists("prod:idempotency:retry-1"))
+
+	fromProd, err := prod.Get(context.Background(), "shared-id")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", fromProd.StudentID)
+	fromStaging, err := staging.Get(context.Background(), "shared-id")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", fromStaging.StudentID)
+	id, err := staging.GetIdempotent(context.Background(), "retry-1")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", id)
+
+	removed, err := prod.Clear(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	assert.False(t, mr.Exists("prod:enrollment:shared-id"))
+	assert.False(t, mr.Exists("prod:enrollments:all"))
+	assert.True(t, mr.Exists("staging:enrollment:shared-id"))
+	assert.True(t, mr.Exists("staging:enrollments:all"))
+	assert.True(t, mr.Exists("enrollment:shared-id"))
+	assert.True(t, mr.Exists("enrollments:all"))
+
+	// An unprefixed cache does not reach into namespaced keys either
+	removed, err = plain.Clear(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	assert.True(t, mr.Exists("staging:enrollment:shared-id"))
+}
+
+// TestDegradedCache validates serving from the repository when Redis is down
+func TestDegradedCache(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "degraded-student",
+		"course_id":  "degraded-course",
+		"status":     "active",
+	})
+
+	resp, err := http.Get(server.URL + "/health/ready")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Take Redis down
+	mr.Close()
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "SKIP", resp.Header.Get("X-Cache-Status"))
+	assert.Equal(t, "true", resp.Header.Get("X-Cache-Degraded"))
+	var fetched models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.Equal(t, created.ID, fetched.ID)
+
+	// Readiness fails while liveness stays green
+	resp, err = http.Get(server.URL + "/health/ready")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/health")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+	var health healthBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	resp.Body.Close()
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Equal(t, "down", health.Checks["redis"])
+	assert.Equal(t, "ok", health.Checks["repository"])
+
+	resp, err = http.Get(server.URL + "/health/live")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+	resp.Body.Close()
+}
+
+// healthBody mirrors the GET /health response
+type healthBody struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// getHealth fetches GET /health and returns the status code and body
+func getHealth(t *testing.T, serverURL string) (int, healthBody) {
+	resp, err := http.Get(serverURL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var health healthBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	return resp.StatusCode, health
+}
+
+// TestHealthChecks validates per-component health reporting
+func TestHealthChecks(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	code, health := getHealth(t, server.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", health.Status)
+	assert.Equal(t, map[string]string{"repository": "ok", "redis": "ok"}, health.Checks)
+
+	// Without a cache Redis is not a dependency
+	noCache := setupTestServerWithoutCache(t)
+	defer noCache.Close()
+
+	code, health = getHealth(t, noCache.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "disabled", health.Checks["redis"])
+
+	// A store that can no longer reach its database is reported down
+	store, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "health.db"))
+	require.NoError(t, err)
+	sqliteServer := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+	defer sqliteServer.Close()
+
+	code, health = getHealth(t, sqliteServer.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", health.Checks["repository"])
+
+	require.NoError(t, store.Close())
+	code, health = getHealth(t, sqliteServer.URL)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Equal(t, "down", health.Checks["repository"])
+}
+
+// brokenSelfTestStore is a Store that is reachable but whose self-test
+// always fails, like a database that can be read but not written
+type brokenSelfTestStore struct {
+	*repository.EnrollmentRepository
+}
+
+func (brokenSelfTestStore) SelfTest(ctx context.Context) error {
+	return errors.New("disk is read-only")
+}
+
+// TestDeepHealthCheck validates that ?deep=true round-trips a record through
+// each store without the record showing up anywhere afterwards
+func TestDeepHealthCheck(t *testing.T) {
+	getDeepHealth := func(serverURL, deep string) (int, healthBody) {
+		resp, err := http.Get(serverURL + "/health?deep=" + deep)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var health healthBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		return resp.StatusCode, health
+	}
+
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "deep.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"file":   repository.NewFilePersistence(filepath.Join(t.TempDir(), "deep.json"), time.Hour),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+			defer server.Close()
+
+			createTestEnrollment(t, server.URL, map[string]interface{}{
+				"student_id": "deep-student",
+				"course_id":  "deep-course",
+				"status":     "pending",
+			})
+
+			for i := 0; i < 3; i++ {
+				code, health := getDeepHealth(server.URL, "true")
+				assert.Equal(t, http.StatusOK, code)
+				assert.Equal(t, "healthy", health.Status)
+				assert.Equal(t, "ok", health.Checks["repository"])
+			}
+
+			// Only the real enrollment remains, in every view of the store
+			all := store.Find(repository.EnrollmentFilter{IncludeDeleted: true})
+			require.Len(t, all, 1)
+			assert.Equal(t, "deep-student", all[0].StudentID)
+			assert.Len(t, store.GetAll(context.Background()), 1)
+			assert.Len(t, store.Recent(10), 1)
+			assert.Equal(t, 1, store.CountByStatus(repository.EnrollmentFilter{})["pending"])
+			assert.Empty(t, store.Search("health-check"))
+
+			var page enrollmentPage
+			resp, err := http.Get(server.URL + "/api/enrollments")
+			require.NoError(t, err)
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			resp.Body.Close()
+			assert.Equal(t, 1, page.Total)
+		})
+	}
+
+	// A reachable store that cannot round-trip a record fails only the deep check
+	broken := httptest.NewServer(app.NewApp(app.Config{Store: brokenSelfTestStore{repository.NewEnrollmentRepository()}}).Routes())
+	defer broken.Close()
+
+	code, health := getHealth(t, broken.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", health.Checks["repository"])
+
+	code, health = getDeepHealth(broken.URL, "false")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", health.Checks["repository"])
+
+	code, health = getDeepHealth(broken.URL, "true")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Equal(t, map[string]string{"repository": "fail", "redis": "disabled"}, health.Checks)
+
+	resp, err := http.Get(broken.URL + "/health?deep=maybe")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var errResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, models.CodeInvalidParameter, errResp.Code)
+}
+
+// TestIdempotencyKey validates that retried creates return the original record
+func TestIdempotencyKey(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	post := func(key, courseID string) models.Enrollment {
+		body, _ := json.Marshal(map[string]interface{}{
+			"student_id": "idem-student",
+			"course_id":  courseID,
+			"status":     "pending",
+		})
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/enrollments", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var created models.Enrollment
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		resp.Body.Close()
+		return created
+	}
+
+	first := post("retry-123", "idem-course")
+	second := post("retry-123", "idem-course")
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, cache.IdempotencyKeyTTL, mr.TTL(cache.IdempotencyKeyPrefix+"retry-123"))
+
+	// A different key creates a new enrollment
+	third := post("retry-456", "idem-course-2")
+	assert.NotEqual(t, first.ID, third.ID)
+
+	// No key behaves exactly as before
+	fourth := post("", "idem-course-3")
+	assert.NotEqual(t, first.ID, fourth.ID)
+
+	resp, err := http.Get(server.URL + "/api/enrollments?include_deleted=true")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 3, page.Total)
+}
+
+// TestIdempotencyKeyReservation validates that concurrent creates with one
+// Idempotency-Key make a single enrollment and that failed creates free the key
+func TestIdempotencyKeyReservation(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	post := func(key string, payload map[string]interface{}) (int, models.ErrorResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/enrollments", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var errResp models.ErrorResponse
+		if resp.StatusCode != http.StatusCreated {
+			json.NewDecoder(resp.Body).Decode(&errResp)
+		}
+		return resp.StatusCode, errResp
+	}
+
+	// Each request names its own course, so only the key stops duplicates
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status, errResp := post("concurrent-key", map[string]interface{}{
+				"student_id": "reserve-student",
+				"course_id":  fmt.Sprintf("reserve-course-%d", i),
+				"status":     "pending",
+			})
+			switch status {
+			case http.StatusCreated:
+				created.Add(1)
+			case http.StatusConflict:
+				assert.Equal(t, models.CodeIdempotencyInFlight, errResp.Code)
+			default:
+				t.Errorf("unexpected status %d", status)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, created.Load(), int32(1))
+
+	resp, err := http.Get(server.URL + "/api/enrollments?student_id=reserve-student")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 1, page.Total)
+
+	// A key reserved by a request that has not finished is in use
+	require.NoError(t, mr.Set(cache.IdempotencyKeyPrefix+"held-key", "pending:other-request"))
+	status, errResp := post("held-key", map[string]interface{}{
+		"student_id": "held-student",
+		"course_id":  "held-course",
+		"status":     "pending",
+	})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, models.CodeIdempotencyInFlight, errResp.Code)
+
+	// A create that fails releases its key for the retry
+	status, _ = post("retry-after-fix", map[string]interface{}{
+		"student_id": "fixed-student",
+		"status":     "pending",
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.False(t, mr.Exists(cache.IdempotencyKeyPrefix+"retry-after-fix"))
+
+	status, _ = post("retry-after-fix", map[string]interface{}{
+		"student_id": "fixed-student",
+		"course_id":  "fixed-course",
+		"status":     "pending",
+	})
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, cache.IdempotencyKeyTTL, mr.TTL(cache.IdempotencyKeyPrefix+"retry-after-fix"))
+}
+
+// TestDuplicateEnrollment validates the one-live-enrollment-per-course rule
+func TestDuplicateEnrollment(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	payload := map[string]interface{}{
+		"student_id": "dup-student",
+		"course_id":  "dup-course",
+		"status":     "pending",
+	}
+	first := createTestEnrollment(t, server.URL, payload)
+
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// Moving another enrollment onto the same course conflicts too
+	other := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "dup-student",
+		"course_id":  "other-course",
+		"status":     "pending",
+	})
+	resp = putEnrollment(t, server.URL, other.ID, map[string]interface{}{
+		"student_id": "dup-student",
+		"course_id":  "dup-course",
+		"status":     "pending",
+		"version":    other.Version,
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// A soft-deleted enrollment does not block re-enrollment
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+first.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	second := createTestEnrollment(t, server.URL, payload)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// ...but the deleted one cannot be restored while the new one is live
+	resp, err = http.Post(server.URL+"/api/enrollments/"+first.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestIDCollisionRetry validates that a taken generated ID is replaced
+// before creating an enrollment, and that only exhausting every attempt fails
+func TestIDCollisionRetry(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "collision.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			taken := uuid.New().String()
+			fresh := uuid.New().String()
+
+			// Hands out queued IDs, then the taken one forever
+			var queue []string
+			generated := 0
+			server := httptest.NewServer(app.NewApp(app.Config{
+				Store: store,
+				NewEnrollmentID: func() string {
+					generated++
+					if len(queue) == 0 {
+						return taken
+					}
+					id := queue[0]
+					queue = queue[1:]
+					return id
+				},
+			}).Routes())
+			defer server.Close()
+
+			first := createTestEnrollment(t, server.URL, map[string]interface{}{
+				"student_id": "collision-1",
+				"course_id":  "collision-course",
+				"status":     "pending",
+			})
+			assert.Equal(t, taken, first.ID)
+
+			// The taken ID is retried away
+			queue = []string{taken, fresh}
+			generated = 0
+			second := createTestEnrollment(t, server.URL, map[string]interface{}{
+				"student_id": "collision-2",
+				"course_id":  "collision-course",
+				"status":     "pending",
+			})
+			assert.Equal(t, fresh, second.ID)
+			assert.Equal(t, 2, generated)
+
+			// A student already in the course is a conflict, not a collision
+			queue = []string{uuid.New().String()}
+			generated = 0
+			body, _ := json.Marshal(map[string]interface{}{
+				"student_id": "collision-2",
+				"course_id":  "collision-course",
+				"status":     "pending",
+			})
+			resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+			resp.Body.Close()
+			assert.Equal(t, 1, generated)
+
+			// Colliding on every attempt gives up with a server error
+			generated = 0
+			body, _ = json.Marshal(map[string]interface{}{
+				"student_id": "collision-3",
+				"course_id":  "collision-course",
+				"status":     "pending",
+			})
+			resp, err = http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+			resp.Body.Close()
+			assert.Equal(t, handlers.MaxIDAttempts, generated)
+		})
+	}
+}
+
+// TestClientSuppliedID validates creating enrollments under a UUID chosen
+// by the client
+func TestClientSuppliedID(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	// postWithID creates an enrollment and returns the response status and error
+	postWithID := func(path, id, studentID string) (int, string) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"id":         id,
+			"student_id": studentID,
+			"course_id":  "supplied-course",
+			"status":     "pending",
+		})
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var errBody models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return resp.StatusCode, errBody.Error
+	}
+
+	// A supplied UUID is kept, in canonical lowercase form
+	id := uuid.New().String()
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"id":         strings.ToUpper(id),
+		"student_id": "supplied-1",
+		"course_id":  "supplied-course",
+		"status":     "pending",
+	})
+	assert.Equal(t, id, created.ID)
+
+	resp, err := http.Get(server.URL + "/api/enrollments/" + id)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Reusing it conflicts, on a dry run too
+	status, message := postWithID("/api/enrollments", id, "supplied-2")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "Enrollment ID already exists", message)
+	status, _ = postWithID("/api/enrollments?dry_run=true", id, "supplied-2")
+	assert.Equal(t, http.StatusConflict, status)
+
+	// ...even once the enrollment holding it is soft-deleted
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+id, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, _ = postWithID("/api/enrollments", id, "supplied-2")
+	assert.Equal(t, http.StatusConflict, status)
+
+	// An ID that is not a UUID is rejected
+	status, message = postWithID("/api/enrollments", "not-a-uuid", "supplied-3")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid enrollment id format", message)
+
+	// Without one an ID is still generated
+	generated := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "supplied-4",
+		"course_id":  "supplied-course",
+		"status":     "pending",
+	})
+	assert.NotEmpty(t, generated.ID)
+	assert.NotEqual(t, id, generated.ID)
+}
+
+// TestCourseName validates that an optional course_name is stored, cached,
+// listed and cleared by an update that omits it
+func TestCourseName(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "course-name.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+			defer mr.Close()
+			server := httptest.NewServer(app.NewApp(app.Config{
+				Store:       store,
+				RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+			}).Routes())
+			defer server.Close()
+
+			created := createTestEnrollment(t, server.URL, map[string]interface{}{
+				"student_id":  "named-1",
+				"course_id":   "db-101",
+				"course_name": "  Introduction to Databases ",
+				"status":      "pending",
+			})
+			assert.Equal(t, "Introduction to Databases", created.CourseName)
+
+			// Served the same from the repository and from the cache
+			for _, cacheStatus := range []string{"MISS", "HIT"} {
+				resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+				require.NoError(t, err)
+				assert.Equal(t, cacheStatus, resp.Header.Get("X-Cache-Status"))
+				var fetched models.Enrollment
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+				resp.Body.Close()
+				assert.Equal(t, "Introduction to Databases", fetched.CourseName)
+			}
+
+			resp, err := http.Get(server.URL + "/api/enrollments?course_id=db-101")
+			require.NoError(t, err)
+			var page struct {
+				Data []models.Enrollment `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			resp.Body.Close()
+			require.Len(t, page.Data, 1)
+			assert.Equal(t, "Introduction to Databases", page.Data[0].CourseName)
+
+			// An update without course_name clears it, and it is then omitted
+			body, _ := json.Marshal(map[string]interface{}{
+				"student_id": "named-1",
+				"course_id":  "db-101",
+				"status":     "active",
+				"version":    created.Version,
+			})
+			req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var raw map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&raw))
+			resp.Body.Close()
+			assert.NotContains(t, raw, "course_name")
+
+			resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+			require.NoError(t, err)
+			var fetched models.Enrollment
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+			resp.Body.Close()
+			assert.Empty(t, fetched.CourseName)
+
+			// Overlong names are rejected
+			body, _ = json.Marshal(map[string]interface{}{
+				"student_id":  "named-2",
+				"course_id":   "db-101",
+				"course_name": strings.Repeat("x", models.MaxCourseNameLength+1),
+				"status":      "pending",
+			})
+			resp, err = http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var validation models.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&validation))
+			resp.Body.Close()
+			assert.Equal(t, "course_name exceeds maximum length", validation.Error)
+		})
+	}
+}
+
+// TestRequestLogger validates the per-request JSON log line
+func TestRequestLogger(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	var logs bytes.Buffer
+	router := app.NewApp(app.Config{
+		RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		LogOutput:   &logs,
+	}).Routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/enrollments/00000000-0000-0000-0000-000000000000", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	var entry struct {
+		Method      string  `json:"method"`
+		Path        string  `json:"path"`
+		Status      int     `json:"status"`
+		DurationMs  float64 `json:"duration_ms"`
+		CacheStatus string  `json:"cache_status"`
+	}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, http.MethodGet, entry.Method)
+	assert.Equal(t, "/api/enrollments/00000000-0000-0000-0000-000000000000", entry.Path)
+	assert.Equal(t, http.StatusNotFound, entry.Status)
+	assert.GreaterOrEqual(t, entry.DurationMs, 0.0)
+	assert.Equal(t, rec.Header().Get("X-Cache-Status"), entry.CacheStatus)
+
+	// Routes outside /api have no cache status, and a plain Write logs 200
+	logs.Reset()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	var liveEntry map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &liveEntry))
+	assert.Equal(t, float64(http.StatusOK), liveEntry["status"])
+	assert.Equal(t, "/health/live", liveEntry["path"])
+	assert.NotContains(t, liveEntry, "cache_status")
+}
+
+// TestDebugBodies validates opt-in body logging, redaction and truncation
+func TestDebugBodies(t *testing.T) {
+	var logs bytes.Buffer
+	router := app.NewApp(app.Config{
+		DebugBodyOutput:   &logs,
+		DebugRedactFields: []string{"Course_Name"},
+	}).Routes()
+
+	body := `{"student_id": "debug-student", "course_id": "debug-course", "course_name": "Secret Seminar", "status": "active"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/enrollments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	// The handler still read the whole body
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created models.Enrollment
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "Secret Seminar", created.CourseName)
+
+	var entry struct {
+		Method            string `json:"method"`
+		Status            int    `json:"status"`
+		RequestID         string `json:"request_id"`
+		RequestBody       string `json:"request_body"`
+		RequestTruncated  bool   `json:"request_truncated"`
+		ResponseBody      string `json:"response_body"`
+		ResponseTruncated bool   `json:"response_truncated"`
+	}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, http.StatusCreated, entry.Status)
+	assert.Equal(t, rec.Header().Get(middleware.RequestIDHeader), entry.RequestID)
+	assert.Contains(t, entry.RequestBody, "debug-student")
+	assert.Contains(t, entry.RequestBody, `"course_name":"[REDACTED]"`)
+	assert.NotContains(t, entry.RequestBody, "Secret Seminar")
+	assert.Contains(t, entry.ResponseBody, created.ID)
+	assert.NotContains(t, entry.ResponseBody, "Secret Seminar")
+	assert.False(t, entry.RequestTruncated)
+	assert.False(t, entry.ResponseTruncated)
+
+	// The default list masks nested fields, and truncated bodies that no
+	// longer parse are still masked
+	logs.Reset()
+	echo := middleware.DebugBodies(&logs, 48, middleware.DefaultRedactedFields)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	body = `{"user": {"name": "ada", "Password": "hunter2"}, "token": "abc123", "note": "` + strings.Repeat("x", 100) + `"}`
+	rec = httptest.NewRecorder()
+	echo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+	assert.Equal(t, body, rec.Body.String())
+
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.True(t, entry.RequestTruncated)
+	assert.True(t, entry.ResponseTruncated)
+	assert.LessOrEqual(t, len(entry.RequestBody), 48)
+	assert.Contains(t, entry.ResponseBody, `"name": "ada"`)
+	for _, logged := range []string{entry.RequestBody, entry.ResponseBody} {
+		assert.NotContains(t, logged, "hunter2")
+		assert.NotContains(t, logged, "abc123")
+	}
+}
+
+// TestRequestID validates request ID generation, propagation and echoing
+func TestRequestID(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	// A generated ID is echoed back and included in error bodies
+	resp, err := http.Get(server.URL + "/api/enrollments/00000000-0000-0000-0000-000000000000")
+	require.NoError(t, err)
+	generated := resp.Header.Get("X-Request-ID")
+	_, err = uuid.Parse(generated)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
+
+	var errorBody map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+	resp.Body.Close()
+	assert.Equal(t, generated, errorBody["request_id"])
+
+	// An incoming ID is reused
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/health/live", nil)
+	req.Header.Set("X-Request-ID", "trace-abc-123")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "trace-abc-123", resp.Header.Get("X-Request-ID"))
+
+	// The ID is available to handlers through the context
+	var seen string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetRequestID(r.Context())
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotEmpty(t, seen)
+	assert.Equal(t, seen, rec.Header().Get("X-Request-ID"))
+}
+
+// TestCORS validates CORS headers and preflight handling
+func TestCORS(t *testing.T) {
+	router := app.NewApp(app.Config{}).Routes()
+
+	preflight := func(handler http.Handler, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/enrollments", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	handler := middleware.CORS([]string{"https://admin.example.com"})(router)
+
+	// Preflight short-circuits with 204 for an allowed origin
+	rec := preflight(handler, "https://admin.example.com")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), "PUT")
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), middleware.APIKeyHeader)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Unknown origins get no CORS headers
+	rec = preflight(handler, "https://evil.example.com")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Simple requests pass through with the origin header set
+	req := httptest.NewRequest(http.MethodGet, "/api/enrollments", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), middleware.RetryAfterHeader)
+
+	// Wildcard allows any origin without credentials
+	rec = preflight(middleware.CORS([]string{"*"})(router), "https://any.example.com")
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Wildcard is ignored once credentials are allowed
+	credentialed := middleware.CORSWithCredentials([]string{"*", "https://admin.example.com"})(router)
+	rec = preflight(credentialed, "https://any.example.com")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	rec = preflight(credentialed, "https://admin.example.com")
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+// TestRateLimit validates that clients over their burst are throttled
+func TestRateLimit(t *testing.T) {
+	const burst = 5
+	handler := middleware.RateLimit(0.001, burst)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/enrollments", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// N requests within the burst succeed, request N+1 is throttled
+	for i := 0; i < burst; i++ {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:1234", "").Code)
+	}
+	rec := send("10.0.0.1:5678", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1000", rec.Header().Get("Retry-After"), "whole seconds until the next token at 0.001 rps")
+	assert.Contains(t, rec.Body.String(), `"code":"RATE_LIMITED"`)
+
+	// Other clients have their own buckets
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:1234", "").Code)
+
+	// An API key is limited separately from the IP it arrives from
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:1234", "dashboard-key").Code)
+
+	// Throttled clients are turned away before their body is validated
+	server := httptest.NewServer(app.NewApp(app.Config{RateLimitRPS: 0.001, RateLimitBurst: 1, ValidateRequests: true}).Routes())
+	defer server.Close()
+	for _, want := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", strings.NewReader(`{"status": 42}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, want, resp.StatusCode)
+	}
+}
+
+// TestRetryAfter validates that throttled and unavailable responses tell
+// clients when to retry, and that the client exposes it
+func TestRetryAfter(t *testing.T) {
+	server := httptest.NewServer(app.NewApp(app.Config{RateLimitRPS: 0.5, RateLimitBurst: 1}).Routes())
+	defer server.Close()
+
+	ctx := context.Background()
+	c := client.New(server.URL+"/", server.Client())
+	_, err := c.List(ctx, client.ListOptions{})
+	require.NoError(t, err)
+
+	_, err = c.List(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, models.CodeRateLimited, apiErr.Code)
+	assert.Equal(t, 2*time.Second, apiErr.RetryAfter, "a token every 2s at 0.5 rps")
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	// A 503 that retrying cannot fix carries no Retry-After
+	noCache := setupTestServerWithoutCache(t)
+	defer noCache.Close()
+	resp, err := http.Get(noCache.URL + "/api/cache/stats")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	// Nor do successful responses
+	resp, err = http.Get(noCache.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	_, err = client.New(noCache.URL+"/", noCache.Client()).Get(ctx, uuid.New().String())
+	require.ErrorAs(t, err, &apiErr)
+	assert.Zero(t, apiErr.RetryAfter)
+}
+
+// TestEnrollmentETag validates ETag generation and conditional GETs
+func TestEnrollmentETag(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "etag-student",
+		"course_id":  "etag-course",
+		"status":     "pending",
+	})
+	url := server.URL + "/api/enrollments/" + created.ID
+
+	get := func(ifNoneMatch string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// MISS and HIT produce the same ETag
+	miss := get("")
+	assert.Equal(t, "MISS", miss.Header.Get("X-Cache-Status"))
+	etag := miss.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	hit := get("")
+	assert.Equal(t, "HIT", hit.Header.Get("X-Cache-Status"))
+	assert.Equal(t, etag, hit.Header.Get("ETag"))
+
+	// A matching If-None-Match returns 304 with no body
+	notModified := get(etag)
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+	assert.Equal(t, etag, notModified.Header.Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, get(`"other", W/`+etag).StatusCode)
+
+	// An update changes the ETag
+	resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "etag-student",
+		"course_id":  "etag-course",
+		"status":     "active",
+		"version":    1,
+	})
+	resp.Body.Close()
+
+	changed := get(etag)
+	assert.Equal(t, http.StatusOK, changed.StatusCode)
+	assert.NotEqual(t, etag, changed.Header.Get("ETag"))
+}
+
+// TestHeadEnrollment validates the body-less existence check and its cache
+// and conditional headers
+func TestHeadEnrollment(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "head-student",
+		"course_id":  "head-course",
+		"status":     "pending",
+	})
+
+	head := func(id, ifNoneMatch string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodHead, server.URL+"/api/enrollments/"+id, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	// The first check loads and caches the enrollment, the second hits the cache
+	resp, body := head(created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.Empty(t, body)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+
+	resp, body = head(created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "HIT", resp.Header.Get("X-Cache-Status"))
+	assert.Equal(t, "redis", resp.Header.Get("X-Cache-Tier"))
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+	assert.Empty(t, body)
+
+	// The ETag matches GET's and a matching If-None-Match gives 304
+	get, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	get.Body.Close()
+	assert.Equal(t, etag, get.Header.Get("ETag"))
+	resp, _ = head(created.ID, etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	// Missing, deleted and malformed IDs
+	resp, body = head(uuid.NewString(), "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("X-Cache-Status"))
+	assert.Empty(t, body)
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	del, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	del.Body.Close()
+	resp, _ = head(created.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = head("not-a-uuid", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestLastModified validates the Last-Modified header and If-Modified-Since
+func TestLastModified(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "modified-student",
+		"course_id":  "modified-course",
+		"status":     "pending",
+	})
+	url := server.URL + "/api/enrollments/" + created.ID
+
+	get := func(headers map[string]string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Last-Modified is UpdatedAt in RFC 1123 GMT, on both MISS and HIT
+	miss := get(nil)
+	lastModified := miss.Header.Get("Last-Modified")
+	assert.Equal(t, created.UpdatedAt.UTC().Format(http.TimeFormat), lastModified)
+	assert.True(t, strings.HasSuffix(lastModified, " GMT"))
+	hit := get(nil)
+	assert.Equal(t, "HIT", hit.Header.Get("X-Cache-Status"))
+	assert.Equal(t, lastModified, hit.Header.Get("Last-Modified"))
+
+	// Unchanged since If-Modified-Since returns 304
+	modifiedAt, err := http.ParseTime(lastModified)
+	require.NoError(t, err)
+	notModified := get(map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+	assert.Equal(t, lastModified, notModified.Header.Get("Last-Modified"))
+	later := modifiedAt.Add(time.Hour).Format(http.TimeFormat)
+	assert.Equal(t, http.StatusNotModified, get(map[string]string{"If-Modified-Since": later}).StatusCode)
+
+	// Modified since an earlier time returns 200
+	earlier := modifiedAt.Add(-time.Second).Format(http.TimeFormat)
+	assert.Equal(t, http.StatusOK, get(map[string]string{"If-Modified-Since": earlier}).StatusCode)
+
+	// Malformed dates are ignored, and If-None-Match takes precedence
+	assert.Equal(t, http.StatusOK, get(map[string]string{"If-Modified-Since": "yesterday"}).StatusCode)
+	assert.Equal(t, http.StatusOK, get(map[string]string{
+		"If-None-Match":     `"stale"`,
+		"If-Modified-Since": lastModified,
+	}).StatusCode)
+}
+
+// TestClient validates the Go client against a live server
+func TestClient(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	c := client.New(server.URL+"/", server.Client())
+
+	created, err := c.Create(ctx, models.Enrollment{StudentID: "client-student", CourseID: "client-course", Status: "Pending"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "pending", created.Status)
+	assert.Equal(t, 1, created.Version)
+
+	got, err := c.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+
+	// A supplied ID is sent on create
+	suppliedID := uuid.NewString()
+	supplied, err := c.Create(ctx, models.Enrollment{ID: suppliedID, StudentID: "client-student-2", CourseID: "client-course", Status: "active"})
+	require.NoError(t, err)
+	assert.Equal(t, suppliedID, supplied.ID)
+	page, err := c.List(ctx, client.ListOptions{CourseID: "client-course", Sort: "status", Order: "asc", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, 1, page.Limit)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "active", page.Data[0].Status)
+
+	got.Status = "active"
+	updated, err := c.Update(ctx, got.ID, *got)
+	require.NoError(t, err)
+	assert.Equal(t, "active", updated.Status)
+	assert.Equal(t, 2, updated.Version)
+
+	// A stale version is a conflict
+	_, err = c.Update(ctx, got.ID, *got)
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	// Validation failures carry the field errors
+	_, err = c.Create(ctx, models.Enrollment{CourseID: "client-course", Status: "active"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, models.CodeValidationFailed, apiErr.Code)
+	assert.NotEmpty(t, apiErr.Errors)
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	require.NoError(t, c.Delete(ctx, created.ID))
+	_, err = c.Get(ctx, created.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.ErrorIs(t, c.Delete(ctx, created.ID), client.ErrNotFound)
+
+	page, err = c.List(ctx, client.ListOptions{CourseID: "client-course", IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+
+	// Every call honors its context
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.Get(cancelled, created.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestSorting validates the sort and order query parameters
+func TestSorting(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	statuses := []string{"active", "pending", "completed"}
+	ids := make([]string, 0, len(statuses))
+	for i, status := range statuses {
+		created := createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": fmt.Sprintf("sort-student-%d", i),
+			"course_id":  "sort-course",
+			"status":     status,
+		})
+		ids = append(ids, created.ID)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	list := func(query string) []string {
+		resp, err := http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page enrollmentPage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		got := make([]string, 0, len(page.Data))
+		for _, enrollment := range page.Data {
+			got = append(got, enrollment.ID)
+		}
+		return got
+	}
+
+	// Default order is descending
+	assert.Equal(t, []string{ids[2], ids[1], ids[0]}, list("sort=created_at"))
+	assert.Equal(t, []string{ids[0], ids[1], ids[2]}, list("sort=created_at&order=asc"))
+	assert.Equal(t, []string{ids[0], ids[2], ids[1]}, list("sort=status&order=asc"))
+	assert.Equal(t, []string{ids[1], ids[2], ids[0]}, list("sort=status"))
+
+	// Sorting applies before pagination
+	assert.Equal(t, []string{ids[1]}, list("sort=created_at&limit=1&offset=1"))
+
+	for _, query := range []string{"sort=student_id", "sort=status&order=sideways"} {
+		resp, err := http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		resp.Body.Close()
+	}
+}
+
+// TestGetAllDeterministic validates that GetAll returns a stable order
+func TestGetAllDeterministic(t *testing.T) {
+	repo := repository.NewEnrollmentRepository()
+
+	// Shared timestamps force the ID tie-break to decide the order
+	createdAt := time.Now()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, repo.Create(context.Background(), &models.Enrollment{
+			ID:        fmt.Sprintf("det-%02d", (i*7)%20),
+			StudentID: fmt.Sprintf("det-student-%d", i),
+			CourseID:  "det-course",
+			Status:    "pending",
+			CreatedAt: createdAt.Add(time.Duration(i%4) * time.Second),
+		}))
+	}
+
+	first := repo.GetAll(context.Background())
+	require.Len(t, first, 20)
+	for i := 1; i < len(first); i++ {
+		prev, curr := first[i-1], first[i]
+		if prev.CreatedAt.Equal(curr.CreatedAt) {
+			assert.Less(t, prev.ID, curr.ID)
+		} else {
+			assert.True(t, prev.CreatedAt.Before(curr.CreatedAt))
+		}
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		assert.Equal(t, first, repo.GetAll(context.Background()))
+	}
+}
+
+// TestCountEnrollments validates the enrollment count endpoint
+func TestCountEnrollments(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	for i, status := range []string{"pending", "active", "active", "completed"} {
+		createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": fmt.Sprintf("count-student-%d", i),
+			"course_id":  "count-course",
+			"status":     status,
+		})
+	}
+
+	count := func(query string) (int, map[string]int) {
+		resp, err := http.Get(server.URL + "/api/enrollments/count?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body struct {
+			Total    int            `json:"total"`
+			ByStatus map[string]int `json:"by_status"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body.Total, body.ByStatus
+	}
+
+	total, byStatus := count("")
+	assert.Equal(t, 4, total)
+	assert.Equal(t, map[string]int{"pending": 1, "active": 2, "completed": 1, "waitlisted": 0}, byStatus)
+
+	total, byStatus = count("status=active")
+	assert.Equal(t, 2, total)
+	assert.Equal(t, map[string]int{"active": 2}, byStatus)
+
+	total, byStatus = count("student_id=count-student-0")
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 0, byStatus["active"])
+
+	resp, err := http.Get(server.URL + "/api/enrollments/count?status=unknown")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestDateRangeFilter validates the from and to query parameters
+func TestDateRangeFilter(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	dates := []string{"2024-01-15T09:00:00Z", "2024-02-15T09:00:00Z", "2024-03-15T09:00:00Z"}
+	for i, date := range dates {
+		createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id":      fmt.Sprintf("range-student-%d", i),
+			"course_id":       "range-course",
+			"status":          "pending",
+			"enrollment_date": date,
+		})
+	}
+
+	total := func(query string) int {
+		resp, err := http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page enrollmentPage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		return page.Total
+	}
+
+	assert.Equal(t, 3, total(""))
+	assert.Equal(t, 2, total("from=2024-02-01T00:00:00Z"))
+	assert.Equal(t, 1, total("to=2024-01-31T00:00:00Z"))
+	assert.Equal(t, 1, total("from=2024-02-01T00:00:00Z&to=2024-02-28T00:00:00Z"))
+	// Bounds are inclusive
+	assert.Equal(t, 1, total("from=2024-02-15T09:00:00Z&to=2024-02-15T09:00:00Z"))
+
+	for query, message := range map[string]string{
+		"from=yesterday": "from must be an RFC3339 timestamp",
+		"to=2024-13-01":  "to must be an RFC3339 timestamp",
+		"from=2024-03-01T00:00:00Z&to=2024-02-01T00:00:00Z": "from must not be after to",
+	} {
+		resp, err := http.Get(server.URL + "/api/enrollments?" + query)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+
+		var errorBody map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+		resp.Body.Close()
+		assert.Equal(t, message, errorBody["error"])
+	}
+}
+
+// TestInvalidIDFormat validates that malformed {id} values are rejected with 400
+func TestInvalidIDFormat(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"student_id": "id-student",
+		"course_id":  "id-course",
+		"status":     "pending",
+	})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/enrollments/not-a-uuid"},
+		{http.MethodPut, "/api/enrollments/not-a-uuid"},
+		{http.MethodDelete, "/api/enrollments/not-a-uuid"},
+		{http.MethodPost, "/api/enrollments/12345/restore"},
+		{http.MethodGet, "/api/enrollments/12345/history"},
+		{http.MethodGet, "/api/enrollments/12345/grades"},
+	}
+	for _, tc := range requests {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, tc.method+" "+tc.path)
+
+		var errorBody map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+		resp.Body.Close()
+		assert.Equal(t, "invalid enrollment id format", errorBody["error"])
+	}
+
+	// Well-formed IDs still reach the not-found path
+	resp, err := http.Get(server.URL + "/api/enrollments/" + uuid.New().String())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestUnknownFields validates that unrecognised JSON fields are rejected
+func TestUnknownFields(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	decodeError := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var errorBody map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+		return errorBody["error"]
+	}
+
+	// Misspelled field on create
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json",
+		bytes.NewBufferString(`{"studnet_id": "s1", "course_id": "c1", "status": "pending"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "unknown field: studnet_id", decodeError(resp))
+
+	// Extra field on update
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "unknown-student",
+		"course_id":  "unknown-course",
+		"status":     "pending",
+	})
+	resp = putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "unknown-student",
+		"course_id":  "unknown-course",
+		"status":     "active",
+		"priority":   "high",
+	})
+	assert.Equal(t, "unknown field: priority", decodeError(resp))
+
+	// Malformed JSON keeps the generic message
+	resp, err = http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBufferString(`{"student_id":`))
+	require.NoError(t, err)
+	assert.Equal(t, "Invalid request payload", decodeError(resp))
+}
+
+// TestBodyLimit validates that oversized request bodies are rejected with 413
+func TestBodyLimit(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	oversized := `{"student_id": "` + strings.Repeat("x", int(middleware.DefaultMaxBodyBytes)) + `", "course_id": "c1", "status": "pending"}`
+
+	assertTooLarge := func(resp *http.Response) {
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+		var errorBody map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+		assert.Equal(t, "Request body too large", errorBody["error"])
+		assert.Equal(t, "BODY_TOO_LARGE", errorBody["code"])
+		assert.Equal(t, resp.Header.Get("X-Request-ID"), errorBody["request_id"])
+	}
+
+	// Declared Content-Length over the limit is rejected before the handler
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json", strings.NewReader(oversized))
+	require.NoError(t, err)
+	assertTooLarge(resp)
+
+	// A chunked body without a length is cut off while decoding
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/enrollments/bulk", io.MultiReader(strings.NewReader("["+oversized+"]")))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assertTooLarge(resp)
+
+	// ...or while checking it against the schema when validation is on
+	validated := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true}).Routes())
+	defer validated.Close()
+	req, _ = http.NewRequest(http.MethodPost, validated.URL+"/api/enrollments", io.MultiReader(strings.NewReader(oversized)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assertTooLarge(resp)
+
+	// Bodies within the limit are unaffected
+	createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "limit-student",
+		"course_id":  "limit-course",
+		"status":     "pending",
+	})
+}
+
+// TestRequireJSON validates that write bodies not declared as JSON are
+// rejected with 415
+func TestRequireJSON(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "json-student",
+		"course_id":  "json-course",
+		"status":     "pending",
+	})
+
+	send := func(method, path, contentType, body string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// A form-encoded create never reaches the decoder
+	resp := send(http.MethodPost, "/api/enrollments", "application/x-www-form-urlencoded", "student_id=json-form&course_id=json-course&status=pending")
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	var errorBody models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorBody))
+	resp.Body.Close()
+	assert.Equal(t, "Content-Type must be application/json", errorBody.Error)
+	assert.NotEmpty(t, errorBody.RequestID)
+
+	// A body without a Content-Type is rejected on PUT too
+	resp = send(http.MethodPut, "/api/enrollments/"+created.ID, "", `{"student_id": "json-student", "course_id": "json-course", "status": "active", "version": 1}`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp.Body.Close()
+
+	// A charset parameter is allowed
+	resp = send(http.MethodPost, "/api/enrollments", "application/json; charset=utf-8", `{"student_id": "json-charset", "course_id": "json-course", "status": "pending"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	// Writes without a body need no Content-Type
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp = send(http.MethodPost, "/api/enrollments/"+created.ID+"/restore", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestTracing validates server and child spans and traceparent propagation
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "trace-student",
+		"course_id":  "trace-course",
+		"status":     "pending",
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/enrollments/"+created.ID, nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+
+	serverSpan, ok := spans["GET /api/enrollments/{id}"]
+	require.True(t, ok, "server span continues the incoming trace")
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+
+	attributes := make(map[string]string)
+	for _, kv := range serverSpan.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, created.ID, attributes["enrollment.id"])
+	assert.Equal(t, "MISS", attributes["cache.status"])
+	assert.Equal(t, "200", attributes["http.response.status_code"])
+
+	for _, name := range []string{"cache.Get", "repository.GetByID", "cache.Set"} {
+		span, ok := spans[name]
+		require.True(t, ok, name)
+		assert.Equal(t, serverSpan.SpanContext().SpanID(), span.Parent().SpanID(), name)
+	}
+}
+
+// TestAppIsolation validates that separate apps do not share state
+func TestAppIsolation(t *testing.T) {
+	first := httptest.NewServer(app.NewApp(app.Config{}).Routes())
+	defer first.Close()
+	second := httptest.NewServer(app.NewApp(app.Config{}).Routes())
+	defer second.Close()
+
+	created := createTestEnrollment(t, first.URL, map[string]interface{}{
+		"student_id": "isolated-student",
+		"course_id":  "isolated-course",
+		"status":     "pending",
+	})
+
+	resp, err := http.Get(first.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Get(second.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// failingStore is a Store whose lookups always fail, used to check that
+// handlers only depend on the repository.Store interface
+type failingStore struct {
+	*repository.EnrollmentRepository
+}
+
+func (failingStore) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	return nil, errors.New("store unavailable")
+}
+
+// TestCustomStore validates that an alternative Store can back the app
+func TestCustomStore(t *testing.T) {
+	store := failingStore{repository.NewEnrollmentRepository()}
+	server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "store-student",
+		"course_id":  "store-course",
+		"status":     "pending",
+	})
+	assert.True(t, store.ExistsForStudentCourse("store-student", "store-course"))
+
+	resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestGetAllSnapshot validates that GetAll returns copies: readers modifying
+// them neither change stored data nor race with concurrent updates. Run it
+// with -race to catch shared pointers.
+func TestGetAllSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewEnrollmentRepository()
+	now := time.Now()
+	enrollment := &models.Enrollment{
+		ID:        uuid.New().String(),
+		StudentID: "snapshot-student",
+		CourseID:  "snapshot-course",
+		Status:    "completed",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, store.Create(ctx, enrollment))
+	id := enrollment.ID
+	completedAt := *enrollment.CompletedAt
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			update := &models.Enrollment{StudentID: "snapshot-student", CourseID: "snapshot-course", Status: "completed"}
+			assert.NoError(t, store.Update(ctx, id, update))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			for _, snapshot := range store.GetAll(ctx) {
+				_, err := json.Marshal(snapshot)
+				assert.NoError(t, err)
+				snapshot.Status = "pending"
+				snapshot.Version = 0
+				*snapshot.CompletedAt = time.Time{}
+			}
+		}
+	}()
+	wg.Wait()
+
+	stored, err := store.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", stored.Status)
+	assert.Equal(t, 201, stored.Version)
+	require.NotNil(t, stored.CompletedAt)
+	assert.True(t, completedAt.Equal(*stored.CompletedAt))
+}
+
+// TestGetByIDCopy validates that modifying an enrollment returned by GetByID
+// leaves the stored record unchanged on both backends
+func TestGetByIDCopy(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "copy.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			enrollment := &models.Enrollment{
+				ID:             uuid.New().String(),
+				StudentID:      "copy-student",
+				CourseID:       "copy-course",
+				Status:         "completed",
+				EnrollmentDate: now,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			require.NoError(t, store.Create(ctx, enrollment))
+
+			fetched, err := store.GetByID(ctx, enrollment.ID)
+			require.NoError(t, err)
+			require.NotNil(t, fetched.CompletedAt)
+			completedAt := *fetched.CompletedAt
+
+			fetched.Status = "pending"
+			fetched.CourseID = "other-course"
+			fetched.Version = 99
+			*fetched.CompletedAt = time.Time{}
+			fetched.DeletedAt = &now
+
+			stored, err := store.GetByID(ctx, enrollment.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "completed", stored.Status)
+			assert.Equal(t, "copy-course", stored.CourseID)
+			assert.Equal(t, 1, stored.Version)
+			assert.Nil(t, stored.DeletedAt)
+			require.NotNil(t, stored.CompletedAt)
+			assert.True(t, completedAt.Equal(*stored.CompletedAt))
+		})
+	}
+}
+
+// TestResetStore validates the admin reset guard and that a reset empties
+// storage and the cache
+func TestResetStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	newServer := func(cfg app.Config) (*httptest.Server, *app.App) {
+		cfg.RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		application := app.NewApp(cfg)
+		return httptest.NewServer(application.Routes()), application
+	}
+	reset := func(url, key string) (int, map[string]interface{}) {
+		req, err := http.NewRequest(http.MethodDelete, url+"/api/enrollments/all", nil)
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	// Without a configured key the endpoint is disabled
+	disabled, _ := newServer(app.Config{})
+	code, _ := reset(disabled.URL, "anything")
+	disabled.Close()
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// Production refuses to reset unless explicitly allowed
+	production, _ := newServer(app.Config{AdminAPIKey: "admin-key", Env: "production"})
+	code, body := reset(production.URL, "admin-key")
+	production.Close()
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body["error"], "production")
+
+	server, application := newServer(app.Config{AdminAPIKey: "admin-key", Env: "production", AllowProductionReset: true})
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "reset-student",
+		"course_id":  "reset-course",
+		"status":     "active",
+	})
+	deleted := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "reset-student-2",
+		"course_id":  "reset-course",
+		"status":     "pending",
+	})
+	req, err := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+deleted.ID, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NoError(t, application.Grades.Create(&models.Grade{ID: "reset-grade", EnrollmentID: created.ID, Score: 80}))
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	resp.Body.Close()
+	cached, err := application.Cache.Get(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+
+	for _, key := range []string{"", "wrong-key"} {
+		code, _ = reset(server.URL, key)
+		assert.Equal(t, http.StatusUnauthorized, code, "key %q", key)
+	}
+	assert.Len(t, application.Enrollments.GetAll(context.Background()), 1)
+
+	// Soft-deleted enrollments are removed too
+	code, body = reset(server.URL, "admin-key")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2.0, body["deleted"])
+	assert.Equal(t, 1.0, body["grades_deleted"])
+	assert.Greater(t, body["cache_keys_flushed"], 0.0)
+
+	cached, err = application.Cache.Get(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	_, err = application.Enrollments.Restore(deleted.ID)
+	assert.Equal(t, repository.ErrNotFound, err)
+	assert.Empty(t, application.Grades.GetByEnrollment(created.ID))
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestRecomputeGrades validates the background job that re-derives letter
+// grades from the configured scale
+func TestRecomputeGrades(t *testing.T) {
+	application := app.NewApp(app.Config{AdminAPIKey: "admin-key", Env: "production"})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	adminRequest := func(method, path, key string) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp, body
+	}
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "recompute-student",
+		"course_id":  "recompute-course",
+		"status":     "completed",
+	})
+
+	// More grades than one batch, every other one with a stale letter
+	total := handlers.RecomputeBatchSize + 50
+	for i := 0; i < total; i++ {
+		letter := "A"
+		if i%2 == 0 {
+			letter = "F"
+		}
+		require.NoError(t, application.Grades.Create(&models.Grade{
+			ID:           fmt.Sprintf("recompute-%03d", i),
+			EnrollmentID: created.ID,
+			Score:        95,
+			LetterGrade:  letter,
+		}))
+	}
+
+	resp, _ := adminRequest(http.MethodPost, "/api/admin/recompute-grades", "wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Recomputing is allowed in production, unlike a reset
+	resp, job := adminRequest(http.MethodPost, "/api/admin/recompute-grades", "admin-key")
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, handlers.JobTypeRecomputeGrades, job["type"])
+	jobPath := "/api/admin/jobs/" + job["id"].(string)
+	assert.Equal(t, jobPath, resp.Header.Get("Location"))
+
+	require.Eventually(t, func() bool {
+		_, job = adminRequest(http.MethodGet, jobPath, "admin-key")
+		return job["status"] == jobs.StateCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(total), job["processed"])
+	assert.Equal(t, float64(total/2), job["updated"])
+	assert.Equal(t, 1.0, job["attempts"])
+	assert.NotEmpty(t, job["finished_at"])
+
+	for _, grade := range application.Grades.GetByEnrollment(created.ID) {
+		assert.Equal(t, "A", grade.LetterGrade, grade.ID)
+	}
+
+	resp, _ = adminRequest(http.MethodGet, "/api/admin/jobs/"+uuid.New().String(), "admin-key")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestJobQueue validates background job retries, backoff and statuses
+func TestJobQueue(t *testing.T) {
+	queue := jobs.NewQueueWithRetry(1, 3, 20*time.Millisecond)
+	defer queue.Close()
+
+	waitFinished := func(id string) jobs.Status {
+		var status jobs.Status
+		require.Eventually(t, func() bool {
+			status, _ = queue.Status(id)
+			return status.State == jobs.StateCompleted || status.State == jobs.StateFailed
+		}, 2*time.Second, 5*time.Millisecond)
+		return status
+	}
+
+	// A job that fails twice succeeds on its third attempt, after backing off
+	var runs []time.Time
+	status, err := queue.Enqueue(jobs.Job{
+		Type: "flaky",
+		Run: func(ctx context.Context, progress *jobs.Progress) error {
+			runs = append(runs, time.Now())
+			progress.Add(2, 1)
+			if len(runs) < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StateQueued, status.State)
+	assert.Equal(t, "flaky", status.Type)
+
+	status = waitFinished(status.ID)
+	assert.Equal(t, jobs.StateCompleted, status.State)
+	assert.Equal(t, 3, status.Attempts)
+	assert.Empty(t, status.Error)
+	assert.Equal(t, 2, status.Processed, "progress restarts with each attempt")
+	assert.Equal(t, 1, status.Updated)
+	require.Len(t, runs, 3)
+	assert.GreaterOrEqual(t, runs[1].Sub(runs[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, runs[2].Sub(runs[1]), 40*time.Millisecond)
+
+	// A job that keeps failing is marked failed with its last error
+	status, err = queue.Enqueue(jobs.Job{
+		Type: "broken",
+		Run: func(ctx context.Context, progress *jobs.Progress) error {
+			return errors.New("always broken")
+		},
+	})
+	require.NoError(t, err)
+	status = waitFinished(status.ID)
+	assert.Equal(t, jobs.StateFailed, status.State)
+	assert.Equal(t, 3, status.Attempts)
+	assert.Equal(t, "always broken", status.Error)
+	assert.NotNil(t, status.FinishedAt)
+
+	_, exists := queue.Status(uuid.New().String())
+	assert.False(t, exists)
+
+	// A full queue refuses new jobs instead of blocking
+	release := make(chan struct{})
+	blocking := jobs.Job{Type: "blocking", Run: func(ctx context.Context, progress *jobs.Progress) error {
+		<-release
+		return nil
+	}}
+	for err == nil {
+		_, err = queue.Enqueue(blocking)
+	}
+	assert.Equal(t, jobs.ErrQueueFull, err)
+	close(release)
+
+	// After Close nothing more is accepted
+	queue.Close()
+	_, err = queue.Enqueue(blocking)
+	assert.Equal(t, jobs.ErrQueueClosed, err)
+}
+
+// TestOptimisticConcurrency validates version checks on updates
+func TestOptimisticConcurrency(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "occ-student",
+		"course_id":  "occ-course",
+		"status":     "pending",
+	})
+	assert.Equal(t, 1, created.Version)
+
+	payload := func(status string, version int) map[string]interface{} {
+		return map[string]interface{}{"student_id": "occ-student", "course_id": "occ-course", "status": status, "version": version}
+	}
+	decodeError := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body["error"]
+	}
+
+	// An update without a version is refused
+	resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "occ-student", "course_id": "occ-course", "status": "active",
+	})
+	assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+	assert.Equal(t, "version or If-Match header is required", decodeError(resp))
+
+	// Two clients read version 1; the first write wins, the second is stale
+	resp = putEnrollment(t, server.URL, created.ID, payload("active", 1))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var updated models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	resp.Body.Close()
+	assert.Equal(t, 2, updated.Version)
+
+	resp = putEnrollment(t, server.URL, created.ID, payload("completed", 1))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "version conflict", decodeError(resp))
+
+	// If-Match with the current ETag is accepted in place of a version
+	resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	etag := resp.Header.Get("ETag")
+	resp.Body.Close()
+
+	putIfMatch := func(ifMatch string) *http.Response {
+		body, _ := json.Marshal(map[string]interface{}{"student_id": "occ-student", "course_id": "occ-course", "status": "completed"})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp = putIfMatch(etag)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	resp.Body.Close()
+
+	// The old ETag is now stale
+	resp = putIfMatch(etag)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "version conflict", decodeError(resp))
+
+	// The cached copy reflects the latest version
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	var current models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&current))
+	resp.Body.Close()
+	assert.Equal(t, 3, current.Version)
+	assert.Equal(t, "completed", current.Status)
+
+	// Concurrent writers based on the same version: exactly one succeeds
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := putEnrollment(t, server.URL, created.ID, payload("completed", 3))
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded)
+}
+
+// countingNotifier counts the lifecycle events handlers raise
+type countingNotifier struct {
+	events atomic.Int32
+}
+
+func (n *countingNotifier) Notify(string, *models.Enrollment) {
+	n.events.Add(1)
+}
+
+// TestDryRun validates that ?dry_run=true checks creates and updates and
+// returns the would-be enrollment without storing, caching, auditing,
+// notifying or recording an idempotency key
+func TestDryRun(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	notifier := &countingNotifier{}
+	application := app.NewApp(app.Config{
+		RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		Notifier:    notifier,
+	})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+	ctx := context.Background()
+
+	send := func(method, path string, payload map[string]interface{}) (*http.Response, models.Enrollment) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "dry-run-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var enrollment models.Enrollment
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+		}
+		return resp, enrollment
+	}
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "dry-student",
+		"course_id":  "dry-course",
+		"status":     "pending",
+	})
+	// Cache the enrollment so a real write would have to invalidate it
+	resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	resp.Body.Close()
+	cachedKeys := mr.Keys()
+
+	// A valid create is previewed with a generated ID but not stored
+	resp, preview := send(http.MethodPost, "/api/enrollments?dry_run=true", map[string]interface{}{
+		"student_id": "dry-student-2", "course_id": "dry-course", "status": "active",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, preview.ID)
+	assert.NotEqual(t, created.ID, preview.ID)
+	assert.Equal(t, 1, preview.Version)
+	assert.Equal(t, "active", preview.Status)
+	assert.False(t, preview.CreatedAt.IsZero())
+	_, err = application.Enrollments.GetByID(ctx, preview.ID)
+	assert.Equal(t, repository.ErrNotFound, err)
+
+	// Invalid and duplicate creates fail as they would for real
+	resp, _ = send(http.MethodPost, "/api/enrollments?dry_run=true", map[string]interface{}{
+		"course_id": "dry-course", "status": "active",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = send(http.MethodPost, "/api/enrollments?dry_run=true", map[string]interface{}{
+		"student_id": "dry-student", "course_id": "dry-course", "status": "pending",
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// A valid update is previewed at the next version
+	resp, preview = send(http.MethodPut, "/api/enrollments/"+created.ID+"?dry_run=true", map[string]interface{}{
+		"student_id": "dry-student", "course_id": "dry-course", "status": "active", "version": 1,
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, created.ID, preview.ID)
+	assert.Equal(t, 2, preview.Version)
+	assert.Equal(t, "active", preview.Status)
+	assert.True(t, created.CreatedAt.Equal(preview.CreatedAt))
+
+	// Stale versions and disallowed transitions fail as they would for real
+	resp, _ = send(http.MethodPut, "/api/enrollments/"+created.ID+"?dry_run=true", map[string]interface{}{
+		"student_id": "dry-student", "course_id": "dry-course", "status": "active", "version": 3,
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = send(http.MethodPut, "/api/enrollments/"+created.ID+"?dry_run=true", map[string]interface{}{
+		"student_id": "dry-student", "course_id": "dry-course", "status": "waitlisted", "version": 1,
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = send(http.MethodPost, "/api/enrollments?dry_run=maybe", map[string]interface{}{
+		"student_id": "dry-student-3", "course_id": "dry-course", "status": "pending",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The store, cache, audit trail and webhooks saw none of it
+	stored, err := application.Enrollments.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Version)
+	assert.Equal(t, "pending", stored.Status)
+	assert.Len(t, application.Enrollments.GetAll(ctx), 1)
+	assert.Len(t, application.Audit.GetByEnrollment(created.ID), 1)
+	assert.Equal(t, int32(1), notifier.events.Load())
+	assert.Equal(t, cachedKeys, mr.Keys())
+	assert.False(t, mr.Exists(cache.IdempotencyKeyPrefix+"dry-run-key"))
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enrollments.db")
+	store, err := repository.NewSQLiteRepository(path)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "sqlite-student",
+		"course_id":  "sqlite-course",
+		"status":     "pending",
+	})
+
+	// Duplicate pair is rejected by the unique index
+	body, _ := json.Marshal(map[string]interface{}{
+		"student_id": "sqlite-student",
+		"course_id":  "sqlite-course",
+		"status":     "pending",
+	})
+	resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// Transitions are enforced
+	resp = putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "sqlite-student",
+		"course_id":  "sqlite-course",
+		"status":     "completed",
+		"version":    1,
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "sqlite-student",
+		"course_id":  "sqlite-course",
+		"status":     "active",
+		"version":    1,
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Soft delete and restore
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/api/enrollments/"+created.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	server.Close()
+	require.NoError(t, store.Close())
+
+	// Data survives reopening the database
+	reopened, err := repository.NewSQLiteRepository(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	enrollment, err := reopened.GetByID(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", enrollment.Status)
+	assert.Equal(t, created.CreatedAt.UnixNano(), enrollment.CreatedAt.UnixNano())
+	assert.Equal(t, 2, enrollment.Version)
+
+	// Stale versions are rejected by the compare-and-swap
+	stale := *enrollment
+	stale.Version = 1
+	assert.Equal(t, repository.ErrVersionConflict, reopened.Update(context.Background(), created.ID, &stale))
+
+	counts := reopened.CountByStatus(repository.EnrollmentFilter{})
+	assert.Equal(t, 1, counts["active"])
+	assert.Equal(t, 0, counts["pending"])
+	assert.Len(t, reopened.Find(repository.EnrollmentFilter{StudentID: "sqlite-student"}), 1)
+
+	updated, err := reopened.UpdateStatusForCourse("sqlite-course", "active", "completed")
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, 3, updated[0].Version)
+	enrollment, err = reopened.GetByID(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", enrollment.Status)
+	assert.Equal(t, 3, enrollment.Version)
+	require.NotNil(t, enrollment.CompletedAt)
+	assert.Equal(t, updated[0].CompletedAt.UnixNano(), enrollment.CompletedAt.UnixNano())
+
+	deleted, err := reopened.DeleteWhere(repository.EnrollmentFilter{CourseID: "sqlite-course", Status: "completed"})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, created.ID, deleted[0].ID)
+	_, err = reopened.GetByID(context.Background(), created.ID)
+	assert.Equal(t, repository.ErrNotFound, err)
+	deleted, err = reopened.DeleteWhere(repository.EnrollmentFilter{CourseID: "sqlite-course"})
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	removed, err := reopened.DeleteAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Empty(t, reopened.GetAll(context.Background()))
+}
+
+func TestFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enrollments.json")
+	store := repository.NewFilePersistence(path, 10*time.Millisecond)
+	require.NoError(t, store.Load())
+	store.Start()
+
+	server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+
+	kept := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "file-student",
+		"course_id":  "file-course",
+		"status":     "pending",
+	})
+	deleted := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "file-student",
+		"course_id":  "file-course-2",
+		"status":     "pending",
+	})
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+deleted.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The periodic snapshot picks up the writes without a shutdown
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), kept.ID) && strings.Contains(string(data), "deleted_at")
+	}, time.Second, 10*time.Millisecond)
+
+	server.Close()
+	require.NoError(t, store.Stop())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// A fresh store loads the snapshot, soft-deleted records included
+	reloaded := repository.NewFilePersistence(path, 0)
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, repository.DefaultSnapshotInterval, reloaded.Interval())
+
+	enrollment, err := reloaded.GetByID(context.Background(), kept.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "file-student", enrollment.StudentID)
+	assert.True(t, reloaded.ExistsForStudentCourse("file-student", "file-course"))
+
+	_, err = reloaded.GetByID(context.Background(), deleted.ID)
+	assert.Equal(t, repository.ErrNotFound, err)
+	restored, err := reloaded.Restore(deleted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "file-course-2", restored.CourseID)
+}
+
+// TestImportSeedFile validates importing enrollments from a JSON file into an
+// empty store, skipping invalid records
+func TestImportSeedFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "seed.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"id": "00000000-0000-4000-8000-0000000000a1", "student_id": "import-1", "course_id": "import-course",
+		 "status": " Active ", "created_at": "2026-01-05T09:00:00Z"},
+		{"student_id": "import-2", "course_id": "import-course", "status": "completed"},
+		{"student_id": "import-3", "course_id": "import-course", "status": "done"},
+		{"student_id": "import-4", "course_id": "import-course", "status": "active", "grade": 90},
+		{"id": "not-a-uuid", "student_id": "import-5", "course_id": "import-course", "status": "active"},
+		{"student_id": "import-1", "course_id": "import-course", "status": "pending"},
+		42
+	]`), 0o644))
+
+	application := app.NewApp(app.Config{})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	imported, err := application.ImportSeedFile(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+
+	resp, err := http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	require.Equal(t, 2, page.Total)
+
+	// The id, status and timestamps in the file are kept
+	first := page.Data[0]
+	assert.Equal(t, "00000000-0000-4000-8000-0000000000a1", first.ID)
+	assert.Equal(t, "active", first.Status)
+	assert.Equal(t, 1, first.Version)
+	assert.True(t, time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC).Equal(first.CreatedAt))
+	assert.True(t, first.CreatedAt.Equal(first.EnrollmentDate))
+	require.Len(t, application.Audit.GetByEnrollment(first.ID), 1)
+
+	second := page.Data[1]
+	assert.Equal(t, "import-2", second.StudentID)
+	_, err = uuid.Parse(second.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, second.CompletedAt)
+
+	// A store that already holds enrollments is left alone
+	imported, err = application.ImportSeedFile(ctx, path)
+	require.NoError(t, err)
+	assert.Zero(t, imported)
+
+	// Only an unreadable file or a non-array is an error
+	notArray := filepath.Join(dir, "object.json")
+	require.NoError(t, os.WriteFile(notArray, []byte(`{"student_id": "import-1"}`), 0o644))
+	for _, path := range []string{notArray, filepath.Join(dir, "missing.json")} {
+		_, err = app.NewApp(app.Config{}).ImportSeedFile(ctx, path)
+		assert.Error(t, err, path)
+	}
+}
+
+// TestConfigLoad validates environment defaults, overrides and errors
+func TestConfigLoad(t *testing.T) {
+	for _, name := range []string{"PORT", "REDIS_ADDR", "REDIS_REPLICA_ADDR", "REDIS_USERNAME", "REDIS_PASSWORD", "REDIS_DB",
+		"REDIS_POOL_SIZE", "REDIS_MIN_IDLE_CONNS", "REDIS_TLS", "CACHE_TTL", "CACHE_WARM_LIMIT", "CACHE_FALLBACK_SIZE", "CACHE_NAMESPACE", "CACHE_COMPRESS_THRESHOLD",
+		"STORAGE_BACKEND", "DATA_FILE", "SNAPSHOT_INTERVAL", "SQLITE_PATH", "MAX_BODY_BYTES",
+		"RATE_LIMIT_RPS", "RATE_LIMIT_BURST", "CORS_ALLOWED_ORIGINS", "CORS_ALLOW_CREDENTIALS", "VALIDATE_REQUESTS", "GRADE_SCALE", "PASSING_SCORE",
+		"CLAMP_PAGE_LIMIT", "ENV", "ADMIN_API_KEY", "ALLOW_PRODUCTION_RESET", "REQUEST_TIMEOUT", "SHUTDOWN_TIMEOUT", "PENDING_EXPIRY", "PENDING_EXPIRY_INTERVAL", "SEED_FILE",
+		"DEFAULT_ENROLLMENT_STATUS", "DEBUG_BODIES", "DEBUG_BODIES_MAX_BYTES", "DEBUG_BODIES_REDACT"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, ":8080", cfg.Addr())
+	assert.Equal(t, "localhost:6379", cfg.Redis.Addr)
+	assert.Empty(t, cfg.RedisReplicaAddr)
+	assert.Equal(t, 10, cfg.Redis.PoolSize)
+	assert.Equal(t, cache.EnrollmentCacheTTL, cfg.CacheTTL)
+	assert.Equal(t, cache.DefaultFallbackSize, cfg.CacheFallbackSize)
+	assert.Empty(t, cfg.CacheNamespace)
+	assert.Zero(t, cfg.CacheCompressThreshold)
+	assert.Equal(t, config.StorageMemory, cfg.StorageBackend)
+	assert.Equal(t, 15*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, middleware.DefaultRequestTimeout, cfg.RequestTimeout)
+	assert.Zero(t, cfg.RateLimitBurst)
+	assert.True(t, cfg.ValidateRequests)
+	assert.False(t, cfg.ClampPageLimit)
+	assert.Empty(t, cfg.DefaultEnrollmentStatus)
+	assert.False(t, cfg.DebugBodies)
+	assert.Equal(t, middleware.DefaultDebugBodyBytes, cfg.DebugBodyBytes)
+	assert.Equal(t, middleware.DefaultRedactedFields, cfg.DebugRedactFields)
+	assert.Equal(t, models.DefaultGradeScale, cfg.GradeScale)
+	assert.Equal(t, models.DefaultPassingScore, cfg.PassingScore)
+	assert.Zero(t, cfg.PendingExpiry)
+	assert.Equal(t, config.DefaultPendingExpiryInterval, cfg.PendingExpiryInterval)
+	assert.Empty(t, cfg.SeedFile)
+
+	t.Setenv("PORT", "9090")
+	t.Setenv("REDIS_ADDR", "redis:6380")
+	t.Setenv("REDIS_REPLICA_ADDR", "redis-replica:6380")
+	t.Setenv("REDIS_DB", "2")
+	t.Setenv("REDIS_TLS", "true")
+	t.Setenv("CACHE_TTL", "90s")
+	t.Setenv("CACHE_NAMESPACE", "prod-eu.v2")
+	t.Setenv("CACHE_COMPRESS_THRESHOLD", "4096")
+	t.Setenv("STORAGE_BACKEND", "sqlite")
+	t.Setenv("RATE_LIMIT_RPS", "2.5")
+	t.Setenv("GRADE_SCALE", "a:93, B:85,C:77,D:70,F:0")
+	t.Setenv("DEFAULT_ENROLLMENT_STATUS", " Pending ")
+	t.Setenv("DEBUG_BODIES", "true")
+	t.Setenv("DEBUG_BODIES_MAX_BYTES", "512")
+	t.Setenv("DEBUG_BODIES_REDACT", "student_id,course_name")
+	t.Setenv("REQUEST_TIMEOUT", "0")
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Addr())
+	assert.Equal(t, "redis:6380", cfg.Redis.Addr)
+	assert.Equal(t, "redis-replica:6380", cfg.RedisReplicaAddr)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.True(t, cfg.Redis.TLS)
+	assert.Equal(t, 90*time.Second, cfg.CacheTTL)
+	assert.Equal(t, "prod-eu.v2", cfg.CacheNamespace)
+	assert.Equal(t, 4096, cfg.CacheCompressThreshold)
+	assert.Equal(t, config.StorageSQLite, cfg.StorageBackend)
+	assert.Equal(t, 3, cfg.RateLimitBurst)
+	assert.Equal(t, "A", cfg.GradeScale.Letter(93))
+	assert.Equal(t, "B", cfg.GradeScale.Letter(92.9))
+	assert.Equal(t, "pending", cfg.DefaultEnrollmentStatus)
+	assert.True(t, cfg.DebugBodies)
+	assert.Equal(t, 512, cfg.DebugBodyBytes)
+	assert.Equal(t, []string{"student_id", "course_name"}, cfg.DebugRedactFields)
+	assert.Zero(t, cfg.RequestTimeout, "0 disables the request deadline")
+
+	invalid := map[string]string{
+		"PORT":                      "http",
+		"CACHE_TTL":                 "five minutes",
+		"STORAGE_BACKEND":           "postgres",
+		"MAX_BODY_BYTES":            "-1",
+		"CORS_ALLOW_CREDENTIALS":    "maybe",
+		"REDIS_DB":                  "-3",
+		"CACHE_FALLBACK_SIZE":       "-10",
+		"CACHE_NAMESPACE":           "prod*",
+		"CACHE_COMPRESS_THRESHOLD":  "1kb",
+		"VALIDATE_REQUESTS":         "sometimes",
+		"CLAMP_PAGE_LIMIT":          "yes please",
+		"GRADE_SCALE":               "A:80,B:90",
+		"PASSING_SCORE":             "101",
+		"ALLOW_PRODUCTION_RESET":    "always",
+		"REQUEST_TIMEOUT":           "-5s",
+		"PENDING_EXPIRY":            "-1h",
+		"PENDING_EXPIRY_INTERVAL":   "0s",
+		"DEFAULT_ENROLLMENT_STATUS": "enrolled",
+		"DEBUG_BODIES":              "loud",
+		"DEBUG_BODIES_MAX_BYTES":    "0",
+	}
+	for name, value := range invalid {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, err := config.Load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), name)
+			assert.Contains(t, err.Error(), value)
+		})
+	}
+}
+
+// TestRedisClient validates client construction and credential checks
+func TestRedisClient(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	mr.RequireAuth("s3cret")
+
+	_, err = cache.NewClient(cache.RedisConfig{Addr: mr.Addr(), Password: "wrong"})
+	assert.ErrorIs(t, err, cache.ErrAuthFailed)
+
+	client, err := cache.NewClient(cache.RedisConfig{Addr: mr.Addr(), Password: "s3cret", DB: 3, PoolSize: 4})
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Set(context.Background(), "db-check", "1", 0).Err())
+	mr.Select(3)
+	assert.True(t, mr.Exists("db-check"))
+
+	// An unreachable server is an error, but not an authentication failure
+	addr := mr.Addr()
+	mr.Close()
+	_, err = cache.NewClient(cache.RedisConfig{Addr: addr})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, cache.ErrAuthFailed)
+}
+
+// countingStore counts repository loads and slows them down so concurrent
+// requests overlap
+type countingStore struct {
+	*repository.EnrollmentRepository
+	loads atomic.Int64
+}
+
+func (s *countingStore) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	s.loads.Add(1)
+	time.Sleep(100 * time.Millisecond)
+	return s.EnrollmentRepository.GetByID(ctx, id)
+}
+
+// TestCacheStampede validates that concurrent misses share one repository load
+func TestCacheStampede(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	store := &countingStore{EnrollmentRepository: repository.NewEnrollmentRepository()}
+	server := httptest.NewServer(app.NewApp(app.Config{
+		Store:       store,
+		RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+	}).Routes())
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "stampede-student",
+		"course_id":  "stampede-course",
+		"status":     "active",
+	})
+	mr.FlushAll()
+	store.loads.Store(0)
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Equal(t, int64(1), store.loads.Load())
+	assert.True(t, mr.Exists(cache.EnrollmentCachePrefix+created.ID))
+}
+
+// contextStore records the request ID carried by each GetByID context
+type contextStore struct {
+	*repository.EnrollmentRepository
+	requestIDs chan string
+}
+
+func (s contextStore) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	s.requestIDs <- middleware.GetRequestID(ctx)
+	return s.EnrollmentRepository.GetByID(ctx, id)
+}
+
+// TestContextPropagation validates that handlers pass the request context
+// to the repository and cache, and that both honor cancellation
+func TestContextPropagation(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	store := contextStore{repository.NewEnrollmentRepository(), make(chan string, 1)}
+	application := app.NewApp(app.Config{
+		Store:       store,
+		RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+	})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "ctx-student",
+		"course_id":  "ctx-course",
+		"status":     "pending",
+	})
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/enrollments/"+created.ID, nil)
+	req.Header.Set("X-Request-ID", "ctx-request")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ctx-request", <-store.requestIDs)
+
+	// A cancelled context stops repository and cache calls
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo := repository.NewEnrollmentRepository()
+	assert.ErrorIs(t, repo.Create(cancelled, &models.Enrollment{ID: "ctx-1", StudentID: "s", CourseID: "c", Status: "pending"}), context.Canceled)
+	require.NoError(t, repo.Create(context.Background(), &models.Enrollment{ID: "ctx-1", StudentID: "s", CourseID: "c", Status: "pending"}))
+	_, err = repo.GetByID(cancelled, "ctx-1")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.Update(cancelled, "ctx-1", &models.Enrollment{StudentID: "s", CourseID: "c", Status: "active"}), context.Canceled)
+	assert.ErrorIs(t, repo.Delete(cancelled, "ctx-1"), context.Canceled)
+
+	sqliteRepo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "ctx.db"))
+	require.NoError(t, err)
+	defer sqliteRepo.Close()
+	assert.ErrorIs(t, sqliteRepo.Create(cancelled, &models.Enrollment{ID: "ctx-1", StudentID: "s", CourseID: "c", Status: "pending"}), context.Canceled)
+	assert.Empty(t, sqliteRepo.GetAll(context.Background()))
+
+	_, err = application.Cache.Get(cancelled, created.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Error(t, application.Cache.Ping(cancelled))
+}
+
+// blockingStore holds GetByID until its context ends and reports why
+type blockingStore struct {
+	*repository.EnrollmentRepository
+	errs chan error
+}
+
+func (s blockingStore) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	<-ctx.Done()
+	s.errs <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+// TestRequestTimeout validates the per-request deadline and its 503 response
+func TestRequestTimeout(t *testing.T) {
+	store := blockingStore{repository.NewEnrollmentRepository(), make(chan error, 1)}
+	server := httptest.NewServer(app.NewApp(app.Config{Store: store, RequestTimeout: 100 * time.Millisecond}).Routes())
+	defer server.Close()
+
+	// Fast requests pass through with their headers intact
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "timeout-student",
+		"course_id":  "timeout-course",
+		"status":     "pending",
+	})
+	resp, err := http.Get(server.URL + "/api/enrollments?student_id=timeout-student")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "SKIP", resp.Header.Get("X-Cache-Status"))
+	assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+
+	// A request stuck in the repository is answered with 503 at the deadline
+	start := time.Now()
+	resp, err = http.Get(server.URL + "/api/enrollments/" + created.ID)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "request timed out", body.Error)
+	assert.Equal(t, resp.Header.Get("X-Request-ID"), body.RequestID)
+
+	// The deadline reached the repository through the request context
+	select {
+	case err := <-store.errs:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		t.Fatal("repository call outlived the request deadline")
+	}
+}
+
+// panicStore panics on every lookup
+type panicStore struct {
+	*repository.EnrollmentRepository
+}
+
+func (panicStore) GetByID(ctx context.Context, id string) (*models.Enrollment, error) {
+	panic("store exploded")
+}
+
+// TestRecoverPanics validates that a handler panic becomes a JSON 500
+func TestRecoverPanics(t *testing.T) {
+	path := "/api/enrollments/" + uuid.New().String()
+
+	for name, cfg := range map[string]app.Config{
+		"plain":   {Store: panicStore{repository.NewEnrollmentRepository()}},
+		"timeout": {Store: panicStore{repository.NewEnrollmentRepository()}, RequestTimeout: time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			cfg.LogOutput = &logs
+			server := httptest.NewServer(app.NewApp(cfg).Routes())
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+			req.Header.Set("X-Request-ID", "panic-request")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			var body models.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "internal server error", body.Error)
+			assert.Equal(t, "panic-request", body.RequestID)
+
+			// The access log records the 500
+			assert.Contains(t, logs.String(), `"status":500`)
+		})
+	}
+
+	// The debug flag re-raises the panic
+	router := app.NewApp(app.Config{
+		Store:          panicStore{repository.NewEnrollmentRepository()},
+		RepanicOnPanic: true,
+	}).Routes()
+	assert.Panics(t, func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	})
+
+	// A response already under way can only be aborted
+	started := middleware.Recover(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("too late")
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		started.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+// TestWebhookNotifications validates signed, retried lifecycle events
+func TestWebhookNotifications(t *testing.T) {
+	const secret = "webhook-secret"
+
+	var mu sync.Mutex
+	attempts := 0
+	received := make(chan notify.Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+
+		// Fail the first delivery to exercise the retry path
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, notify.Sign([]byte(secret), body), r.Header.Get(notify.SignatureHeader))
+
+		var event notify.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, event.EventType, r.Header.Get(notify.EventTypeHeader))
+		received <- event
+	}))
+	defer receiver.Close()
+
+	notifier := notify.NewWebhookNotifierWithRetry(receiver.URL, secret, 3, 10*time.Millisecond)
+	server := httptest.NewServer(app.NewApp(app.Config{Notifier: notifier}).Routes())
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "webhook-student",
+		"course_id":  "webhook-course",
+		"status":     "pending",
+	})
+	resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+		"student_id": "webhook-student", "course_id": "webhook-course", "status": "active", "version": 1,
+	})
+	resp.Body.Close()
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+created.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Close waits for every queued event to be delivered
+	require.NoError(t, notifier.Close(context.Background()))
+	close(received)
+
+	var events []notify.Event
+	for event := range received {
+		events = append(events, event)
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, notify.EventEnrollmentCreated, events[0].EventType)
+	assert.Equal(t, notify.EventEnrollmentUpdated, events[1].EventType)
+	assert.Equal(t, "active", events[1].Enrollment.Status)
+	assert.Equal(t, notify.EventEnrollmentDeleted, events[2].EventType)
+	for _, event := range events {
+		assert.Equal(t, created.ID, event.Enrollment.ID)
+		assert.False(t, event.Timestamp.IsZero())
+	}
+	assert.Equal(t, 4, attempts)
+}
+
+// TestWebhookCloseDeadline validates that Close gives up on a failing
+// receiver once its context ends instead of retrying every queued event
+func TestWebhookCloseDeadline(t *testing.T) {
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	// Delivering all of these would take minutes of backoff
+	notifier := notify.NewWebhookNotifierWithRetry(receiver.URL, "", 5, time.Second)
+	for i := 0; i < 20; i++ {
+		notifier.Notify(notify.EventEnrollmentCreated, &models.Enrollment{ID: fmt.Sprintf("close-%d", i)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := notifier.Close(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Nothing is retried after Close returns
+	sent := attempts.Load()
+	assert.Equal(t, int32(1), sent, "only the first attempt fits before the deadline")
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, sent, attempts.Load())
+}
+
+// TestSearchEnrollments validates free-text search ranking on both backends
+func TestSearchEnrollments(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "search.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+			defer server.Close()
+
+			create := func(studentID, courseID string) models.Enrollment {
+				return createTestEnrollment(t, server.URL, map[string]interface{}{
+					"student_id": studentID,
+					"course_id":  courseID,
+					"status":     "pending",
+				})
+			}
+			contains := create("s-math-fan", "c1")
+			coursePrefix := create("stu2", "MATH-101")
+			create("stu3", "bio")
+			studentPrefix := create("Mathilda", "c2")
+			deleted := create("math-dropout", "c3")
+
+			req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+deleted.ID, nil)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			resp, err = http.Get(server.URL + "/api/enrollments/search?q=math")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			var results []models.Enrollment
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+			resp.Body.Close()
+
+			ids := make([]string, len(results))
+			for i, result := range results {
+				ids[i] = result.ID
+			}
+			assert.Equal(t, []string{coursePrefix.ID, studentPrefix.ID, contains.ID}, ids)
+
+			for _, q := range []string{"", "%20%20"} {
+				resp, err = http.Get(server.URL + "/api/enrollments/search?q=" + q)
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+// TestBulkStatusUpdate validates moving a course's enrollments between statuses
+func TestBulkStatusUpdate(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	create := func(studentID, courseID, status string) models.Enrollment {
+		return createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": studentID,
+			"course_id":  courseID,
+			"status":     status,
+		})
+	}
+	first := create("term-1", "term-course", "active")
+	create("term-2", "term-course", "active")
+	pending := create("term-3", "term-course", "pending")
+	other := create("term-1", "other-course", "active")
+	deleted := create("term-4", "term-course", "active")
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments/"+deleted.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	get := func(id string) (models.Enrollment, string) {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + id)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var enrollment models.Enrollment
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+		return enrollment, resp.Header.Get("X-Cache-Status")
+	}
+	get(first.ID)
+	_, cacheStatus := get(first.ID)
+	require.Equal(t, "HIT", cacheStatus)
+
+	bulkStatus := func(payload map[string]interface{}) (int, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(server.URL+"/api/enrollments/bulk-status", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	code, result := bulkStatus(map[string]interface{}{"course_id": "term-course", "from_status": "active", "to_status": "completed"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), result["updated"])
+
+	// The cached copy was invalidated and the version bumped
+	enrollment, cacheStatus := get(first.ID)
+	assert.Equal(t, "MISS", cacheStatus)
+	assert.Equal(t, "completed", enrollment.Status)
+	assert.Equal(t, 2, enrollment.Version)
+
+	// Other statuses, courses and deleted enrollments are untouched
+	enrollment, _ = get(pending.ID)
+	assert.Equal(t, "pending", enrollment.Status)
+	enrollment, _ = get(other.ID)
+	assert.Equal(t, "active", enrollment.Status)
+
+	// Transition rules apply
+	code, result = bulkStatus(map[string]interface{}{"course_id": "term-course", "from_status": "completed", "to_status": "active"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "cannot transition from completed to active", result["error"])
+
+	code, result = bulkStatus(map[string]interface{}{"course_id": "term-course", "from_status": "active", "to_status": "done"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "to_status must be one of: pending, active, completed, waitlisted", result["error"])
+
+	code, result = bulkStatus(map[string]interface{}{"from_status": "active", "to_status": "completed"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "course_id is required", result["error"])
+}
+
+// TestRecentEnrollments validates the newest-first listing on both backends
+func TestRecentEnrollments(t *testing.T) {
+	sqliteStore, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "recent.db"))
+	require.NoError(t, err)
+	defer sqliteStore.Close()
+
+	stores := map[string]repository.Store{
+		"memory": repository.NewEnrollmentRepository(),
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			server := httptest.NewServer(app.NewApp(app.Config{Store: store}).Routes())
+			defer server.Close()
+
+			// Created out of order so the index has to insert, not just append
+			base := time.Now().Add(-time.Hour)
+			ids := make(map[string]string)
+			for _, offset := range []int{3, 1, 4, 2, 5} {
+				created := base.Add(time.Duration(offset) * time.Minute)
+				enrollment := &models.Enrollment{
+					ID:        uuid.New().String(),
+					StudentID: fmt.Sprintf("recent-%d", offset),
+					CourseID:  "recent-course",
+					Status:    "active",
+					CreatedAt: created,
+					UpdatedAt: created,
+				}
+				require.NoError(t, store.Create(ctx, enrollment))
+				ids[enrollment.StudentID] = enrollment.ID
+			}
+			require.NoError(t, store.Delete(ctx, ids["recent-4"]))
+
+			// Moving created_at on update moves the enrollment in the listing
+			moved, err := store.GetByID(ctx, ids["recent-1"])
+			require.NoError(t, err)
+			moved.CreatedAt = base.Add(10 * time.Minute)
+			moved.Version = 0
+			require.NoError(t, store.Update(ctx, moved.ID, moved))
+
+			recent := func(query string) (int, []string) {
+				resp, err := http.Get(server.URL + "/api/enrollments/recent" + query)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return resp.StatusCode, nil
+				}
+				var enrollments []models.Enrollment
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollments))
+				students := make([]string, len(enrollments))
+				for i, enrollment := range enrollments {
+					students[i] = enrollment.StudentID
+				}
+				return resp.StatusCode, students
+			}
+
+			code, students := recent("")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, []string{"recent-1", "recent-5", "recent-3", "recent-2"}, students)
+
+			_, students = recent("?limit=2")
+			assert.Equal(t, []string{"recent-1", "recent-5"}, students)
+
+			// Limits above the maximum are capped rather than rejected
+			code, students = recent("?limit=1000")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Len(t, students, 4)
+
+			for _, query := range []string{"?limit=0", "?limit=-1", "?limit=ten"} {
+				code, _ = recent(query)
+				assert.Equal(t, http.StatusBadRequest, code, query)
+			}
+		})
+	}
+}
+
+// TestStatusIndex validates that status-filtered queries on the memory
+// repository stay in step with every kind of write
+func TestStatusIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewEnrollmentRepository()
+
+	// statusIDs lists the IDs a status filter returns, in creation order
+	statusIDs := func(filter repository.EnrollmentFilter) []string {
+		ids := []string{}
+		for _, enrollment := range repo.Find(filter) {
+			ids = append(ids, enrollment.ID)
+		}
+		return ids
+	}
+
+	base := time.Now().Add(-time.Hour)
+	for i, status := range []string{"pending", "active", "active", "completed"} {
+		created := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, repo.Create(ctx, &models.Enrollment{
+			ID:        fmt.Sprintf("index-%d", i),
+			StudentID: fmt.Sprintf("index-student-%d", i),
+			CourseID:  "index-course",
+			Status:    status,
+			CreatedAt: created,
+			UpdatedAt: created,
+		}))
+	}
+	assert.Equal(t, []string{"index-1", "index-2"}, statusIDs(repository.EnrollmentFilter{Status: "active"}))
+	assert.Empty(t, statusIDs(repository.EnrollmentFilter{Status: "waitlisted"}))
+
+	// Update moves an enrollment between statuses
+	moved, err := repo.GetByID(ctx, "index-0")
+	require.NoError(t, err)
+	moved.Status = "active"
+	require.NoError(t, repo.Update(ctx, moved.ID, moved))
+	assert.Empty(t, statusIDs(repository.EnrollmentFilter{Status: "pending"}))
+	assert.Equal(t, []string{"index-0", "index-1", "index-2"}, statusIDs(repository.EnrollmentFilter{Status: "active"}))
+
+	// So does a bulk status change, which reads the index itself
+	updated, err := repo.UpdateStatusForCourse("index-course", "active", "completed")
+	require.NoError(t, err)
+	assert.Len(t, updated, 3)
+	assert.Empty(t, statusIDs(repository.EnrollmentFilter{Status: "active"}))
+	assert.Equal(t, 4, repo.CountByStatus(repository.EnrollmentFilter{Status: "completed"})["completed"])
+
+	// Soft-deleted enrollments stay indexed but only match IncludeDeleted
+	require.NoError(t, repo.Delete(ctx, "index-3"))
+	assert.Equal(t, []string{"index-0", "index-1", "index-2"}, statusIDs(repository.EnrollmentFilter{Status: "completed"}))
+	assert.Len(t, statusIDs(repository.EnrollmentFilter{Status: "completed", IncludeDeleted: true}), 4)
+	_, err = repo.Restore("index-3")
+	require.NoError(t, err)
+	assert.Len(t, statusIDs(repository.EnrollmentFilter{Status: "completed"}), 4)
+
+	// Load and DeleteAll rebuild it
+	repo.Load([]*models.Enrollment{{ID: "loaded", StudentID: "loaded-student", CourseID: "index-course", Status: "waitlisted"}})
+	assert.Equal(t, []string{"loaded"}, statusIDs(repository.EnrollmentFilter{Status: "waitlisted"}))
+	assert.Empty(t, statusIDs(repository.EnrollmentFilter{Status: "completed"}))
+	_, err = repo.DeleteAll()
+	require.NoError(t, err)
+	assert.Empty(t, statusIDs(repository.EnrollmentFilter{Status: "waitlisted"}))
+}
+
+// BenchmarkStatusIndex compares a status filter, served by the status index,
+// with a course filter that has to scan all 100,000 enrollments. Both match
+// the same 1,000 enrollments.
+func BenchmarkStatusIndex(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewEnrollmentRepository()
+	for i := 0; i < 100000; i++ {
+		enrollment := &models.Enrollment{
+			ID:        fmt.Sprintf("bench-%d", i),
+			StudentID: fmt.Sprintf("bench-student-%d", i),
+			CourseID:  "bench-course",
+			Status:    "active",
+		}
+		if i%100 == 0 {
+			enrollment.CourseID = "bench-rare"
+			enrollment.Status = "waitlisted"
+		}
+		require.NoError(b, repo.Create(ctx, enrollment))
+	}
+	b.ResetTimer()
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			repo.Find(repository.EnrollmentFilter{Status: "waitlisted"})
+		}
+	})
+	b.Run("full scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			repo.Find(repository.EnrollmentFilter{CourseID: "bench-rare"})
+		}
+	})
+}
+
+// TestBulkDelete validates deleting every enrollment matching a filter
+func TestBulkDelete(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	create := func(studentID, courseID, status string) models.Enrollment {
+		return createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": studentID,
+			"course_id":  courseID,
+			"status":     status,
+		})
+	}
+	first := create("purge-1", "purge-course", "pending")
+	second := create("purge-2", "purge-course", "pending")
+	active := create("purge-3", "purge-course", "active")
+	other := create("purge-1", "other-course", "pending")
+
+	get := func(id string) (int, string) {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + id)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get("X-Cache-Status")
+	}
+	get(first.ID)
+	_, cacheStatus := get(first.ID)
+	require.Equal(t, "HIT", cacheStatus)
+
+	bulkDelete := func(query string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/enrollments"+query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	code, result := bulkDelete("?course_id=purge-course&status=pending")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), result["deleted"])
+
+	// The cached copy was invalidated along with the enrollments
+	for _, id := range []string{first.ID, second.ID} {
+		code, _ := get(id)
+		assert.Equal(t, http.StatusNotFound, code)
+	}
+	resp, err := http.Get(server.URL + "/api/enrollments/" + first.ID + "/history")
+	require.NoError(t, err)
+	var history []models.AuditEntry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	resp.Body.Close()
+	require.Len(t, history, 2)
+	assert.Equal(t, models.AuditActionDelete, history[1].Action)
+
+	// Other statuses and courses are untouched
+	for _, id := range []string{active.ID, other.ID} {
+		code, _ := get(id)
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// Repeating the delete matches nothing
+	code, result = bulkDelete("?course_id=purge-course&status=pending")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(0), result["deleted"])
+
+	// A filter is required
+	for _, query := range []string{"", "?include_deleted=true"} {
+		code, result = bulkDelete(query)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "at least one of student_id, course_id, status, from or to is required", result["error"])
+	}
+	code, result = bulkDelete("?status=done")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	var page enrollmentPage
+	resp, err = http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, 2, page.Total)
+}
+
+// TestPendingExpiry validates that stale pending enrollments are soft-deleted
+func TestPendingExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewEnrollmentRepository()
+	application := app.NewApp(app.Config{Store: store})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	seed := func(studentID, status string, age time.Duration) *models.Enrollment {
+		created := time.Now().Add(-age)
+		enrollment := &models.Enrollment{
+			ID:        uuid.New().String(),
+			StudentID: studentID,
+			CourseID:  "expiry-course",
+			Status:    status,
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		require.NoError(t, store.Create(ctx, enrollment))
+		return enrollment
+	}
+	stale := seed("expiry-1", "pending", 48*time.Hour)
+	active := seed("expiry-2", "active", 48*time.Hour)
+	fresh := seed("expiry-3", "pending", time.Minute)
+
+	get := func(id string) int {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + id)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	expired, err := application.ExpirePending(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, http.StatusNotFound, get(stale.ID))
+	assert.Equal(t, http.StatusOK, get(active.ID))
+	assert.Equal(t, http.StatusOK, get(fresh.ID))
+
+	history := application.Audit.GetByEnrollment(stale.ID)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.AuditActionExpire, history[0].Action)
+
+	// Expired enrollments can be restored like any soft-deleted one
+	resp, err := http.Post(server.URL+"/api/enrollments/"+stale.ID+"/restore", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The background sweep expires the restored enrollment again
+	application.StartPendingExpiry(10*time.Millisecond, 24*time.Hour)
+	assert.Eventually(t, func() bool {
+		return get(stale.ID) == http.StatusNotFound
+	}, 2*time.Second, 10*time.Millisecond)
+	application.StopPendingExpiry()
+	application.StopPendingExpiry()
+
+	assert.Equal(t, http.StatusOK, get(fresh.ID))
+}
+
+// TestCompletedAt validates the completion timestamp across update paths
+func TestCompletedAt(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "done-student",
+		"course_id":  "done-course",
+		"status":     "pending",
+	})
+	assert.Nil(t, created.CompletedAt)
+
+	put := func(status string, version int) map[string]interface{} {
+		resp := putEnrollment(t, server.URL, created.ID, map[string]interface{}{
+			"student_id": "done-student", "course_id": "done-course", "status": status, "version": version,
+		})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	// Non-completed enrollments report an explicit null
+	body := put("active", 1)
+	value, present := body["completed_at"]
+	assert.True(t, present)
+	assert.Nil(t, value)
+
+	before := time.Now()
+	body = put("completed", 2)
+	require.NotNil(t, body["completed_at"])
+	completedAt, err := time.Parse(time.RFC3339Nano, body["completed_at"].(string))
+	require.NoError(t, err)
+	assert.False(t, completedAt.Before(before))
+
+	// Staying completed keeps the original time
+	body = put("completed", 3)
+	assert.Equal(t, completedAt.Format(time.RFC3339Nano), body["completed_at"])
+
+	// Bulk status changes stamp it too
+	other := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "done-student-2",
+		"course_id":  "done-course",
+		"status":     "active",
+	})
+	payload, _ := json.Marshal(map[string]interface{}{"course_id": "done-course", "from_status": "active", "to_status": "completed"})
+	resp, err := http.Post(server.URL+"/api/enrollments/bulk-status", "application/json", bytes.NewBuffer(payload))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + other.ID)
+	require.NoError(t, err)
+	var fetched models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&fetched))
+	resp.Body.Close()
+	assert.NotNil(t, fetched.CompletedAt)
+
+	// Leaving completed clears it
+	enrollment := fetched
+	enrollment.Status = "active"
+	enrollment.TrackCompletion(&fetched, time.Now())
+	assert.Nil(t, enrollment.CompletedAt)
+}
+
+// TestFutureEnrollmentDate validates rejection of far-future enrollment dates
+func TestFutureEnrollmentDate(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	post := func(courseID string, date time.Time) (int, string) {
+		payload := map[string]interface{}{
+			"student_id": "future-student",
+			"course_id":  courseID,
+			"status":     "pending",
+		}
+		if !date.IsZero() {
+			payload["enrollment_date"] = date.Format(time.RFC3339)
+		}
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		errMsg, _ := result["error"].(string)
+		return resp.StatusCode, errMsg
+	}
+
+	code, errMsg := post("future-1", time.Now().Add(48*time.Hour))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "enrollment_date cannot be in the future", errMsg)
+
+	// Within the allowed skew, in the past, or defaulted
+	code, _ = post("future-2", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = post("future-3", time.Now().AddDate(-1, 0, 0))
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = post("future-4", time.Time{})
+	assert.Equal(t, http.StatusCreated, code)
+
+	// The skew is adjustable
+	defer func(skew time.Duration) { models.MaxEnrollmentDateSkew = skew }(models.MaxEnrollmentDateSkew)
+	models.MaxEnrollmentDateSkew = 0
+	code, errMsg = post("future-5", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "enrollment_date cannot be in the future", errMsg)
+}
+
+// TestIDValidation validates length limits and blank IDs
+func TestIDValidation(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	post := func(studentID, courseID string) (int, string) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"student_id": studentID,
+			"course_id":  courseID,
+			"status":     "pending",
+		})
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		errMsg, _ := result["error"].(string)
+		return resp.StatusCode, errMsg
+	}
+
+	longID := strings.Repeat("x", models.MaxIDLength+1)
+	cases := []struct {
+		name      string
+		studentID string
+		courseID  string
+		code      int
+		errMsg    string
+	}{
+		{"max length", strings.Repeat("s", models.MaxIDLength), "len-course", http.StatusCreated, ""},
+		{"multi-byte at max length", strings.Repeat("é", models.MaxIDLength), "len-course", http.StatusCreated, ""},
+		{"student too long", longID, "len-course", http.StatusBadRequest, "student_id exceeds maximum length"},
+		{"course too long", "len-student", longID, http.StatusBadRequest, "course_id exceeds maximum length"},
+		{"blank student", "   ", "len-course", http.StatusBadRequest, "student_id is required"},
+		{"blank course", "len-student", "\t", http.StatusBadRequest, "course_id is required"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, errMsg := post(tc.studentID, tc.courseID)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.errMsg, errMsg)
+		})
+	}
+}
+
+func TestStatusNormalization(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	post := func(studentID, status string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"student_id": studentID,
+			"course_id":  "norm-course",
+			"status":     status,
+		})
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	cases := []struct {
+		name   string
+		status string
+		want   string
+	}{
+		{"mixed case", "Active", "active"},
+		{"upper case", "PENDING", "pending"},
+		{"padded", " active ", "active"},
+		{"padded mixed case", "\tCompleted\n", "completed"},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, result := post(fmt.Sprintf("norm-student-%d", i), tc.status)
+			require.Equal(t, http.StatusCreated, code)
+			assert.Equal(t, tc.want, result["status"])
+
+			resp, err := http.Get(server.URL + "/api/enrollments/" + result["id"].(string))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var stored models.Enrollment
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&stored))
+			assert.Equal(t, tc.want, stored.Status)
+		})
+	}
+
+	t.Run("blank and unknown statuses still rejected", func(t *testing.T) {
+		code, result := post("norm-student-blank", "   ")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "status is required", result["error"])
+
+		code, result = post("norm-student-bogus", " Bogus ")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "status must be one of: pending, active, completed, waitlisted", result["error"])
+	})
+
+	t.Run("status filter", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/enrollments?course_id=norm-course&status=ACTIVE")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var page struct {
+			Data []models.Enrollment `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Len(t, page.Data, 2)
+	})
+
+	t.Run("bulk status", func(t *testing.T) {
+		body := `{"course_id":"norm-course","from_status":" Active ","to_status":"COMPLETED"}`
+		resp, err := http.Post(server.URL+"/api/enrollments/bulk-status", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var result map[string]int
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, 2, result["updated"])
+	})
+}
+
+func TestStructuredValidationErrors(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	type errorBody struct {
+		Error  string              `json:"error"`
+		Errors []models.FieldError `json:"errors"`
+	}
+
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		body := `{"student_id":"  ","course_id":"","status":"unknown","enrollment_date":"2999-01-01T00:00:00Z"}`
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var result errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, []models.FieldError{
+			{Field: "student_id", Message: "student_id is required"},
+			{Field: "course_id", Message: "course_id is required"},
+			{Field: "status", Message: "status must be one of: pending, active, completed, waitlisted"},
+			{Field: "enrollment_date", Message: "enrollment_date cannot be in the future"},
+		}, result.Errors)
+		assert.True(t, strings.HasPrefix(result.Error, "4 validation errors: "), result.Error)
+	})
+
+	t.Run("single failure keeps its message", func(t *testing.T) {
+		body := `{"student_id":"ve-student","course_id":"ve-course"}`
+		resp, err := http.Post(server.URL+"/api/enrollments", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var result errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, "status is required", result.Error)
+		assert.Equal(t, []models.FieldError{{Field: "status", Message: "status is required"}}, result.Errors)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		created := createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": "ve-student",
+			"course_id":  "ve-course",
+			"status":     "pending",
+		})
+		body := `{"student_id":"","course_id":"","status":"pending","version":1}`
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+created.ID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var result errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Len(t, result.Errors, 2)
+	})
+
+	t.Run("bulk create", func(t *testing.T) {
+		body := `[{"student_id":"","course_id":"","status":"pending"},{"student_id":"ve-bulk","course_id":"ve-course","status":"active"}]`
+		resp, err := http.Post(server.URL+"/api/enrollments/bulk", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+
+		var results []struct {
+			Index  int                 `json:"index"`
+			ID     string              `json:"id"`
+			Error  string              `json:"error"`
+			Errors []models.FieldError `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		require.Len(t, results, 2)
+		assert.Len(t, results[0].Errors, 2)
+		assert.Equal(t, "2 validation errors: student_id is required; course_id is required", results[0].Error)
+		assert.NotEmpty(t, results[1].ID)
+		assert.Empty(t, results[1].Errors)
+	})
+}
+
+func TestOptionsAllow(t *testing.T) {
+	router := app.NewApp(app.Config{}).Routes()
+
+	options := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cases := []struct {
+		path  string
+		allow string
+	}{
+		{"/api/enrollments", "GET, POST, DELETE"},
+		{"/api/enrollments/" + uuid.NewString(), "GET, HEAD, PUT, DELETE"},
+		{"/api/enrollments/bulk", "POST"},
+		{"/api/enrollments/" + uuid.NewString() + "/grades", "GET, POST"},
+		{"/health", "GET"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := options(tc.path)
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, tc.allow, rec.Header().Get("Allow"))
+			assert.Empty(t, rec.Body.String())
+		})
+	}
+
+	t.Run("unknown path", func(t *testing.T) {
+		rec := options("/api/nope")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("Allow"))
+	})
+
+	t.Run("CORS preflight still short-circuits", func(t *testing.T) {
+		handler := middleware.CORS([]string{"https://admin.example.com"})(router)
+		req := httptest.NewRequest(http.MethodOptions, "/api/enrollments", nil)
+		req.Header.Set("Origin", "https://admin.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Empty(t, rec.Header().Get("Allow"))
+	})
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	router := app.NewApp(app.Config{}).Routes()
+
+	serve := func(method, path string) (*httptest.ResponseRecorder, map[string]string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec, body := serve(http.MethodPatch, "/api/enrollments")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "GET, POST, DELETE", rec.Header().Get("Allow"))
+		assert.Equal(t, "method not allowed", body["error"])
+
+		rec, _ = serve(http.MethodPost, "/health")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET", rec.Header().Get("Allow"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, path := range []string{"/api/nope", "/nope", "/api/enrollments/" + uuid.NewString() + "/nope"} {
+			rec, body := serve(http.MethodGet, path)
+			assert.Equal(t, http.StatusNotFound, rec.Code, path)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Empty(t, rec.Header().Get("Allow"))
+			assert.Equal(t, "not found", body["error"])
+		}
+	})
+}
+
+func TestPrettyJSON(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	get := func(path, accept string) string {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, `{"total":0,"by_status":{"active":0,"completed":0,"pending":0,"waitlisted":0}}`, get("/api/enrollments/count", ""))
+	assert.Equal(t, "{\n  \"total\": 0,\n  \"by_status\": {\n    \"active\": 0,\n    \"completed\": 0,\n    \"pending\": 0,\n    \"waitlisted\": 0\n  }\n}",
+		get("/api/enrollments/count?pretty=true", ""))
+	assert.Contains(t, get("/api/enrollments/count", "application/json; indent=4"), "\n    \"by_status\": {\n        \"active\"")
+	assert.Contains(t, get("/api/enrollments/count", "text/html, application/json;indent=99"), "\n        \"by_status\"")
+	assert.Equal(t, `{"total":0,"by_status":{"active":0,"completed":0,"pending":0,"waitlisted":0}}`, get("/api/enrollments/count?pretty=false", "application/json"))
+
+	// Errors honor it too
+	assert.Equal(t, "{\n  \"error\": \"not found\",\n  \"code\": \"NOT_FOUND\"\n}", get("/api/nope?pretty=1", ""))
+}
+
+func TestYAMLResponses(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "42",
+		"course_id":  "yaml-course",
+		"status":     "active",
+	})
+
+	get := func(path, accept string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := get("/api/enrollments/"+created.ID, "application/yaml")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Accept")
+	assert.True(t, strings.HasPrefix(body, "id: "+created.ID+"\nstudent_id: \"42\"\ncourse_id: yaml-course\n"), body)
+
+	var fields map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(body), &fields))
+	assert.Equal(t, "42", fields["student_id"])
+	assert.Equal(t, "active", fields["status"])
+	assert.Equal(t, 1, fields["version"])
+	assert.Nil(t, fields["completed_at"])
+
+	t.Run("errors", func(t *testing.T) {
+		resp, body := get("/api/enrollments/"+uuid.NewString(), "application/x-yaml")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+		assert.Contains(t, body, "error: Enrollment not found\n")
+
+		resp, body = get("/api/nope", "text/yaml")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "error: not found\ncode: NOT_FOUND\n", body)
+	})
+
+	t.Run("JSON stays the default", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "application/json, application/yaml", "text/html"} {
+			resp, body := get("/api/enrollments/count", accept)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"), accept)
+			assert.True(t, json.Valid([]byte(body)), accept)
+		}
+
+		resp, _ := get("/api/enrollments/count", "text/html, application/yaml;q=0.9")
+		assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+	})
+}
+
+// TestJSONAPIResponses validates JSON:API documents for Accept:
+// application/vnd.api+json
+func TestJSONAPIResponses(t *testing.T) {
+	server := setupTestServerWithoutCache(t)
+	defer server.Close()
+
+	created := createTestEnrollment(t, server.URL, map[string]interface{}{
+		"student_id": "jsonapi-student",
+		"course_id":  "jsonapi-course",
+		"status":     "active",
+	})
+
+	send := func(method, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		var body io.Reader
+		if payload != nil {
+			encoded, _ := json.Marshal(payload)
+			body = bytes.NewReader(encoded)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, body)
+		req.Header.Set("Accept", "application/vnd.api+json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var doc map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		return resp, doc
+	}
+
+	resp, doc := send(http.MethodGet, "/api/enrollments/"+created.ID, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/vnd.api+json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Accept")
+	data := doc["data"].(map[string]interface{})
+	assert.Equal(t, "enrollments", data["type"])
+	assert.Equal(t, created.ID, data["id"])
+	attributes := data["attributes"].(map[string]interface{})
+	assert.Equal(t, "jsonapi-student", attributes["student_id"])
+	assert.Equal(t, "active", attributes["status"])
+	assert.Equal(t, float64(1), attributes["version"])
+	assert.NotContains(t, attributes, "id")
+	assert.Equal(t, "/api/enrollments/"+created.ID, data["links"].(map[string]interface{})["self"])
+
+	resp, doc = send(http.MethodGet, "/api/enrollments?limit=10", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	list := doc["data"].([]interface{})
+	require.Len(t, list, 1)
+	assert.Equal(t, created.ID, list[0].(map[string]interface{})["id"])
+	assert.Equal(t, map[string]interface{}{"total": float64(1), "limit": float64(10), "offset": float64(0)}, doc["meta"])
+
+	resp, doc = send(http.MethodGet, "/api/enrollments/search?q=nobody", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []interface{}{}, doc["data"])
+
+	resp, doc = send(http.MethodPost, "/api/enrollments/"+created.ID+"/grades", map[string]interface{}{"score": 91})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	data = doc["data"].(map[string]interface{})
+	assert.Equal(t, "grades", data["type"])
+	assert.Equal(t, created.ID, data["attributes"].(map[string]interface{})["enrollment_id"])
+
+	// Payloads that are not records are sent as meta
+	resp, doc = send(http.MethodGet, "/api/enrollments/count", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, doc, "data")
+	assert.Equal(t, float64(1), doc["meta"].(map[string]interface{})["total"])
+
+	t.Run("errors", func(t *testing.T) {
+		resp, doc := send(http.MethodGet, "/api/enrollments/"+uuid.NewString(), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "application/vnd.api+json", resp.Header.Get("Content-Type"))
+		assert.NotContains(t, doc, "data")
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"status": "404", "code": "ENROLLMENT_NOT_FOUND", "title": "Not Found", "detail": "Enrollment not found",
+		}}, doc["errors"])
+		assert.Equal(t, resp.Header.Get("X-Request-ID"), doc["meta"].(map[string]interface{})["request_id"])
+
+		resp, doc = send(http.MethodPost, "/api/enrollments", map[string]interface{}{"status": "unknown"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		errs := doc["errors"].([]interface{})
+		require.Len(t, errs, 3)
+		first := errs[0].(map[string]interface{})
+		assert.Equal(t, "400", first["status"])
+		assert.Equal(t, "student_id is required", first["detail"])
+		assert.Equal(t, map[string]interface{}{"pointer": "/data/attributes/student_id"}, first["source"])
+	})
+
+	t.Run("plain JSON stays the default", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + created.ID)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var enrollment models.Enrollment
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+		assert.Equal(t, created.ID, enrollment.ID)
+	})
+}
+
+func TestCacheFallback(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	// No command or dial retries, so each request sees the outage
+	// immediately and the short TTL is not used up waiting on backoff
+	application := app.NewApp(app.Config{
+		RedisClient:       redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1, DialerRetries: 1}),
+		CacheTTL:          2 * time.Second,
+		CacheFallbackSize: 2,
+	})
+	server := httptest.NewServer(application.Routes())
+	defer server.Close()
+
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i] = createTestEnrollment(t, server.URL, map[string]interface{}{
+			"student_id": fmt.Sprintf("fallback-student-%d", i),
+			"course_id":  "fallback-course",
+			"status":     "pending",
+		}).ID
+	}
+
+	get := func(id string) *http.Response {
+		resp, err := http.Get(server.URL + "/api/enrollments/" + id)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+
+	// Healthy Redis serves hits from the redis tier
+	get(ids[0])
+	resp := get(ids[0])
+	assert.Equal(t, "HIT", resp.Header.Get("X-Cache-Status"))
+	assert.Equal(t, "redis", resp.Header.Get("X-Cache-Tier"))
+	assert.Empty(t, resp.Header.Get("X-Cache-Degraded"))
+
+	mr.Close()
+
+	// During the outage a miss is cached in process and the next read hits it
+	resp = get(ids[0])
+	assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+	assert.Equal(t, "true", resp.Header.Get("X-Cache-Degraded"))
+	assert.Empty(t, resp.Header.Get("X-Cache-Tier"))
+
+	resp = get(ids[0])
+	assert.Equal(t, "HIT", resp.Header.Get("X-Cache-Status"))
+	assert.Equal(t, "memory", resp.Header.Get("X-Cache-Tier"))
+	assert.Equal(t, "true", resp.Header.Get("X-Cache-Degraded"))
+
+	stats, err := application.Cache.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats["fallback_entries"])
+
+	t.Run("updates invalidate the fallback", func(t *testing.T) {
+		body := `{"student_id":"fallback-student-0","course_id":"fallback-course","status":"active","version":1}`
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/enrollments/"+ids[0], strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = get(ids[0])
+		assert.Equal(t, "MISS", resp.Header.Get("X-Cache-Status"))
+		resp = get(ids[0])
+		assert.Equal(t, "HIT", resp.Header.Get("X-Cache-Status"))
+	})
+
+	t.Run("least recently used entry is evicted", func(t *testing.T) {
+		get(ids[1])
+		get(ids[0]) // touch ids[0] so ids[1] is the oldest
+		get(ids[2])
+
+		assert.Equal(t, "HIT", get(ids[0]).Header.Get("X-Cache-Status"))
+		assert.Equal(t, "HIT", get(ids[2]).Header.Get("X-Cache-Status"))
+		assert.Equal(t, "MISS", get(ids[1]).Header.Get("X-Cache-Status"))
+	})
+
+	t.Run("entries expire after the cache TTL", func(t *testing.T) {
+		assert.Equal(t, "HIT", get(ids[1]).Header.Get("X-Cache-Status"))
+		time.Sleep(2100 * time.Millisecond)
+		assert.Equal(t, "MISS", get(ids[1]).Header.Get("X-Cache-Status"))
+	})
+}
+
+// TestRequestValidation validates request bodies against the OpenAPI spec
+func TestRequestValidation(t *testing.T) {
+	server := httptest.NewServer(app.NewApp(app.Config{ValidateRequests: true}).Routes())
+	defer server.Close()
+
+	type errorBody struct {
+		Error  string              `json:"error"`
+		Errors []models.FieldError `json:"errors"`
+	}
+	post := func(path, body string) (*http.Response, errorBody) {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp, result
+	}
+	fields := func(violations []models.FieldError) []string {
+		names := make([]string, len(violations))
+		for i, violation := range violations {
+			names[i] = violation.Field
+		}
+		return names
+	}
+
+	t.Run("valid body reaches the handler", func(t *testing.T) {
+		resp, _ := post("/api/enrollments", `{"student_id":"rv-student","course_id":"rv-course","status":"Active"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("every violation is reported", func(t *testing.T) {
+		body := fmt.Sprintf(`{"student_id":%q,"course_id":101,"status":"pending"}`, strings.Repeat("x", models.MaxIDLength+1))
+		resp, result := post("/api/enrollments", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "request body does not match the API schema", result.Error)
+		assert.ElementsMatch(t, []string{"student_id", "course_id"}, fields(result.Errors))
+	})
+
+	t.Run("missing required property", func(t *testing.T) {
+		resp, result := post("/api/enrollments", `{"student_id":"rv-student","course_id":"rv-course"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0].Message, "status")
+	})
+
+	t.Run("bulk items are checked by position", func(t *testing.T) {
+		resp, result := post("/api/enrollments/bulk", `[{"student_id":"a","course_id":"b","status":"pending"},{"student_id":7,"course_id":"b","status":"pending"}]`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []string{"1.student_id"}, fields(result.Errors))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		plain := setupTestServerWithoutCache(t)
+		defer plain.Close()
+		resp, err := http.Post(plain.URL+"/api/enrollments", "application/json",
+			strings.NewReader(`{"student_id":"rv-student","course_id":101,"status":"pending"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.NotEqual(t, "request body does not match the API schema", result.Error)
+	})
+}
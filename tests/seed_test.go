@@ -0,0 +1,59 @@
+//go:build integration && seed
+// +build integration,seed
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"techwave/models"
+	"techwave/seed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeedEndpoint validates that POST /api/_seed loads the same enrollments
+// every time and is safe to repeat
+func TestSeedEndpoint(t *testing.T) {
+	server, mr, _ := setupTestServer(t)
+	defer server.Close()
+	defer mr.Close()
+
+	postSeed := func() seed.Result {
+		resp, err := http.Post(server.URL+"/api/_seed", "application/json", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result seed.Result
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	expected := seed.Enrollments()
+	assert.Equal(t, seed.Result{Created: len(expected)}, postSeed())
+	assert.Equal(t, seed.Result{Existing: len(expected)}, postSeed())
+
+	// Seeded enrollments keep their stable IDs and list in seed order
+	resp, err := http.Get(server.URL + "/api/enrollments")
+	require.NoError(t, err)
+	var page enrollmentPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	require.Len(t, page.Data, len(expected))
+	for i, enrollment := range page.Data {
+		assert.Equal(t, expected[i].ID, enrollment.ID)
+		assert.Equal(t, expected[i].Status, enrollment.Status)
+		assert.True(t, seed.Epoch.Equal(enrollment.EnrollmentDate))
+	}
+
+	resp, err = http.Get(server.URL + "/api/enrollments/" + expected[0].ID)
+	require.NoError(t, err)
+	var first models.Enrollment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&first))
+	resp.Body.Close()
+	assert.Equal(t, expected[0].StudentID, first.StudentID)
+}